@@ -2,14 +2,25 @@ package api
 
 import (
 	"context"
-	"encoding/base64"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 
 	// "github.com/mifaabiyyu/backend-go/api"
+	"github.com/mifaabiyyu/backend-go/internal/abac"
 	"github.com/mifaabiyyu/backend-go/internal/auth"
 	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	perm "github.com/mifaabiyyu/backend-go/internal/permission"
+	"github.com/mifaabiyyu/backend-go/internal/policy"
 	"github.com/mifaabiyyu/backend-go/utils"
 )
 
@@ -20,8 +31,6 @@ type AppAll struct {
 	Application *Application
 }
 
-const userCtx userKey = "user"
-
 func (app *AppAll) AuthTokenMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -37,6 +46,20 @@ func (app *AppAll) AuthTokenMiddleware(next http.Handler) http.Handler {
 		}
 
 		token := parts[1]
+
+		if auth.IsPersonalAccessToken(token) {
+			user, scopes, err := app.personalAccessTokenUser(r.Context(), token)
+			if err != nil {
+				app.AppWrapper.UnauthorizedErrorResponse(w, r, err)
+				return
+			}
+
+			ctx := auth.WithUser(r.Context(), user)
+			ctx = auth.WithScopes(ctx, scopes)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		jwtToken, err := app.Application.Authenticator.ValidateToken(token)
 		if err != nil {
 			app.AppWrapper.UnauthorizedErrorResponse(w, r, err)
@@ -54,56 +77,271 @@ func (app *AppAll) AuthTokenMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		userID := claims.UserID
+		if claims.Purpose != "" {
+			app.AppWrapper.UnauthorizedErrorResponse(w, r, fmt.Errorf("token cannot be used as an access token"))
+			return
+		}
+
+		if claims.ID == "" {
+			app.AppWrapper.UnauthorizedErrorResponse(w, r, fmt.Errorf("token id is missing"))
+			return
+		}
 
 		ctx := r.Context()
 
+		revoked, err := app.Application.Revocations.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			app.AppWrapper.InternalServerError(w, r, err)
+			return
+		}
+
+		if revoked {
+			app.AppWrapper.UnauthorizedErrorResponse(w, r, fmt.Errorf("token has been revoked"))
+			return
+		}
+
+		userID := claims.UserID
+
 		user, err := app.getUser(ctx, userID)
 		if err != nil {
 			app.AppWrapper.UnauthorizedErrorResponse(w, r, err)
 			return
 		}
 
-		ctx = context.WithValue(ctx, userCtx, user)
+		if claims.TokenVersion != user.TokenVersion {
+			app.AppWrapper.UnauthorizedErrorResponse(w, r, fmt.Errorf("token has been revoked"))
+			return
+		}
+
+		if claims.SessionID != "" {
+			if err := app.checkSession(ctx, claims, r); err != nil {
+				if errors.Is(err, errSessionRevoked) {
+					app.AppWrapper.UnauthorizedErrorResponse(w, r, err)
+					return
+				}
+				app.AppWrapper.InternalServerError(w, r, err)
+				return
+			}
+		}
+
+		ctx = auth.WithUser(ctx, user)
+		ctx = auth.WithClaims(ctx, claims)
+
+		if claims.Actor != nil {
+			app.serveImpersonated(w, r.WithContext(ctx), next, claims)
+			return
+		}
+
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// ImpersonatedByHeader is set on every response to a request made with an
+// impersonation token, its value is the id of the real user.
+const ImpersonatedByHeader = "X-Impersonated-By"
+
+// serveImpersonated records the request in the impersonation audit log
+// before serving it and its status afterwards. A request that cannot be
+// recorded is not served.
+func (app *AppAll) serveImpersonated(w http.ResponseWriter, r *http.Request, next http.Handler, claims *auth.Claims) {
+	ctx := r.Context()
+
+	ip := remoteIP(r)
+
+	requestID, err := app.Application.Store.Queries.CreateImpersonationRequest(ctx, sqlc.CreateImpersonationRequestParams{
+		TokenID: claims.ID,
+		ActorID: claims.Actor.UserID,
+		UserID:  claims.UserID,
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Ip:      ip,
+	})
+	if err != nil {
+		app.AppWrapper.InternalServerError(w, r, fmt.Errorf("recording impersonated request: %w", err))
+		return
+	}
+
+	w.Header().Set(ImpersonatedByHeader, strconv.FormatInt(claims.Actor.UserID, 10))
+
+	ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+	next.ServeHTTP(ww, r)
+
+	status := ww.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	// The client may already be gone, the audit log is written anyway.
+	err = app.Application.Store.Queries.SetImpersonationRequestStatus(context.WithoutCancel(ctx), sqlc.SetImpersonationRequestStatusParams{
+		ID:     requestID,
+		Status: pgtype.Int4{Int32: int32(status), Valid: true},
+	})
+	if err != nil {
+		app.AppWrapper.Logger.Errorw("recording impersonated request status failed", "request_id", requestID, "error", err.Error())
+	}
+}
+
+// DenyImpersonation rejects requests made with an impersonation token. It
+// guards account-level actions support staff must not take on a user's
+// behalf, like minting tokens or changing the second factor.
+func (app *AppAll) DenyImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if claims, ok := auth.ClaimsFromContext(r.Context()); ok && claims.Actor != nil {
+			app.AppWrapper.ForbiddenResponse(w, r, fmt.Errorf("not allowed while impersonating another user"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+var errSessionRevoked = errors.New("session has been revoked")
+
+// sessionTouchInterval limits how often last_seen_at is written, one
+// update per session and minute is plenty for the device list.
+const sessionTouchInterval = time.Minute
+
+// checkSession makes sure the token's session is still active and records
+// that it has been seen.
+func (app *AppAll) checkSession(ctx context.Context, claims *auth.Claims, r *http.Request) error {
+	session, err := app.Application.Store.Queries.GetSession(ctx, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errSessionRevoked
+		}
+		return err
+	}
+
+	if session.RevokedAt.Valid || session.UserID != claims.UserID {
+		return errSessionRevoked
+	}
+
+	if time.Since(session.LastSeenAt.Time) < sessionTouchInterval {
+		return nil
+	}
+
+	ip := remoteIP(r)
+
+	return app.Application.Store.Queries.TouchSession(ctx, sqlc.TouchSessionParams{
+		ID: session.ID,
+		Ip: ip,
+	})
+}
+
+// personalAccessTokenUser returns the owner and scopes of a personal access
+// token. Tokens carry no claims, handlers that need a login session keep
+// rejecting them.
+func (app *AppAll) personalAccessTokenUser(ctx context.Context, token string) (*sqlc.User, []string, error) {
+	lookup, ok := auth.ParsePersonalAccessToken(token)
+	if !ok {
+		return nil, nil, fmt.Errorf("token is malformed")
+	}
+
+	pat, err := app.Application.Store.Queries.GetPersonalAccessTokenByPrefix(ctx, lookup)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, fmt.Errorf("token invalid or expired")
+		}
+		return nil, nil, err
+	}
+
+	if !auth.VerifyPersonalAccessToken(token, pat.TokenHash) {
+		return nil, nil, fmt.Errorf("token invalid or expired")
+	}
+
+	user, err := app.getUser(ctx, pat.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := app.Application.Store.Queries.TouchPersonalAccessToken(ctx, pat.ID); err != nil {
+		return nil, nil, err
+	}
+
+	return user, pat.Scopes, nil
+}
+
+// BasicAuthMiddleware authenticates service accounts with HTTP Basic auth,
+// the client id as user name and the client secret as password. Failures
+// get the WWW-Authenticate challenge.
 func (app *AppAll) BasicAuthMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				app.AppWrapper.UnauthorizedErrorResponse(w, r, fmt.Errorf("authorization header is missing"))
+			clientID, secret, ok := r.BasicAuth()
+			if !ok {
+				app.AppWrapper.UnauthorizedBasicErrorResponse(w, r, fmt.Errorf("authorization header is missing or malformed"))
 				return
 			}
 
-			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || parts[0] != "Basic" {
-				app.AppWrapper.UnauthorizedErrorResponse(w, r, fmt.Errorf("authorization header is malformed"))
+			ctx := r.Context()
+
+			account, err := app.Application.Store.Queries.GetServiceAccountByClientID(ctx, clientID)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				app.AppWrapper.InternalServerError(w, r, err)
 				return
 			}
 
-			decoded, err := base64.StdEncoding.DecodeString(parts[1])
-			if err != nil {
-				app.AppWrapper.UnauthorizedErrorResponse(w, r, err)
-				return
+			// Unknown client ids are compared against a dummy hash so the
+			// response time does not tell them apart from wrong secrets.
+			hash := unknownServiceAccountHash
+			if err == nil {
+				hash = account.SecretHash
 			}
 
-			username := app.Application.Config.Auth.Basic.User
-			pass := app.Application.Config.Auth.Basic.Pass
+			valid := subtle.ConstantTimeCompare([]byte(auth.HashOpaqueToken(secret)), []byte(hash)) == 1
+			if err != nil || !valid {
+				app.AppWrapper.UnauthorizedBasicErrorResponse(w, r, fmt.Errorf("invalid credentials"))
+				return
+			}
 
-			creds := strings.SplitN(string(decoded), ":", 2)
-			if len(creds) != 2 || creds[0] != username || creds[1] != pass {
-				app.AppWrapper.UnauthorizedErrorResponse(w, r, fmt.Errorf("invalid credentials"))
+			if err := app.Application.Store.Queries.TouchServiceAccount(ctx, account.ID); err != nil {
+				app.AppWrapper.InternalServerError(w, r, err)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			ctx = context.WithValue(ctx, serviceAccountCtx, &account)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+const serviceAccountCtx userKey = "service_account"
+
+var unknownServiceAccountHash = auth.HashOpaqueToken("unknown service account")
+
+// AuthenticateMiddleware accepts both users (bearer JWT or personal access
+// token) and service accounts (Basic), for routes shared by people and
+// machines.
+func (app *AppAll) AuthenticateMiddleware(next http.Handler) http.Handler {
+	bearer := app.AuthTokenMiddleware(next)
+	basic := app.BasicAuthMiddleware()(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, _, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		if strings.EqualFold(scheme, "Basic") {
+			basic.ServeHTTP(w, r)
+			return
+		}
+
+		bearer.ServeHTTP(w, r)
+	})
+}
+
+// principalRoleID returns the role of the authenticated user or service
+// account.
+func principalRoleID(ctx context.Context) (int32, error) {
+	if account, ok := ctx.Value(serviceAccountCtx).(*sqlc.ServiceAccount); ok {
+		return account.RoleID, nil
+	}
+
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return 0, fmt.Errorf("user not authenticated")
+	}
+
+	return user.RoleID.Int32, nil
+}
+
 func (app *AppAll) getUser(ctx context.Context, userID int64) (*sqlc.User, error) {
 	if !app.Application.Config.RedisCfg.Enabled {
 		u, err := app.Application.Store.Queries.GetUserByID(ctx, userID)
@@ -149,42 +387,258 @@ func (app *AppAll) RateLimiterMiddleware(next http.Handler) http.Handler {
 func (app *AppAll) RequirePermission(permission string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			userVal := r.Context().Value(userCtx)
-			if userVal == nil {
-				app.AppWrapper.UnauthorizedErrorResponse(w, r, fmt.Errorf("user not authenticated"))
+			if !app.checkPermission(w, r, permission) {
 				return
 			}
 
-			user, ok := userVal.(*sqlc.User)
-			if !ok {
-				app.AppWrapper.UnauthorizedErrorResponse(w, r, fmt.Errorf("invalid user type in context"))
-				return
-			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// checkPermission answers the request itself and returns false when the
+// caller's role, or token scopes, do not grant permission.
+func (app *AppAll) checkPermission(w http.ResponseWriter, r *http.Request, permission string) bool {
+	roleID, err := principalRoleID(r.Context())
+	if err != nil {
+		app.AppWrapper.UnauthorizedErrorResponse(w, r, err)
+		return false
+	}
+
+	granted, err := app.rolePermissions(r.Context(), roleID)
+	if err != nil {
+		app.AppWrapper.ForbiddenResponse(w, r, fmt.Errorf("failed to retrieve permissions: %w", err))
+		return false
+	}
+
+	if !app.checkScopes(w, r, permission) {
+		return false
+	}
+
+	if !granted.Allows(permission) {
+		app.AppWrapper.ForbiddenResponse(w, r, fmt.Errorf("you don't have permission to perform this action"))
+		return false
+	}
+
+	return true
+}
+
+// checkScopes refuses personal access tokens not scoped for permission, a
+// token only gets the part of the role it was scoped to.
+func (app *AppAll) checkScopes(w http.ResponseWriter, r *http.Request, permission string) bool {
+	if scopes, ok := auth.ScopesFromContext(r.Context()); ok && !perm.Compile(scopes).Allows(permission) {
+		app.AppWrapper.ForbiddenResponse(w, r, fmt.Errorf("token is not scoped for this action"))
+		return false
+	}
+	return true
+}
 
-			permissions, err := app.Application.Store.Queries.GetPermissionsByRoleID(r.Context(), user.RoleID.Int32)
+// rolePermissions returns the compiled effective permissions of a role,
+// from the cache when possible. A failing cache falls back to Postgres.
+func (app *AppAll) rolePermissions(ctx context.Context, roleID int32) (*perm.Set, error) {
+	cached := app.Application.CacheStorage.Permissions
+
+	set, version, cacheErr := cached.Get(ctx, roleID)
+	if cacheErr != nil {
+		app.AppWrapper.Logger.Warnw("permission cache unavailable", "role_id", roleID, "error", cacheErr.Error())
+	} else if set != nil {
+		return set, nil
+	}
+
+	permissions, err := app.Application.Store.Queries.GetEffectivePermissionsByRoleID(ctx, int64(roleID))
+	if err != nil {
+		return nil, err
+	}
+
+	grants := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		grants = append(grants, p.Name)
+	}
+
+	if cacheErr == nil {
+		if err := cached.Set(ctx, version, roleID, grants); err != nil {
+			app.AppWrapper.Logger.Warnw("permission cache update failed", "role_id", roleID, "error", err.Error())
+		}
+	}
+
+	return perm.Compile(grants), nil
+}
+
+// RequireRoleLevel only lets through callers whose role has at least the
+// given roles.level.
+func (app *AppAll) RequireRoleLevel(level int32) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			roleID, err := principalRoleID(r.Context())
 			if err != nil {
-				app.AppWrapper.ForbiddenResponse(w, r, fmt.Errorf("failed to retrieve permissions: %w", err))
+				app.AppWrapper.UnauthorizedErrorResponse(w, r, err)
 				return
 			}
 
-			for _, p := range permissions {
-				if p.Name == permission {
-					next.ServeHTTP(w, r)
-					return
-				}
+			if err := app.Application.Roles.RequireLevel(r.Context(), int64(roleID), level); err != nil {
+				app.AppWrapper.ForbiddenResponse(w, r, err)
+				return
 			}
 
-			app.AppWrapper.ForbiddenResponse(w, r, fmt.Errorf("you don't have permission to perform this action"))
+			next.ServeHTTP(w, r)
 		})
 	}
 }
 
 func (app *AppAll) RequireLogin(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Context().Value(userCtx) == nil {
+		if _, ok := auth.UserFromContext(r.Context()); !ok {
 			app.AppWrapper.UnauthorizedErrorResponse(w, r, fmt.Errorf("unauthenticated"))
 			return
 		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// Authorize loads the resource a request refers to and checks it against
+// p. The resource is then available to the handler through
+// policy.ResourceFromContext.
+func (app *AppAll) Authorize(p policy.Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			roleID, err := principalRoleID(r.Context())
+			if err != nil {
+				app.AppWrapper.UnauthorizedErrorResponse(w, r, err)
+				return
+			}
+
+			subject, err := app.policySubject(r.Context(), roleID)
+			if err != nil {
+				app.AppWrapper.ForbiddenResponse(w, r, err)
+				return
+			}
+
+			res, err := p.Load(r)
+			if err == nil {
+				err = p.Authorize(r.Context(), subject, res)
+			}
+
+			switch {
+			case err == nil:
+				next.ServeHTTP(w, r.WithContext(policy.WithResource(r.Context(), res)))
+			case errors.Is(err, policy.ErrNotFound):
+				app.AppWrapper.NotFoundResponse(w, r, policy.ErrNotFound)
+			case errors.Is(err, policy.ErrForbidden):
+				app.AppWrapper.ForbiddenResponse(w, r, err)
+			default:
+				app.AppWrapper.InternalServerError(w, r, err)
+			}
+		})
+	}
+}
+
+// policySubject describes the authenticated user or service account for
+// policy rules.
+func (app *AppAll) policySubject(ctx context.Context, roleID int32) (policy.Subject, error) {
+	granted, err := app.rolePermissions(ctx, roleID)
+	if err != nil {
+		return policy.Subject{}, fmt.Errorf("failed to retrieve permissions: %w", err)
+	}
+
+	subject := policy.Subject{
+		RoleID:      int64(roleID),
+		Permissions: granted,
+		Roles:       app.Application.Roles,
+	}
+	if user, ok := auth.UserFromContext(ctx); ok {
+		subject.UserID = user.ID
+	}
+	if scopes, ok := auth.ScopesFromContext(ctx); ok {
+		subject.Scopes = perm.Compile(scopes)
+	}
+
+	return subject, nil
+}
+
+// ResourceAttributes describes the resource a request refers to for ABAC
+// policies, abac.ErrNotFound when it does not exist.
+type ResourceAttributes func(r *http.Request) (map[string]any, error)
+
+// RequirePolicy evaluates the ABAC policies for action, a permission name.
+// A matching deny refuses the request and a matching allow lets it through
+// even when the role lacks the permission. Without a matching policy the
+// request falls back to RequirePermission(action). Token scopes always
+// apply. resource may be nil for routes not about a single resource.
+func (app *AppAll) RequirePolicy(action string, resource ResourceAttributes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			subject, err := app.subjectAttributes(r.Context())
+			if err != nil {
+				if errors.Is(err, errUnauthenticated) {
+					app.AppWrapper.UnauthorizedErrorResponse(w, r, err)
+				} else {
+					app.AppWrapper.InternalServerError(w, r, err)
+				}
+				return
+			}
+
+			resourceAttributes := map[string]any{}
+			if resource != nil {
+				resourceAttributes, err = resource(r)
+				if err != nil {
+					if errors.Is(err, abac.ErrNotFound) {
+						app.AppWrapper.NotFoundResponse(w, r, err)
+					} else {
+						app.AppWrapper.InternalServerError(w, r, err)
+					}
+					return
+				}
+			}
+
+			policies := app.Application.Policies
+			decision := policies.Evaluate(abac.Request{
+				Subject:     subject,
+				Resource:    resourceAttributes,
+				Action:      action,
+				Environment: policies.Environment(time.Now(), remoteIP(r)),
+			})
+
+			switch decision.Effect {
+			case abac.Deny:
+				app.AppWrapper.ForbiddenResponse(w, r, fmt.Errorf("denied by policy %s", decision.PolicyID))
+				return
+			case abac.Allow:
+				if !app.checkScopes(w, r, action) {
+					return
+				}
+			default:
+				if !app.checkPermission(w, r, action) {
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+var errUnauthenticated = errors.New("user not authenticated")
+
+// subjectAttributes describes the authenticated user or service account
+// for ABAC policies.
+func (app *AppAll) subjectAttributes(ctx context.Context) (map[string]any, error) {
+	if account, ok := ctx.Value(serviceAccountCtx).(*sqlc.ServiceAccount); ok {
+		return app.Application.Attributes.ServiceAccount(ctx, account)
+	}
+
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return nil, errUnauthenticated
+	}
+	return app.Application.Attributes.User(ctx, user)
+}
+
+// remoteIP is the client address without its port. RemoteAddr has already
+// been rewritten by the RealIP middleware.
+func remoteIP(r *http.Request) string {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return ip
+}
@@ -6,20 +6,32 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	accesscontrol "github.com/mifaabiyyu/backend-go/cmd/api/abac"
 	authentication "github.com/mifaabiyyu/backend-go/cmd/api/auth"
+	"github.com/mifaabiyyu/backend-go/cmd/api/post"
+	"github.com/mifaabiyyu/backend-go/cmd/api/privacy"
+	"github.com/mifaabiyyu/backend-go/cmd/api/rbac"
 	"github.com/mifaabiyyu/backend-go/cmd/api/user"
+	"github.com/mifaabiyyu/backend-go/internal/abac"
 	"github.com/mifaabiyyu/backend-go/internal/auth"
 	"github.com/mifaabiyyu/backend-go/internal/env"
+	"github.com/mifaabiyyu/backend-go/internal/hierarchy"
+	"github.com/mifaabiyyu/backend-go/internal/lockout"
 	"github.com/mifaabiyyu/backend-go/internal/mailer"
+	"github.com/mifaabiyyu/backend-go/internal/oidc"
+	"github.com/mifaabiyyu/backend-go/internal/password"
+	"github.com/mifaabiyyu/backend-go/internal/policy"
 	"github.com/mifaabiyyu/backend-go/internal/ratelimiter"
 	"github.com/mifaabiyyu/backend-go/internal/store"
 	"github.com/mifaabiyyu/backend-go/internal/store/cache"
+	"github.com/mifaabiyyu/backend-go/internal/webauthn"
 	"github.com/mifaabiyyu/backend-go/utils"
 
 	"github.com/swaggo/swag/example/basic/docs"
@@ -29,15 +41,23 @@ import (
 const version = "1.1.0"
 
 type Application struct {
-	ApiURL        string
-	Config        Config
-	CacheStorage  cache.Storage
-	Logger        *zap.SugaredLogger
-	Store         *store.Store
-	RateLimiter   ratelimiter.Limiter
-	Authenticator auth.Authenticator
-	Mailer        mailer.Client
-	middleware    AppAll
+	ApiURL         string
+	Config         Config
+	CacheStorage   cache.Storage
+	Logger         *zap.SugaredLogger
+	Store          *store.Store
+	RateLimiter    ratelimiter.Limiter
+	Lockout        *lockout.Guard
+	PasswordPolicy *password.Policy
+	OAuthProviders map[string]*oidc.Provider
+	WebAuthn       *webauthn.RelyingParty
+	Roles          *hierarchy.Guard
+	Policies       *abac.Engine
+	Attributes     *abac.Directory
+	Authenticator  auth.Authenticator
+	Revocations    auth.RevocationStore
+	Mailer         mailer.Client
+	middleware     AppAll
 }
 
 type Config struct {
@@ -50,6 +70,8 @@ type Config struct {
 	Auth        AuthConfig
 	RedisCfg    RedisConfig
 	RateLimiter ratelimiter.Config
+	Lockout     lockout.Config
+	Privacy     PrivacyConfig
 }
 
 type DbConfig struct {
@@ -67,19 +89,45 @@ type RedisConfig struct {
 }
 
 type AuthConfig struct {
-	Basic BasicConfig
-	Token TokenConfig
+	Token          TokenConfig
+	Password       password.Config
+	PasswordPolicy password.PolicyConfig
+	// BreachedPasswordsFile extends the bundled breached password list,
+	// see password.HashList.Load for the format.
+	BreachedPasswordsFile string
+	OAuth                 []oidc.Config
+	WebAuthn              webauthn.Config
+	// PolicyFile holds the ABAC policies, see abac.Load for the format.
+	// Empty means no policies, RequirePolicy then behaves like
+	// RequirePermission.
+	PolicyFile string
 }
 
 type TokenConfig struct {
-	Secret string
-	Exp    time.Duration
-	Iss    string
+	Secret     string
+	Exp        time.Duration
+	RefreshExp time.Duration
+	Iss        string
+	Aud        string
+	// Leeway is the clock skew tolerated when checking exp, nbf and iat.
+	Leeway time.Duration
+	// SigningKeyFile switches token signing from HS256 with Secret to the
+	// RSA or Ed25519 key in this PEM file.
+	SigningKeyFile string
+	// VerifyKeyFiles are extra PEM keys still accepted for verification,
+	// e.g. the previous signing key during a rotation.
+	VerifyKeyFiles []string
+	// ImpersonationExp is the lifetime of admin impersonation tokens.
+	ImpersonationExp time.Duration
 }
 
-type BasicConfig struct {
-	User string
-	Pass string
+type PrivacyConfig struct {
+	// ErasureGracePeriod is how long an account deletion can be cancelled.
+	ErasureGracePeriod time.Duration
+	// ExportExp is how long a data export can be downloaded.
+	ExportExp time.Duration
+	// PollInterval is how often the privacy worker looks for work.
+	PollInterval time.Duration
 }
 
 type MailConfig struct {
@@ -113,22 +161,27 @@ func (app *Application) Mount() http.Handler {
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	r.Use(app.middleware.RateLimiterMiddleware)
 
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{env.GetString("CORS_ALLOWED_ORIGIN", "http://localhost:5174")},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", ImpersonatedByHeader},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
 
 	r.Use(middleware.Timeout(60 * time.Second))
 
+	r.Get("/.well-known/jwks.json", app.jwksHandler)
+
 	r.Route("/v1", func(v1 chi.Router) {
 		// Grouped routes for users
 		app.mountUserRoutes(v1)
 
+		app.mountPostRoutes(v1)
+
 		// In the future:
 		// app.mountProductRoutes(v1)
 		// app.mountAuthRoutes(v1)
@@ -139,20 +192,251 @@ func (app *Application) Mount() http.Handler {
 
 func (app *Application) mountUserRoutes(r chi.Router) {
 	userHandler := user.InitUserModule(app.Store.Queries)
-	authHandler := authentication.InitAuthModule(app.Store, app.middleware.AppWrapper, app.Authenticator)
+
+	oauthProviders := make(map[string]authentication.OAuthProvider, len(app.OAuthProviders))
+	for name, provider := range app.OAuthProviders {
+		oauthProviders[name] = provider
+	}
+
+	authHandler := authentication.InitAuthModule(app.Store, app.middleware.AppWrapper, app.Authenticator, app.Revocations, app.Mailer, app.CacheStorage, app.Lockout, authentication.Config{
+		FrontendURL:    app.Config.FrontendURL,
+		MailExp:        app.Config.Mail.Exp,
+		IsProdEnv:      app.Config.Env == "production",
+		CacheEnabled:   app.Config.RedisCfg.Enabled,
+		TokenExp:       app.Config.Auth.Token.Exp,
+		RefreshExp:     app.Config.Auth.Token.RefreshExp,
+		TokenIss:       app.Config.Auth.Token.Iss,
+		TokenAud:       app.Config.Auth.Token.Aud,
+		PasswordPolicy: app.PasswordPolicy,
+		OAuthProviders: oauthProviders,
+
+		ImpersonationExp: app.Config.Auth.Token.ImpersonationExp,
+		WebAuthn:         app.WebAuthn,
+	})
+
+	rbacHandler := rbac.InitRBACModule(app.Store, app.middleware.AppWrapper, app.Roles, app.CacheStorage, rbac.Config{
+		CacheEnabled: app.Config.RedisCfg.Enabled,
+	})
+
+	privacyHandler := privacy.InitPrivacyModule(app.Store, app.middleware.AppWrapper, app.CacheStorage, app.privacyConfig())
+
+	abacHandler := accesscontrol.InitABACModule(app.Store, app.middleware.AppWrapper, app.Policies, app.Attributes, app.Roles)
 
 	r.Route("/users", func(r chi.Router) {
-		r.With(app.middleware.AuthTokenMiddleware, app.middleware.RequirePermission("user:read")).Get("/", userHandler.ListUsers)
-		r.With(app.middleware.AuthTokenMiddleware, app.middleware.RequirePermission("user:read")).Get("/{id}", userHandler.GetUser)
+		r.With(app.middleware.AuthenticateMiddleware, app.middleware.RequirePermission("user:read")).Get("/", userHandler.ListUsers)
+		r.With(app.middleware.AuthenticateMiddleware, app.middleware.RequirePolicy("user:read", app.userResource)).Get("/{id}", userHandler.GetUser)
 
 	})
 
 	r.Route("/auth", func(r chi.Router) {
 		r.Post("/register", authHandler.Register)
 		r.Post("/login", authHandler.Login)
+		r.Post("/refresh", authHandler.Refresh)
+		r.With(app.middleware.AuthTokenMiddleware).Post("/logout", authHandler.Logout)
+		r.With(app.middleware.AuthTokenMiddleware, app.middleware.DenyImpersonation).Post("/logout/all", authHandler.LogoutAll)
+		r.Put("/activate/{token}", authHandler.Activate)
+		r.Post("/activate/resend", authHandler.ResendActivation)
+		r.Post("/password/forgot", authHandler.ForgotPassword)
+		r.Post("/password/reset", authHandler.ResetPassword)
+		r.Post("/unlock", authHandler.UnlockAccount)
+		r.Post("/magic-link", authHandler.RequestMagicLink)
+		r.Post("/magic-link/consume", authHandler.ConsumeMagicLink)
+		r.Post("/email/confirm", authHandler.ConfirmEmailChange)
+		r.Post("/mfa/verify", authHandler.VerifyMFA)
+		r.Get("/oauth/{provider}", authHandler.OAuthStart)
+		r.Get("/oauth/{provider}/callback", authHandler.OAuthCallback)
+		r.With(app.middleware.AuthTokenMiddleware, app.middleware.DenyImpersonation).Post("/mfa/enroll", authHandler.EnrollMFA)
+		r.With(app.middleware.AuthTokenMiddleware, app.middleware.DenyImpersonation).Post("/mfa/enable", authHandler.EnableMFA)
+		r.With(app.middleware.AuthTokenMiddleware, app.middleware.DenyImpersonation).Post("/mfa/disable", authHandler.DisableMFA)
+		r.With(app.middleware.AuthTokenMiddleware).Get("/tokens", authHandler.ListTokens)
+		r.With(app.middleware.AuthTokenMiddleware, app.middleware.DenyImpersonation).Post("/tokens", authHandler.CreateToken)
+		r.With(app.middleware.AuthTokenMiddleware, app.middleware.DenyImpersonation).Delete("/tokens/{id}", authHandler.RevokeToken)
+
+		r.Route("/webauthn", func(r chi.Router) {
+			r.Post("/login/begin", authHandler.BeginWebAuthnLogin)
+			r.Post("/login/finish", authHandler.FinishWebAuthnLogin)
+			r.With(app.middleware.AuthTokenMiddleware, app.middleware.DenyImpersonation).Post("/register/begin", authHandler.BeginWebAuthnRegistration)
+			r.With(app.middleware.AuthTokenMiddleware, app.middleware.DenyImpersonation).Post("/register/finish", authHandler.FinishWebAuthnRegistration)
+			r.With(app.middleware.AuthTokenMiddleware).Get("/credentials", authHandler.ListWebAuthnCredentials)
+			r.With(app.middleware.AuthTokenMiddleware, app.middleware.DenyImpersonation).Delete("/credentials/{id}", authHandler.DeleteWebAuthnCredential)
+		})
+	})
+
+	r.Route("/me", func(r chi.Router) {
+		r.Use(app.middleware.AuthTokenMiddleware)
+		r.Get("/", authHandler.GetProfile)
+		r.Patch("/", authHandler.UpdateProfile)
+		r.With(app.middleware.DenyImpersonation).Delete("/", privacyHandler.RequestErasure)
+		r.Get("/erasure", privacyHandler.GetErasure)
+		r.With(app.middleware.DenyImpersonation).Delete("/erasure", privacyHandler.CancelErasure)
+		r.Get("/exports", privacyHandler.ListExports)
+		r.With(app.middleware.DenyImpersonation).Post("/exports", privacyHandler.RequestExport)
+		r.With(app.middleware.DenyImpersonation).Get("/exports/{id}/download", privacyHandler.DownloadExport)
+		r.With(app.middleware.DenyImpersonation).Put("/password", authHandler.ChangePassword)
+		r.With(app.middleware.DenyImpersonation).Post("/email", authHandler.RequestEmailChange)
+		r.With(app.middleware.DenyImpersonation).Post("/identities/{provider}", authHandler.LinkIdentity)
+		r.Get("/sessions", authHandler.ListSessions)
+		r.With(app.middleware.DenyImpersonation).Delete("/sessions/{id}", authHandler.RevokeSession)
+	})
+
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(app.middleware.AuthTokenMiddleware)
+		r.With(app.middleware.DenyImpersonation, app.middleware.RequirePermission("user:unlock")).Delete("/users/{id}/lockout", authHandler.ClearLockout)
+		r.With(app.middleware.DenyImpersonation, app.middleware.RequirePermission("user:impersonate")).Post("/users/{id}/impersonate", authHandler.Impersonate)
+
+		r.With(app.middleware.DenyImpersonation, app.middleware.RequirePermission("rbac:manage")).Put("/users/{id}/role", rbacHandler.AssignRole)
+
+		r.Route("/roles", func(r chi.Router) {
+			r.Use(app.middleware.DenyImpersonation, app.middleware.RequirePermission("rbac:manage"))
+			r.Get("/", rbacHandler.ListRoles)
+			r.Post("/", rbacHandler.CreateRole)
+			r.Get("/{id}", rbacHandler.GetRole)
+			r.Patch("/{id}", rbacHandler.UpdateRole)
+			r.Delete("/{id}", rbacHandler.DeleteRole)
+			r.Put("/{id}/permissions/{permissionID}", rbacHandler.AttachPermission)
+			r.Delete("/{id}/permissions/{permissionID}", rbacHandler.DetachPermission)
+		})
+
+		r.Route("/permissions", func(r chi.Router) {
+			r.Use(app.middleware.DenyImpersonation, app.middleware.RequirePermission("rbac:manage"))
+			r.Get("/", rbacHandler.ListPermissions)
+			r.Post("/", rbacHandler.CreatePermission)
+			r.Patch("/{id}", rbacHandler.UpdatePermission)
+			r.Delete("/{id}", rbacHandler.DeletePermission)
+		})
+
+		r.With(app.middleware.DenyImpersonation, app.middleware.RequirePermission("policy:manage")).Get("/users/{id}/attributes", abacHandler.GetAttributes)
+		r.With(app.middleware.DenyImpersonation, app.middleware.RequirePermission("policy:manage")).Put("/users/{id}/attributes", abacHandler.SetAttributes)
+
+		r.Route("/policies", func(r chi.Router) {
+			r.Use(app.middleware.DenyImpersonation, app.middleware.RequirePermission("policy:manage"))
+			r.Get("/", abacHandler.ListPolicies)
+			r.Post("/evaluate", abacHandler.Evaluate)
+			r.Post("/reload", abacHandler.ReloadPolicies)
+		})
+
+		r.Route("/service-accounts", func(r chi.Router) {
+			r.Use(app.middleware.DenyImpersonation, app.middleware.RequirePermission("service_account:manage"))
+			r.Get("/", authHandler.ListServiceAccounts)
+			r.Post("/", authHandler.CreateServiceAccount)
+			r.Post("/{id}/secret", authHandler.RotateServiceAccountSecret)
+			r.Delete("/{id}", authHandler.DeleteServiceAccount)
+		})
+	})
+}
+
+// userResource describes the user in the URL for ABAC policies.
+func (app *Application) userResource(r *http.Request) (map[string]any, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return nil, abac.ErrNotFound
+	}
+	return app.Attributes.UserByID(r.Context(), id)
+}
+
+// mountPostRoutes lets holders of post:create write posts, authors edit
+// and delete their own, and holders of post:update / post:delete anyone's.
+func (app *Application) mountPostRoutes(r chi.Router) {
+	postHandler := post.InitPostModule(app.Store, app.middleware.AppWrapper)
+
+	editPost := policy.Policy{
+		Load:  postHandler.LoadPost,
+		Rules: []policy.Rule{policy.Owner(), policy.Permission("post:update")},
+	}
+	deletePost := policy.Policy{
+		Load:  postHandler.LoadPost,
+		Rules: []policy.Rule{policy.Owner(), policy.Permission("post:delete")},
+	}
+
+	r.Route("/posts", func(r chi.Router) {
+		r.Use(app.middleware.AuthTokenMiddleware)
+		r.With(app.middleware.RequirePermission("post:create")).Post("/", postHandler.CreatePost)
+		r.Get("/{id}", postHandler.GetPost)
+		r.With(app.middleware.Authorize(editPost)).Patch("/{id}", postHandler.UpdatePost)
+		r.With(app.middleware.Authorize(deletePost)).Delete("/{id}", postHandler.DeletePost)
 	})
 }
 
+// permissionsChannel is notified by triggers on roles, permissions and
+// roles_permissions, see migration 000021.
+const permissionsChannel = "role_permissions_changed"
+
+// permissionListenerRetry is the pause before reconnecting a failed
+// listener.
+const permissionListenerRetry = time.Second * 5
+
+// RunPermissionListener drops the cached role permissions whenever they
+// change in Postgres, keeping every API instance in sync. The cache is
+// also dropped after each (re)connect since notifications sent while
+// disconnected are lost.
+func (app *Application) RunPermissionListener(ctx context.Context) {
+	invalidate := func() {
+		if err := app.CacheStorage.Permissions.Invalidate(ctx); err != nil {
+			app.Logger.Errorw("permission cache invalidation failed", "error", err.Error())
+		}
+	}
+
+	for {
+		err := app.Store.Listen(ctx, permissionsChannel, invalidate, func(string) { invalidate() })
+		if ctx.Err() != nil {
+			return
+		}
+		app.Logger.Errorw("permission listener stopped, reconnecting", "error", err.Error())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(permissionListenerRetry):
+		}
+	}
+}
+
+// RunPolicyReloader reloads the ABAC policy file on SIGHUP until ctx is
+// cancelled. An invalid file is logged and the current policies are kept.
+func (app *Application) RunPolicyReloader(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := app.Policies.Reload(); err != nil {
+				app.Logger.Errorw("policy reload failed", "error", err.Error())
+				continue
+			}
+			app.Logger.Infow("policies reloaded", "policies", len(app.Policies.Policies().Policies))
+		}
+	}
+}
+
+// RunPrivacyWorker builds data exports and carries out due account
+// erasures until ctx is cancelled.
+func (app *Application) RunPrivacyWorker(ctx context.Context) {
+	privacy.NewWorker(app.Store, app.CacheStorage, app.Logger, app.privacyConfig()).Run(ctx)
+}
+
+func (app *Application) privacyConfig() privacy.Config {
+	return privacy.Config{
+		CacheEnabled:       app.Config.RedisCfg.Enabled,
+		ErasureGracePeriod: app.Config.Privacy.ErasureGracePeriod,
+		ExportExp:          app.Config.Privacy.ExportExp,
+		PollInterval:       app.Config.Privacy.PollInterval,
+	}
+}
+
+func (app *Application) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	jwks := auth.JWKSet{Keys: []auth.JWK{}}
+	if publisher, ok := app.Authenticator.(auth.KeyPublisher); ok {
+		jwks = publisher.JWKS()
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	utils.WriteJSON(w, http.StatusOK, jwks)
+}
+
 func (app *Application) Run(mux http.Handler) error {
 	// Docs
 	docs.SwaggerInfo.Version = version
@@ -0,0 +1,130 @@
+package abac
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	"github.com/mifaabiyyu/backend-go/internal/hierarchy"
+	"github.com/mifaabiyyu/backend-go/utils"
+)
+
+type Handler struct {
+	Service Service
+	*utils.AppWrapper
+}
+
+func (h *Handler) ListPolicies(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, h.Service.Policies())
+}
+
+func (h *Handler) ReloadPolicies(w http.ResponseWriter, r *http.Request) {
+	res, err := h.Service.Reload()
+	if err != nil {
+		h.errorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) Evaluate(w http.ResponseWriter, r *http.Request) {
+	var input EvaluateRequest
+	if !h.readInput(w, r, &input) {
+		return
+	}
+
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	res, err := h.Service.Evaluate(r.Context(), input, ip)
+	if err != nil {
+		h.errorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) GetAttributes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.urlID(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := h.Service.GetAttributes(r.Context(), userID)
+	if err != nil {
+		h.errorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) SetAttributes(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("user not authenticated"))
+		return
+	}
+
+	userID, ok := h.urlID(w, r)
+	if !ok {
+		return
+	}
+
+	var input AttributesRequest
+	if !h.readInput(w, r, &input) {
+		return
+	}
+
+	res, err := h.Service.SetAttributes(r.Context(), int64(user.RoleID.Int32), userID, input.Attributes)
+	if err != nil {
+		h.errorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) errorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		h.NotFoundResponse(w, r, err)
+	case errors.Is(err, hierarchy.ErrOutranked):
+		h.ForbiddenResponse(w, r, err)
+	case errors.Is(err, ErrInvalidAttributes):
+		h.BadRequestResponse(w, r, err)
+	case errors.Is(err, ErrInvalidPolicies):
+		// The error points at the problem in the policy file.
+		h.ConflictResponse(w, r, err)
+	default:
+		h.InternalServerError(w, r, err)
+	}
+}
+
+func (h *Handler) readInput(w http.ResponseWriter, r *http.Request, input any) bool {
+	if err := utils.ReadJSON(w, r, input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return false
+	}
+	if err := utils.Validate.Struct(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return false
+	}
+	return true
+}
+
+func (h *Handler) urlID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id < 1 {
+		h.BadRequestResponse(w, r, errors.New("invalid user id"))
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,38 @@
+package abac
+
+import (
+	"time"
+
+	engine "github.com/mifaabiyyu/backend-go/internal/abac"
+)
+
+// EvaluateRequest describes a request to decide without making it. The
+// attributes of real users can be loaded by id, the maps then only
+// override some of them.
+type EvaluateRequest struct {
+	Action         string         `json:"action" validate:"required,max=255"`
+	SubjectUserID  int64          `json:"subject_user_id" validate:"omitempty,min=1"`
+	Subject        map[string]any `json:"subject"`
+	ResourceUserID int64          `json:"resource_user_id" validate:"omitempty,min=1"`
+	Resource       map[string]any `json:"resource"`
+	// Time defaults to now and IP to the caller's address.
+	Time        *time.Time     `json:"time"`
+	IP          string         `json:"ip" validate:"omitempty,ip"`
+	Environment map[string]any `json:"environment"`
+}
+
+type EvaluateResponse struct {
+	engine.Decision
+	// Fallback tells what RequirePolicy does when no policy applies.
+	Fallback string         `json:"fallback,omitempty"`
+	Request  engine.Request `json:"request"`
+}
+
+type AttributesRequest struct {
+	Attributes map[string]any `json:"attributes" validate:"required,max=32"`
+}
+
+type AttributesResponse struct {
+	UserID     int64          `json:"user_id"`
+	Attributes map[string]any `json:"attributes"`
+}
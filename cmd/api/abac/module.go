@@ -0,0 +1,19 @@
+package abac
+
+import (
+	engine "github.com/mifaabiyyu/backend-go/internal/abac"
+	"github.com/mifaabiyyu/backend-go/internal/hierarchy"
+	"github.com/mifaabiyyu/backend-go/internal/store"
+	"github.com/mifaabiyyu/backend-go/utils"
+)
+
+func InitABACModule(store *store.Store, wrapper *utils.AppWrapper, policies *engine.Engine, attributes *engine.Directory, roles *hierarchy.Guard) *Handler {
+	repo := NewABACRepository(store)
+
+	service := NewABACService(repo, policies, attributes, roles)
+
+	return &Handler{
+		Service:    service,
+		AppWrapper: wrapper,
+	}
+}
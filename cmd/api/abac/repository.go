@@ -0,0 +1,42 @@
+package abac
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/store"
+)
+
+type Repository interface {
+	GetUserByID(ctx context.Context, userID int64) (sqlc.User, error)
+	UpsertUserAttributes(ctx context.Context, userID int64, attributes []byte) error
+}
+
+type abacRepo struct {
+	q *sqlc.Queries
+}
+
+func NewABACRepository(store *store.Store) Repository {
+	return &abacRepo{q: store.Queries}
+}
+
+func (r *abacRepo) GetUserByID(ctx context.Context, userID int64) (sqlc.User, error) {
+	user, err := r.q.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.User{}, ErrNotFound
+		}
+		return sqlc.User{}, err
+	}
+	return user, nil
+}
+
+func (r *abacRepo) UpsertUserAttributes(ctx context.Context, userID int64, attributes []byte) error {
+	_, err := r.q.UpsertUserAttributes(ctx, sqlc.UpsertUserAttributesParams{
+		UserID:     userID,
+		Attributes: attributes,
+	})
+	return err
+}
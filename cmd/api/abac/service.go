@@ -0,0 +1,187 @@
+package abac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"time"
+
+	engine "github.com/mifaabiyyu/backend-go/internal/abac"
+	"github.com/mifaabiyyu/backend-go/internal/hierarchy"
+)
+
+var (
+	ErrNotFound          = errors.New("user not found")
+	ErrInvalidPolicies   = errors.New("the policy file is invalid, the current policies are kept")
+	ErrInvalidAttributes = errors.New("attribute names must start with a letter and contain only lowercase letters, numbers and _, values must be strings, numbers, booleans or lists of strings")
+)
+
+var attributeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+const maxAttributeLength = 255
+
+type Service interface {
+	Policies() *engine.PolicySet
+	Reload() (*engine.PolicySet, error)
+	Evaluate(ctx context.Context, req EvaluateRequest, ip string) (*EvaluateResponse, error)
+	GetAttributes(ctx context.Context, userID int64) (*AttributesResponse, error)
+	SetAttributes(ctx context.Context, actorRoleID, userID int64, attributes map[string]any) (*AttributesResponse, error)
+}
+
+type abacService struct {
+	repo       Repository
+	policies   *engine.Engine
+	attributes *engine.Directory
+	roles      *hierarchy.Guard
+}
+
+func NewABACService(repo Repository, policies *engine.Engine, attributes *engine.Directory, roles *hierarchy.Guard) Service {
+	return &abacService{
+		repo:       repo,
+		policies:   policies,
+		attributes: attributes,
+		roles:      roles,
+	}
+}
+
+func (s *abacService) Policies() *engine.PolicySet {
+	return s.policies.Policies()
+}
+
+// Reload only reloads this API instance, other instances keep their
+// policies until reloaded themselves.
+func (s *abacService) Reload() (*engine.PolicySet, error) {
+	if err := s.policies.Reload(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPolicies, err)
+	}
+	return s.policies.Policies(), nil
+}
+
+// Evaluate decides req the way RequirePolicy would, with a trace of every
+// policy.
+func (s *abacService) Evaluate(ctx context.Context, req EvaluateRequest, ip string) (*EvaluateResponse, error) {
+	subject, err := s.describeUser(ctx, req.SubjectUserID, req.Subject)
+	if err != nil {
+		return nil, err
+	}
+	resource, err := s.describeUser(ctx, req.ResourceUserID, req.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if req.Time != nil {
+		now = *req.Time
+	}
+	if req.IP != "" {
+		ip = req.IP
+	}
+	environment := s.policies.Environment(now, ip)
+	maps.Copy(environment, req.Environment)
+
+	request := engine.Request{
+		Subject:     subject,
+		Resource:    resource,
+		Action:      req.Action,
+		Environment: environment,
+	}
+
+	res := &EvaluateResponse{
+		Decision: s.policies.Explain(request),
+		Request:  request,
+	}
+	if res.Effect == engine.NotApplicable {
+		res.Fallback = "no policy applies, the request needs the " + req.Action + " permission"
+	}
+	return res, nil
+}
+
+// describeUser loads the attributes of userID when set and applies
+// overrides on top.
+func (s *abacService) describeUser(ctx context.Context, userID int64, overrides map[string]any) (map[string]any, error) {
+	described := map[string]any{}
+	if userID != 0 {
+		var err error
+		described, err = s.attributes.UserByID(ctx, userID)
+		if err != nil {
+			if errors.Is(err, engine.ErrNotFound) {
+				return nil, ErrNotFound
+			}
+			return nil, err
+		}
+	}
+
+	maps.Copy(described, overrides)
+	return described, nil
+}
+
+func (s *abacService) GetAttributes(ctx context.Context, userID int64) (*AttributesResponse, error) {
+	if _, err := s.repo.GetUserByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	attributes, err := s.attributes.UserAttributes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AttributesResponse{UserID: userID, Attributes: attributes}, nil
+}
+
+// SetAttributes replaces the attributes of a user. They can widen what
+// policies allow, so only users below the actor's level can be changed.
+func (s *abacService) SetAttributes(ctx context.Context, actorRoleID, userID int64, attributes map[string]any) (*AttributesResponse, error) {
+	if err := validateAttributes(attributes); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.roles.CanManageUser(ctx, actorRoleID, int64(user.RoleID.Int32)); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpsertUserAttributes(ctx, userID, data); err != nil {
+		return nil, err
+	}
+
+	return &AttributesResponse{UserID: userID, Attributes: attributes}, nil
+}
+
+func validateAttributes(attributes map[string]any) error {
+	for name, value := range attributes {
+		if !attributeNamePattern.MatchString(name) || !validAttribute(value, true) {
+			return ErrInvalidAttributes
+		}
+	}
+	return nil
+}
+
+func validAttribute(value any, allowList bool) bool {
+	switch v := value.(type) {
+	case string:
+		return len(v) <= maxAttributeLength
+	case float64, bool:
+		return true
+	case []any:
+		if !allowList {
+			return false
+		}
+		for _, e := range v {
+			if s, ok := e.(string); !ok || !validAttribute(s, false) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -3,16 +3,26 @@ package auth
 import (
 	"encoding/json"
 	"errors"
+	"io"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	"github.com/mifaabiyyu/backend-go/internal/lockout"
+	"github.com/mifaabiyyu/backend-go/internal/password"
 	"github.com/mifaabiyyu/backend-go/utils"
 )
 
 type Handler struct {
 	Service Service
 	*utils.AppWrapper
+	cfg Config
 }
 
 func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
@@ -25,45 +35,28 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 
 	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
 	req.Username = strings.TrimSpace(req.Username)
-	req.Password = strings.TrimSpace(req.Password)
-
-	// Validasi dengan validator
-	if err := utils.Validate.Struct(req); err != nil {
-		var messages []string
-		for _, e := range err.(validator.ValidationErrors) {
-			field := strings.ToLower(e.Field())
-			switch e.Tag() {
-			case "required":
-				messages = append(messages, field+" is required")
-			case "email":
-				messages = append(messages, "invalid email format")
-			case "min":
-				messages = append(messages, field+" must be at least "+e.Param()+" characters")
-			case "max":
-				messages = append(messages, field+" must be at most "+e.Param()+" characters")
-			case "alphanum":
-				messages = append(messages, field+" must contain only letters and numbers")
-			default:
-				messages = append(messages, "invalid "+field)
-			}
-		}
-		h.BadRequestResponse(w, r, errors.New(strings.Join(messages, ", ")))
-		return
-	}
+	req.FullName = strings.TrimSpace(req.FullName)
 
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
+	if err := validate(req); err != nil {
 		h.BadRequestResponse(w, r, err)
 		return
 	}
 
 	user, err := h.Service.Register(r.Context(), req)
 	if err != nil {
-		h.BadRequestResponse(w, r, err)
+		var policyErr *password.PolicyError
+		switch {
+		case errors.As(err, &policyErr):
+			h.FailedValidationResponse(w, r, err, policyErr.Fields())
+		case errors.Is(err, ErrEmailExists):
+			h.ConflictResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusCreated, user)
+	utils.WriteJSON(w, http.StatusCreated, profileResponse(*user))
 }
 
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
@@ -74,42 +67,249 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	input.Email = strings.TrimSpace(strings.ToLower(input.Email))
-	input.Password = strings.TrimSpace(input.Password)
-
-	if err := utils.Validate.Struct(input); err != nil {
-		var messages []string
-		for _, e := range err.(validator.ValidationErrors) {
-			field := strings.ToLower(e.Field())
-			switch e.Tag() {
-			case "required":
-				messages = append(messages, field+" is required")
-			case "email":
-				messages = append(messages, "invalid email format")
-			case "min":
-				messages = append(messages, field+" must be at least "+e.Param()+" characters")
-			case "max":
-				messages = append(messages, field+" must be at most "+e.Param()+" characters")
-			case "alphanum":
-				messages = append(messages, field+" must contain only letters and numbers")
-			default:
-				messages = append(messages, "invalid "+field)
-			}
+
+	if err := validate(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	res, err := h.Service.Login(r.Context(), input.Email, input.Password, clientMeta(r))
+	if err != nil {
+		var locked *lockout.LockedError
+		switch {
+		case errors.As(err, &locked):
+			h.RateLimitExceededResponse(w, r, retryAfterSeconds(locked.RetryAfter))
+		case errors.Is(err, ErrNotVerified):
+			h.ForbiddenResponse(w, r, err)
+		default:
+			h.UnauthorizedErrorResponse(w, r, err)
 		}
-		h.BadRequestResponse(w, r, errors.New(strings.Join(messages, ", ")))
 		return
 	}
 
-	validate := validator.New()
-	if err := validate.Struct(input); err != nil {
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var input RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := validate(input); err != nil {
 		h.BadRequestResponse(w, r, err)
 		return
 	}
 
-	token, err := h.Service.Login(r.Context(), input.Email, input.Password)
+	res, err := h.Service.Refresh(r.Context(), input.RefreshToken, clientMeta(r))
 	if err != nil {
-		h.UnauthorizedErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, ErrInvalidToken), errors.Is(err, ErrTokenReused):
+			h.UnauthorizedErrorResponse(w, r, err)
+		case errors.Is(err, ErrNotVerified):
+			h.ForbiddenResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("user not authenticated"))
+		return
+	}
+
+	// The body is optional, a refresh token only needs to be sent when the
+	// client wants it revoked together with the access token.
+	var input LogoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && !errors.Is(err, io.EOF) {
+		h.BadRequestResponse(w, r, err)
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, LoginResponse{Token: token})
+	if err := h.Service.Logout(r.Context(), claims, input.RefreshToken); err != nil {
+		h.InternalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("user not authenticated"))
+		return
+	}
+
+	if err := h.Service.LogoutAll(r.Context(), claims.UserID); err != nil {
+		h.InternalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) Activate(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	if err := h.Service.Activate(r.Context(), token); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidToken):
+			h.NotFoundResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ResendActivation(w http.ResponseWriter, r *http.Request) {
+	var input EmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	input.Email = strings.TrimSpace(strings.ToLower(input.Email))
+
+	if err := validate(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := h.Service.ResendActivation(r.Context(), input.Email); err != nil {
+		h.InternalServerError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, MessageResponse{
+		Message: "if the account exists and is not activated, a new activation email has been sent",
+	})
+}
+
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var input EmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	input.Email = strings.TrimSpace(strings.ToLower(input.Email))
+
+	if err := validate(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	// Failures are only logged, the response has to look the same whether
+	// or not the email belongs to an account.
+	if err := h.Service.ForgotPassword(r.Context(), input.Email); err != nil {
+		h.Logger.Errorw("forgot password failed", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, MessageResponse{
+		Message: "if the account exists, a password reset email has been sent",
+	})
+}
+
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var input ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := validate(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := h.Service.ResetPassword(r.Context(), input.Token, input.Password); err != nil {
+		var policyErr *password.PolicyError
+		switch {
+		case errors.As(err, &policyErr):
+			h.FailedValidationResponse(w, r, err, policyErr.Fields())
+		case errors.Is(err, ErrInvalidToken):
+			h.BadRequestResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// maxUserAgentLength bounds what is stored per session, the header is
+// entirely client controlled.
+const maxUserAgentLength = 512
+
+// clientMeta describes the caller. RemoteAddr has already been rewritten by
+// the RealIP middleware, the port is dropped so every connection from one
+// host shares the same IP.
+func clientMeta(r *http.Request) ClientMeta {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+
+	return ClientMeta{
+		IP:        ip,
+		UserAgent: userAgent,
+	}
+}
+
+// retryAfterSeconds formats d for the Retry-After header, rounding up so
+// clients never retry too early.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// validate runs the struct validator and turns its errors into a single
+// human readable message.
+func validate(input any) error {
+	err := utils.Validate.Struct(input)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	var messages []string
+	for _, e := range validationErrors {
+		field := strings.ToLower(e.Field())
+		switch e.Tag() {
+		case "required":
+			messages = append(messages, field+" is required")
+		case "email":
+			messages = append(messages, "invalid email format")
+		case "min":
+			messages = append(messages, field+" must be at least "+e.Param()+" characters")
+		case "max":
+			messages = append(messages, field+" must be at most "+e.Param()+" characters")
+		case "alphanum":
+			messages = append(messages, field+" must contain only letters and numbers")
+		default:
+			messages = append(messages, "invalid "+field)
+		}
+	}
+
+	return errors.New(strings.Join(messages, ", "))
 }
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	"github.com/mifaabiyyu/backend-go/utils"
+)
+
+func (h *Handler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("user not authenticated"))
+		return
+	}
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || userID < 1 {
+		h.BadRequestResponse(w, r, errors.New("invalid user id"))
+		return
+	}
+
+	var input ImpersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := validate(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	res, err := h.Service.Impersonate(r.Context(), claims, userID, input.Reason, clientMeta(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			h.NotFoundResponse(w, r, err)
+		case errors.Is(err, ErrImpersonateSelf):
+			h.BadRequestResponse(w, r, err)
+		case errors.Is(err, ErrImpersonationForbidden), errors.Is(err, ErrImpersonating):
+			h.ForbiddenResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	h.Logger.Infow("impersonation started", "actor_id", claims.UserID, "user_id", userID, "expires_at", res.ExpiresAt)
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handler) UnlockAccount(w http.ResponseWriter, r *http.Request) {
+	var input UnlockAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := validate(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := h.Service.UnlockAccount(r.Context(), input.Token); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidToken):
+			h.BadRequestResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ClearLockout lets an admin unlock a user before the lockout expires.
+func (h *Handler) ClearLockout(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || userID < 1 {
+		h.BadRequestResponse(w, r, errors.New("invalid user id"))
+		return
+	}
+
+	if err := h.Service.ClearLockout(r.Context(), userID); err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			h.NotFoundResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/mifaabiyyu/backend-go/utils"
+)
+
+func (h *Handler) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	var input EmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	input.Email = strings.TrimSpace(strings.ToLower(input.Email))
+
+	if err := validate(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	// Same as ForgotPassword, the response must not reveal whether the
+	// email belongs to an account.
+	if err := h.Service.RequestMagicLink(r.Context(), input.Email); err != nil {
+		h.Logger.Errorw("magic link request failed", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, MessageResponse{
+		Message: "if the account exists, a sign-in link has been sent",
+	})
+}
+
+func (h *Handler) ConsumeMagicLink(w http.ResponseWriter, r *http.Request) {
+	var input MagicLinkConsumeRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := validate(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	res, err := h.Service.ConsumeMagicLink(r.Context(), input.Token, clientMeta(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidToken):
+			h.UnauthorizedErrorResponse(w, r, err)
+		case errors.Is(err, ErrNotVerified):
+			h.ForbiddenResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
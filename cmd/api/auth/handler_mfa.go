@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	"github.com/mifaabiyyu/backend-go/internal/lockout"
+	"github.com/mifaabiyyu/backend-go/utils"
+)
+
+func (h *Handler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("user not authenticated"))
+		return
+	}
+
+	res, err := h.Service.EnrollMFA(r.Context(), claims.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrMFAAlreadyEnabled):
+			h.ConflictResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) EnableMFA(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("user not authenticated"))
+		return
+	}
+
+	var input MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := validate(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	res, err := h.Service.EnableMFA(r.Context(), claims.UserID, input.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrMFAAlreadyEnabled):
+			h.ConflictResponse(w, r, err)
+		case errors.Is(err, ErrMFANotEnabled), errors.Is(err, ErrInvalidMFACode):
+			h.BadRequestResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("user not authenticated"))
+		return
+	}
+
+	var input MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := validate(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := h.Service.DisableMFA(r.Context(), claims.UserID, input.Code, clientMeta(r)); err != nil {
+		var locked *lockout.LockedError
+		switch {
+		case errors.As(err, &locked):
+			h.RateLimitExceededResponse(w, r, retryAfterSeconds(locked.RetryAfter))
+		case errors.Is(err, ErrMFANotEnabled), errors.Is(err, ErrInvalidMFACode):
+			h.BadRequestResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var input MFAVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := validate(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	res, err := h.Service.VerifyMFA(r.Context(), input.MFAToken, input.Code, clientMeta(r))
+	if err != nil {
+		var locked *lockout.LockedError
+		switch {
+		case errors.As(err, &locked):
+			h.RateLimitExceededResponse(w, r, retryAfterSeconds(locked.RetryAfter))
+		case errors.Is(err, ErrInvalidToken), errors.Is(err, ErrInvalidMFACode):
+			h.UnauthorizedErrorResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
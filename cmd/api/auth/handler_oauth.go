@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mifaabiyyu/backend-go/utils"
+)
+
+const oauthStateCookie = "oauth_state"
+
+// OAuthStart redirects the browser to the identity provider. The state is
+// also stored in a cookie so the callback can tell the sign-in was started
+// by the same browser.
+func (h *Handler) OAuthStart(w http.ResponseWriter, r *http.Request) {
+	res, err := h.Service.OAuthStart(r.Context(), chi.URLParam(r, "provider"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUnknownProvider):
+			h.NotFoundResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	h.setOAuthStateCookie(w, res.State)
+	http.Redirect(w, r, res.AuthURL, http.StatusFound)
+}
+
+// LinkIdentity starts a sign-in whose identity is linked to the current
+// user. It is called with the access token, so the provider URL is
+// returned for the frontend to open instead of a redirect.
+func (h *Handler) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := h.Service.OAuthLinkStart(r.Context(), user.ID, chi.URLParam(r, "provider"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUnknownProvider):
+			h.NotFoundResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	h.setOAuthStateCookie(w, res.State)
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) setOAuthStateCookie(w http.ResponseWriter, state string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/v1/auth/oauth",
+		MaxAge:   int(oauthStateExp.Seconds()),
+		HttpOnly: true,
+		Secure:   h.cfg.IsProdEnv,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// OAuthCallback is the redirect URI registered with the provider. The
+// outcome is handed to the frontend in the URL fragment, which never
+// reaches server logs.
+func (h *Handler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	state := query.Get("state")
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Path:     "/v1/auth/oauth",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   h.cfg.IsProdEnv,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	// Provider errors are not echoed, the frontend only gets known codes.
+	if providerErr := query.Get("error"); providerErr != "" {
+		h.Logger.Warnw("oauth provider returned an error", "path", r.URL.Path, "error", providerErr)
+		h.oauthRedirect(w, r, url.Values{"error": {"access_denied"}})
+		return
+	}
+
+	cookie, err := r.Cookie(oauthStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		h.Logger.Warnw("oauth state mismatch", "path", r.URL.Path)
+		h.oauthRedirect(w, r, url.Values{"error": {"invalid_state"}})
+		return
+	}
+
+	res, err := h.Service.OAuthCallback(r.Context(), chi.URLParam(r, "provider"), query.Get("code"), state, clientMeta(r))
+	if err != nil {
+		var code string
+		switch {
+		case errors.Is(err, ErrUnknownProvider):
+			h.NotFoundResponse(w, r, err)
+			return
+		case errors.Is(err, ErrInvalidToken):
+			code = "invalid_state"
+		case errors.Is(err, ErrOAuthEmailUnverified):
+			code = "email_not_verified"
+		case errors.Is(err, ErrOAuthLinkRequired):
+			code = "link_required"
+		case errors.Is(err, ErrIdentityLinked):
+			code = "identity_in_use"
+		case errors.Is(err, ErrOAuthFailed):
+			code = "access_denied"
+		default:
+			code = "server_error"
+		}
+
+		h.Logger.Warnw("oauth sign-in failed", "path", r.URL.Path, "error", err.Error())
+		h.oauthRedirect(w, r, url.Values{"error": {code}})
+		return
+	}
+
+	values := url.Values{"expires_at": {res.ExpiresAt.Format(time.RFC3339)}}
+	if res.MFARequired {
+		values.Set("mfa_required", strconv.FormatBool(res.MFARequired))
+		values.Set("mfa_token", res.MFAToken)
+	} else {
+		values.Set("token", res.Token)
+		values.Set("refresh_token", res.RefreshToken)
+	}
+
+	h.oauthRedirect(w, r, values)
+}
+
+func (h *Handler) oauthRedirect(w http.ResponseWriter, r *http.Request, values url.Values) {
+	target := fmt.Sprintf("%s/oauth/callback#%s", h.cfg.FrontendURL, values.Encode())
+	http.Redirect(w, r, target, http.StatusFound)
+}
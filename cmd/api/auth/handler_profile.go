@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/password"
+	"github.com/mifaabiyyu/backend-go/utils"
+)
+
+// The profile is read with any bearer token, but changing it takes a login
+// session: a personal access token has no claims and is rejected by
+// sessionUser.
+
+func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("user not authenticated"))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, profileResponse(*user))
+}
+
+func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := validate(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	res, err := h.Service.UpdateProfile(r.Context(), user.ID, input)
+	if err != nil {
+		h.InternalServerError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := validate(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	res, err := h.Service.ChangePassword(r.Context(), user.ID, input, clientMeta(r))
+	if err != nil {
+		var policyErr *password.PolicyError
+		switch {
+		case errors.As(err, &policyErr):
+			h.FailedValidationResponse(w, r, err, policyErr.Fields())
+		case errors.Is(err, ErrInvalidCredentials):
+			h.ForbiddenResponse(w, r, errors.New("current password is incorrect"))
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) RequestEmailChange(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	input.NewEmail = strings.TrimSpace(strings.ToLower(input.NewEmail))
+
+	if err := validate(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := h.Service.RequestEmailChange(r.Context(), user.ID, input); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidCredentials):
+			h.ForbiddenResponse(w, r, errors.New("current password is incorrect"))
+		case errors.Is(err, ErrSameEmail):
+			h.BadRequestResponse(w, r, err)
+		case errors.Is(err, ErrEmailExists):
+			h.ConflictResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, MessageResponse{
+		Message: "a confirmation link has been sent to the new email",
+	})
+}
+
+func (h *Handler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var input ConfirmEmailChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := validate(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := h.Service.ConfirmEmailChange(r.Context(), input.Token); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidToken):
+			h.BadRequestResponse(w, r, err)
+		case errors.Is(err, ErrEmailExists):
+			h.ConflictResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sessionUser returns the user of a request made with a login session and
+// writes a 401 for anything else.
+func (h *Handler) sessionUser(w http.ResponseWriter, r *http.Request) (*sqlc.User, bool) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("user not authenticated"))
+		return nil, false
+	}
+
+	if _, ok := auth.ClaimsFromContext(r.Context()); !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("this action requires a login session"))
+		return nil, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	"github.com/mifaabiyyu/backend-go/utils"
+)
+
+func (h *Handler) CreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("user not authenticated"))
+		return
+	}
+
+	var input CreateServiceAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := validate(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	res, err := h.Service.CreateServiceAccount(r.Context(), claims.UserID, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUnknownRole):
+			h.BadRequestResponse(w, r, err)
+		case errors.Is(err, ErrRoleTooHigh):
+			h.ForbiddenResponse(w, r, err)
+		case errors.Is(err, ErrServiceAccountExists):
+			h.ConflictResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, res)
+}
+
+func (h *Handler) ListServiceAccounts(w http.ResponseWriter, r *http.Request) {
+	res, err := h.Service.ListServiceAccounts(r.Context())
+	if err != nil {
+		h.InternalServerError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) RotateServiceAccountSecret(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id < 1 {
+		h.BadRequestResponse(w, r, errors.New("invalid service account id"))
+		return
+	}
+
+	res, err := h.Service.RotateServiceAccountSecret(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			h.NotFoundResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) DeleteServiceAccount(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id < 1 {
+		h.BadRequestResponse(w, r, errors.New("invalid service account id"))
+		return
+	}
+
+	if err := h.Service.DeleteServiceAccount(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			h.NotFoundResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	"github.com/mifaabiyyu/backend-go/utils"
+)
+
+func (h *Handler) BeginWebAuthnRegistration(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("user not authenticated"))
+		return
+	}
+
+	res, err := h.Service.BeginWebAuthnRegistration(r.Context(), claims.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPasskeysDisabled):
+			h.NotFoundResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) FinishWebAuthnRegistration(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("user not authenticated"))
+		return
+	}
+
+	var input WebAuthnRegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := validate(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	res, err := h.Service.FinishWebAuthnRegistration(r.Context(), claims.UserID, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPasskeysDisabled):
+			h.NotFoundResponse(w, r, err)
+		case errors.Is(err, ErrInvalidToken), errors.Is(err, ErrPasskeyInvalid):
+			h.BadRequestResponse(w, r, err)
+		case errors.Is(err, ErrPasskeyExists):
+			h.ConflictResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, res)
+}
+
+func (h *Handler) BeginWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
+	res, err := h.Service.BeginWebAuthnLogin(r.Context())
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPasskeysDisabled):
+			h.NotFoundResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) FinishWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
+	var input WebAuthnLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	res, err := h.Service.FinishWebAuthnLogin(r.Context(), input, clientMeta(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPasskeysDisabled):
+			h.NotFoundResponse(w, r, err)
+		case errors.Is(err, ErrInvalidToken), errors.Is(err, ErrPasskeyInvalid):
+			// The details stay in the log, the client only learns that
+			// the passkey was not accepted.
+			h.Logger.Warnw("passkey login rejected", "path", r.URL.Path, "error", err.Error())
+			h.UnauthorizedErrorResponse(w, r, ErrPasskeyInvalid)
+		case errors.Is(err, ErrNotVerified):
+			h.ForbiddenResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) ListWebAuthnCredentials(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("user not authenticated"))
+		return
+	}
+
+	res, err := h.Service.ListWebAuthnCredentials(r.Context(), claims.UserID)
+	if err != nil {
+		h.InternalServerError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) DeleteWebAuthnCredential(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("user not authenticated"))
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id < 1 {
+		h.BadRequestResponse(w, r, errors.New("invalid passkey id"))
+		return
+	}
+
+	if err := h.Service.DeleteWebAuthnCredential(r.Context(), claims.UserID, id); err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			h.NotFoundResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
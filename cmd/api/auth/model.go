@@ -1,8 +1,14 @@
 package auth
 
+import (
+	"time"
+
+	"github.com/mifaabiyyu/backend-go/internal/webauthn"
+)
+
 type RegisterRequest struct {
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=8"`
+	Password string `json:"password" validate:"required"`
 	Username string `json:"username" validate:"required"`
 	FullName string `json:"fullname" validate:"required"`
 }
@@ -13,5 +19,173 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token        string    `json:"token,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	// MFARequired is set instead of the tokens above when the account has
+	// two-factor authentication enabled; MFAToken must then be exchanged
+	// at /auth/mfa/verify.
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type MFAEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// ClientMeta is what we know about the client making a request.
+type ClientMeta struct {
+	IP        string
+	UserAgent string
+}
+
+type UnlockAccountRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type OAuthStartResponse struct {
+	AuthURL string `json:"auth_url"`
+	State   string `json:"-"`
+}
+
+type MagicLinkConsumeRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type CreateTokenRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,required"`
+	// ExpiresInDays defaults to 30 days.
+	ExpiresInDays int `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+}
+
+type TokenResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// Token is only returned once, when the token is created.
+	Token string `json:"token,omitempty"`
+}
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// Current marks the session of the token making the request.
+	Current bool `json:"current"`
+}
+
+type ImpersonateRequest struct {
+	// Reason is kept in the audit log, e.g. the support ticket.
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+type CreateServiceAccountRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=500"`
+	// Role is the name of the role whose permissions the account gets.
+	Role string `json:"role" validate:"required"`
+}
+
+type ServiceAccountResponse struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	ClientID    string     `json:"client_id"`
+	RoleID      int32      `json:"role_id"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	// ClientSecret is only returned when the account is created and when
+	// its secret is rotated.
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+type WebAuthnRegisterRequest struct {
+	// Name tells the user's passkeys apart, e.g. "MacBook".
+	Name       string                       `json:"name" validate:"max=100"`
+	Credential webauthn.AttestationResponse `json:"credential"`
+}
+
+type WebAuthnLoginRequest struct {
+	Credential webauthn.AssertionResponse `json:"credential"`
+}
+
+type WebAuthnCredentialResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Transports []string   `json:"transports"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type ProfileResponse struct {
+	ID        int64     `json:"id"`
+	Email     string    `json:"email"`
+	Username  string    `json:"username"`
+	FullName  string    `json:"full_name"`
+	Verified  bool      `json:"verified"`
+	RoleID    int32     `json:"role_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UpdateProfileRequest only changes the fields that are present.
+type UpdateProfileRequest struct {
+	Username *string `json:"username" validate:"omitempty,min=1,max=100"`
+	FullName *string `json:"full_name" validate:"omitempty,min=1,max=200"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" validate:"required"`
 }
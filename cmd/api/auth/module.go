@@ -2,17 +2,21 @@ package auth
 
 import (
 	"github.com/mifaabiyyu/backend-go/internal/auth"
+	"github.com/mifaabiyyu/backend-go/internal/lockout"
+	"github.com/mifaabiyyu/backend-go/internal/mailer"
 	"github.com/mifaabiyyu/backend-go/internal/store"
+	"github.com/mifaabiyyu/backend-go/internal/store/cache"
 	"github.com/mifaabiyyu/backend-go/utils"
 )
 
-func InitAuthModule(store *store.Store, wrapper *utils.AppWrapper, authenticator auth.Authenticator) *Handler {
-	repo := NewAuthRepository(store.Queries)
+func InitAuthModule(store *store.Store, wrapper *utils.AppWrapper, authenticator auth.Authenticator, revocations auth.RevocationStore, mailer mailer.Client, cacheStorage cache.Storage, guard *lockout.Guard, cfg Config) *Handler {
+	repo := NewAuthRepository(store)
 
-	service := NewAuthService(repo, authenticator)
+	service := NewAuthService(repo, authenticator, revocations, mailer, cacheStorage, guard, cfg)
 
 	return &Handler{
 		Service:    service,
 		AppWrapper: wrapper,
+		cfg:        cfg,
 	}
 }
@@ -2,25 +2,99 @@ package auth
 
 import (
 	"context"
-	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/store"
 )
 
 type Repository interface {
+	WithTx(ctx context.Context, fn func(Repository) error) error
 	IsEmailExists(ctx context.Context, email string) (bool, error)
 	CreateUser(ctx context.Context, arg sqlc.CreateUserParams) (sqlc.User, error)
 	GetUserByEmail(ctx context.Context, email string) (sqlc.User, error)
+	GetUserByID(ctx context.Context, userID int64) (sqlc.User, error)
+	UpdateUser(ctx context.Context, arg sqlc.UpdateUserParams) error
+	VerifyUser(ctx context.Context, userID int64) error
+	CreateUserInvitation(ctx context.Context, arg sqlc.CreateUserInvitationParams) error
+	GetUserByInvitationToken(ctx context.Context, tokenHash string) (sqlc.User, error)
+	DeleteUserInvitations(ctx context.Context, userID int64) error
+	UpdateUserPassword(ctx context.Context, userID int64, hashedPassword string) error
+	RehashUserPassword(ctx context.Context, userID int64, oldHash, newHash string) error
+	IncrementTokenVersion(ctx context.Context, userID int64) error
+	CreatePasswordReset(ctx context.Context, arg sqlc.CreatePasswordResetParams) error
+	ConsumePasswordReset(ctx context.Context, tokenHash string) (int64, error)
+	InvalidatePasswordResets(ctx context.Context, userID int64) error
+	CreateRefreshToken(ctx context.Context, arg sqlc.CreateRefreshTokenParams) (sqlc.RefreshToken, error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (sqlc.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, id int64) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID int64) error
+	UpsertUserMFA(ctx context.Context, userID int64, secret string) error
+	GetUserMFA(ctx context.Context, userID int64) (sqlc.UserMfa, error)
+	EnableUserMFA(ctx context.Context, userID int64, step int64) error
+	UpdateMFALastUsedStep(ctx context.Context, userID int64, step int64) (bool, error)
+	DeleteUserMFA(ctx context.Context, userID int64) error
+	CreateMFARecoveryCode(ctx context.Context, userID int64, codeHash string) error
+	UseMFARecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error)
+	DeleteMFARecoveryCodes(ctx context.Context, userID int64) error
+	CreateAccountUnlock(ctx context.Context, arg sqlc.CreateAccountUnlockParams) error
+	ConsumeAccountUnlock(ctx context.Context, tokenHash string) (int64, error)
+	CreateOAuthState(ctx context.Context, arg sqlc.CreateOAuthStateParams) error
+	ConsumeOAuthState(ctx context.Context, stateHash, provider string) (sqlc.OauthState, error)
+	GetUserByIdentity(ctx context.Context, provider, subject string) (sqlc.User, error)
+	CreateUserIdentity(ctx context.Context, arg sqlc.CreateUserIdentityParams) error
+	TouchUserIdentity(ctx context.Context, provider, subject, email string) error
+	CreateMagicLink(ctx context.Context, arg sqlc.CreateMagicLinkParams) error
+	CountRecentMagicLinks(ctx context.Context, userID int64, since time.Time) (int64, error)
+	ConsumeMagicLink(ctx context.Context, tokenHash string) (sqlc.ConsumeMagicLinkRow, error)
+	InvalidateMagicLinks(ctx context.Context, userID int64) error
+	CreateEmailChange(ctx context.Context, arg sqlc.CreateEmailChangeParams) error
+	ConsumeEmailChange(ctx context.Context, tokenHash string) (sqlc.ConsumeEmailChangeRow, error)
+	InvalidateEmailChanges(ctx context.Context, userID int64) error
+	GetEffectivePermissionsByRoleID(ctx context.Context, roleID int32) ([]sqlc.Permission, error)
+	CreatePersonalAccessToken(ctx context.Context, arg sqlc.CreatePersonalAccessTokenParams) (sqlc.PersonalAccessToken, error)
+	ListPersonalAccessTokens(ctx context.Context, userID int64) ([]sqlc.PersonalAccessToken, error)
+	RevokePersonalAccessToken(ctx context.Context, userID, tokenID int64) (bool, error)
+	RevokeUserPersonalAccessTokens(ctx context.Context, userID int64) error
+	UpsertSession(ctx context.Context, arg sqlc.UpsertSessionParams) (sqlc.Session, error)
+	ListUserSessions(ctx context.Context, userID int64) ([]sqlc.Session, error)
+	RevokeSession(ctx context.Context, userID int64, sessionID string) (bool, error)
+	RevokeUserSessions(ctx context.Context, userID int64) error
+	GetRoleByID(ctx context.Context, roleID int32) (sqlc.Role, error)
+	CreateImpersonation(ctx context.Context, arg sqlc.CreateImpersonationParams) error
+	GetRoleByName(ctx context.Context, name string) (sqlc.Role, error)
+	CreateServiceAccount(ctx context.Context, arg sqlc.CreateServiceAccountParams) (sqlc.ServiceAccount, error)
+	GetServiceAccountByID(ctx context.Context, id int64) (sqlc.ServiceAccount, error)
+	ListServiceAccounts(ctx context.Context) ([]sqlc.ServiceAccount, error)
+	UpdateServiceAccountSecret(ctx context.Context, id int64, secretHash string) (bool, error)
+	DeleteServiceAccount(ctx context.Context, id int64) (bool, error)
+	CreateWebAuthnChallenge(ctx context.Context, arg sqlc.CreateWebAuthnChallengeParams) error
+	ConsumeWebAuthnChallenge(ctx context.Context, challengeHash, ceremony string) (sqlc.WebauthnChallenge, error)
+	CreateWebAuthnCredential(ctx context.Context, arg sqlc.CreateWebAuthnCredentialParams) (sqlc.WebauthnCredential, error)
+	GetWebAuthnCredentialByCredentialID(ctx context.Context, credentialID []byte) (sqlc.WebauthnCredential, error)
+	ListWebAuthnCredentials(ctx context.Context, userID int64) ([]sqlc.WebauthnCredential, error)
+	UpdateWebAuthnSignCount(ctx context.Context, id, signCount int64) (bool, error)
+	DeleteWebAuthnCredential(ctx context.Context, userID, id int64) (bool, error)
 }
 
 type authRepo struct {
-	q *sqlc.Queries
+	q     *sqlc.Queries
+	store *store.Store
 }
 
-func NewAuthRepository(q *sqlc.Queries) Repository {
-	return &authRepo{q}
+func NewAuthRepository(store *store.Store) Repository {
+	return &authRepo{q: store.Queries, store: store}
+}
+
+func (r *authRepo) WithTx(ctx context.Context, fn func(Repository) error) error {
+	return r.store.WithTx(ctx, func(q *sqlc.Queries) error {
+		return fn(&authRepo{q: q, store: r.store})
+	})
 }
 
 func (r *authRepo) IsEmailExists(ctx context.Context, email string) (bool, error) {
@@ -41,10 +115,514 @@ func (r *authRepo) CreateUser(ctx context.Context, arg sqlc.CreateUserParams) (s
 func (r *authRepo) GetUserByEmail(ctx context.Context, email string) (sqlc.User, error) {
 	user, err := r.q.GetByEmail(ctx, email)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return sqlc.User{}, sql.ErrNoRows
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.User{}, ErrNotFound
+		}
+		return sqlc.User{}, err
+	}
+	return user, nil
+}
+
+func (r *authRepo) GetUserByID(ctx context.Context, userID int64) (sqlc.User, error) {
+	user, err := r.q.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.User{}, ErrNotFound
+		}
+		return sqlc.User{}, err
+	}
+	return user, nil
+}
+
+func (r *authRepo) VerifyUser(ctx context.Context, userID int64) error {
+	return r.q.VerifyUser(ctx, userID)
+}
+
+func (r *authRepo) CreateUserInvitation(ctx context.Context, arg sqlc.CreateUserInvitationParams) error {
+	return r.q.CreateUserInvitation(ctx, arg)
+}
+
+func (r *authRepo) GetUserByInvitationToken(ctx context.Context, tokenHash string) (sqlc.User, error) {
+	user, err := r.q.GetUserByInvitationToken(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.User{}, ErrNotFound
 		}
 		return sqlc.User{}, err
 	}
 	return user, nil
 }
+
+// UpdateUser maps a taken email to ErrEmailExists, the unique index has the
+// last word when two accounts race for the same address.
+func (r *authRepo) UpdateUser(ctx context.Context, arg sqlc.UpdateUserParams) error {
+	err := r.q.UpdateUser(ctx, arg)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return ErrEmailExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *authRepo) DeleteUserInvitations(ctx context.Context, userID int64) error {
+	return r.q.DeleteUserInvitations(ctx, userID)
+}
+
+func (r *authRepo) UpdateUserPassword(ctx context.Context, userID int64, hashedPassword string) error {
+	return r.q.UpdateUserPassword(ctx, sqlc.UpdateUserPasswordParams{
+		ID:       userID,
+		Password: hashedPassword,
+	})
+}
+
+// RehashUserPassword only replaces the hash if it is still oldHash, a
+// password changed in the meantime wins over the upgrade.
+func (r *authRepo) RehashUserPassword(ctx context.Context, userID int64, oldHash, newHash string) error {
+	_, err := r.q.RehashUserPassword(ctx, sqlc.RehashUserPasswordParams{
+		ID:          userID,
+		OldPassword: oldHash,
+		NewPassword: newHash,
+	})
+	return err
+}
+
+func (r *authRepo) IncrementTokenVersion(ctx context.Context, userID int64) error {
+	return r.q.IncrementTokenVersion(ctx, userID)
+}
+
+func (r *authRepo) CreatePasswordReset(ctx context.Context, arg sqlc.CreatePasswordResetParams) error {
+	return r.q.CreatePasswordReset(ctx, arg)
+}
+
+func (r *authRepo) ConsumePasswordReset(ctx context.Context, tokenHash string) (int64, error) {
+	userID, err := r.q.ConsumePasswordReset(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+func (r *authRepo) InvalidatePasswordResets(ctx context.Context, userID int64) error {
+	return r.q.InvalidatePasswordResets(ctx, userID)
+}
+
+func (r *authRepo) CreateRefreshToken(ctx context.Context, arg sqlc.CreateRefreshTokenParams) (sqlc.RefreshToken, error) {
+	return r.q.CreateRefreshToken(ctx, arg)
+}
+
+func (r *authRepo) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (sqlc.RefreshToken, error) {
+	token, err := r.q.GetRefreshTokenByHash(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.RefreshToken{}, ErrNotFound
+		}
+		return sqlc.RefreshToken{}, err
+	}
+	return token, nil
+}
+
+// RevokeRefreshToken reports whether this call was the one that revoked the
+// token, which lets concurrent rotations of the same token be detected.
+func (r *authRepo) RevokeRefreshToken(ctx context.Context, id int64) (bool, error) {
+	rows, err := r.q.RevokeRefreshToken(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (r *authRepo) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	return r.q.RevokeRefreshTokenFamily(ctx, familyID)
+}
+
+func (r *authRepo) RevokeUserRefreshTokens(ctx context.Context, userID int64) error {
+	return r.q.RevokeUserRefreshTokens(ctx, userID)
+}
+
+func (r *authRepo) UpsertUserMFA(ctx context.Context, userID int64, secret string) error {
+	return r.q.UpsertUserMFA(ctx, sqlc.UpsertUserMFAParams{
+		UserID: userID,
+		Secret: secret,
+	})
+}
+
+func (r *authRepo) GetUserMFA(ctx context.Context, userID int64) (sqlc.UserMfa, error) {
+	mfa, err := r.q.GetUserMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.UserMfa{}, ErrNotFound
+		}
+		return sqlc.UserMfa{}, err
+	}
+	return mfa, nil
+}
+
+func (r *authRepo) EnableUserMFA(ctx context.Context, userID int64, step int64) error {
+	return r.q.EnableUserMFA(ctx, sqlc.EnableUserMFAParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+}
+
+// UpdateMFALastUsedStep only moves the step forward, it reports false when
+// step was already used which means the code is being replayed.
+func (r *authRepo) UpdateMFALastUsedStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	rows, err := r.q.UpdateMFALastUsedStep(ctx, sqlc.UpdateMFALastUsedStepParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (r *authRepo) DeleteUserMFA(ctx context.Context, userID int64) error {
+	return r.q.DeleteUserMFA(ctx, userID)
+}
+
+func (r *authRepo) CreateMFARecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	return r.q.CreateMFARecoveryCode(ctx, sqlc.CreateMFARecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: codeHash,
+	})
+}
+
+func (r *authRepo) UseMFARecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	rows, err := r.q.UseMFARecoveryCode(ctx, sqlc.UseMFARecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: codeHash,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (r *authRepo) DeleteMFARecoveryCodes(ctx context.Context, userID int64) error {
+	return r.q.DeleteMFARecoveryCodes(ctx, userID)
+}
+
+func (r *authRepo) CreateAccountUnlock(ctx context.Context, arg sqlc.CreateAccountUnlockParams) error {
+	return r.q.CreateAccountUnlock(ctx, arg)
+}
+
+func (r *authRepo) ConsumeAccountUnlock(ctx context.Context, tokenHash string) (int64, error) {
+	userID, err := r.q.ConsumeAccountUnlock(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+// CreateOAuthState also drops expired states, abandoned sign-ins would
+// pile up otherwise.
+func (r *authRepo) CreateOAuthState(ctx context.Context, arg sqlc.CreateOAuthStateParams) error {
+	if err := r.q.DeleteExpiredOAuthStates(ctx); err != nil {
+		return err
+	}
+	return r.q.CreateOAuthState(ctx, arg)
+}
+
+func (r *authRepo) ConsumeOAuthState(ctx context.Context, stateHash, provider string) (sqlc.OauthState, error) {
+	state, err := r.q.ConsumeOAuthState(ctx, sqlc.ConsumeOAuthStateParams{
+		StateHash: stateHash,
+		Provider:  provider,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.OauthState{}, ErrNotFound
+		}
+		return sqlc.OauthState{}, err
+	}
+	return state, nil
+}
+
+func (r *authRepo) GetUserByIdentity(ctx context.Context, provider, subject string) (sqlc.User, error) {
+	user, err := r.q.GetUserByIdentity(ctx, sqlc.GetUserByIdentityParams{
+		Provider: provider,
+		Subject:  subject,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.User{}, ErrNotFound
+		}
+		return sqlc.User{}, err
+	}
+	return user, nil
+}
+
+func (r *authRepo) CreateUserIdentity(ctx context.Context, arg sqlc.CreateUserIdentityParams) error {
+	return r.q.CreateUserIdentity(ctx, arg)
+}
+
+func (r *authRepo) TouchUserIdentity(ctx context.Context, provider, subject, email string) error {
+	return r.q.TouchUserIdentity(ctx, sqlc.TouchUserIdentityParams{
+		Provider: provider,
+		Subject:  subject,
+		Email:    email,
+	})
+}
+
+func (r *authRepo) CreateMagicLink(ctx context.Context, arg sqlc.CreateMagicLinkParams) error {
+	return r.q.CreateMagicLink(ctx, arg)
+}
+
+func (r *authRepo) CountRecentMagicLinks(ctx context.Context, userID int64, since time.Time) (int64, error) {
+	return r.q.CountRecentMagicLinks(ctx, sqlc.CountRecentMagicLinksParams{
+		UserID:    userID,
+		CreatedAt: pgtype.Timestamptz{Time: since, Valid: true},
+	})
+}
+
+func (r *authRepo) ConsumeMagicLink(ctx context.Context, tokenHash string) (sqlc.ConsumeMagicLinkRow, error) {
+	link, err := r.q.ConsumeMagicLink(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.ConsumeMagicLinkRow{}, ErrNotFound
+		}
+		return sqlc.ConsumeMagicLinkRow{}, err
+	}
+	return link, nil
+}
+
+func (r *authRepo) InvalidateMagicLinks(ctx context.Context, userID int64) error {
+	return r.q.InvalidateMagicLinks(ctx, userID)
+}
+
+func (r *authRepo) CreateEmailChange(ctx context.Context, arg sqlc.CreateEmailChangeParams) error {
+	return r.q.CreateEmailChange(ctx, arg)
+}
+
+func (r *authRepo) ConsumeEmailChange(ctx context.Context, tokenHash string) (sqlc.ConsumeEmailChangeRow, error) {
+	change, err := r.q.ConsumeEmailChange(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.ConsumeEmailChangeRow{}, ErrNotFound
+		}
+		return sqlc.ConsumeEmailChangeRow{}, err
+	}
+	return change, nil
+}
+
+func (r *authRepo) InvalidateEmailChanges(ctx context.Context, userID int64) error {
+	return r.q.InvalidateEmailChanges(ctx, userID)
+}
+
+// GetEffectivePermissionsByRoleID includes the permissions inherited from
+// lower level roles.
+func (r *authRepo) GetEffectivePermissionsByRoleID(ctx context.Context, roleID int32) ([]sqlc.Permission, error) {
+	return r.q.GetEffectivePermissionsByRoleID(ctx, int64(roleID))
+}
+
+func (r *authRepo) CreatePersonalAccessToken(ctx context.Context, arg sqlc.CreatePersonalAccessTokenParams) (sqlc.PersonalAccessToken, error) {
+	return r.q.CreatePersonalAccessToken(ctx, arg)
+}
+
+func (r *authRepo) ListPersonalAccessTokens(ctx context.Context, userID int64) ([]sqlc.PersonalAccessToken, error) {
+	return r.q.ListPersonalAccessTokens(ctx, userID)
+}
+
+func (r *authRepo) RevokePersonalAccessToken(ctx context.Context, userID, tokenID int64) (bool, error) {
+	rows, err := r.q.RevokePersonalAccessToken(ctx, sqlc.RevokePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: userID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (r *authRepo) RevokeUserPersonalAccessTokens(ctx context.Context, userID int64) error {
+	return r.q.RevokeUserPersonalAccessTokens(ctx, userID)
+}
+
+func (r *authRepo) UpsertSession(ctx context.Context, arg sqlc.UpsertSessionParams) (sqlc.Session, error) {
+	session, err := r.q.UpsertSession(ctx, arg)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.Session{}, ErrNotFound
+		}
+		return sqlc.Session{}, err
+	}
+	return session, nil
+}
+
+func (r *authRepo) ListUserSessions(ctx context.Context, userID int64) ([]sqlc.Session, error) {
+	return r.q.ListUserSessions(ctx, userID)
+}
+
+func (r *authRepo) RevokeSession(ctx context.Context, userID int64, sessionID string) (bool, error) {
+	rows, err := r.q.RevokeSession(ctx, sqlc.RevokeSessionParams{
+		ID:     sessionID,
+		UserID: userID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (r *authRepo) RevokeUserSessions(ctx context.Context, userID int64) error {
+	return r.q.RevokeUserSessions(ctx, userID)
+}
+
+func (r *authRepo) GetRoleByID(ctx context.Context, roleID int32) (sqlc.Role, error) {
+	role, err := r.q.GetRoleByID(ctx, int64(roleID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.Role{}, ErrNotFound
+		}
+		return sqlc.Role{}, err
+	}
+	return role, nil
+}
+
+func (r *authRepo) CreateImpersonation(ctx context.Context, arg sqlc.CreateImpersonationParams) error {
+	return r.q.CreateImpersonation(ctx, arg)
+}
+
+func (r *authRepo) GetRoleByName(ctx context.Context, name string) (sqlc.Role, error) {
+	role, err := r.q.GetRoleByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.Role{}, ErrNotFound
+		}
+		return sqlc.Role{}, err
+	}
+	return role, nil
+}
+
+// uniqueViolation is the Postgres error code of a unique constraint
+// failure.
+const uniqueViolation = "23505"
+
+func (r *authRepo) CreateServiceAccount(ctx context.Context, arg sqlc.CreateServiceAccountParams) (sqlc.ServiceAccount, error) {
+	account, err := r.q.CreateServiceAccount(ctx, arg)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return sqlc.ServiceAccount{}, ErrServiceAccountExists
+		}
+		return sqlc.ServiceAccount{}, err
+	}
+	return account, nil
+}
+
+func (r *authRepo) GetServiceAccountByID(ctx context.Context, id int64) (sqlc.ServiceAccount, error) {
+	account, err := r.q.GetServiceAccountByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.ServiceAccount{}, ErrNotFound
+		}
+		return sqlc.ServiceAccount{}, err
+	}
+	return account, nil
+}
+
+func (r *authRepo) ListServiceAccounts(ctx context.Context) ([]sqlc.ServiceAccount, error) {
+	return r.q.ListServiceAccounts(ctx)
+}
+
+func (r *authRepo) UpdateServiceAccountSecret(ctx context.Context, id int64, secretHash string) (bool, error) {
+	rows, err := r.q.UpdateServiceAccountSecret(ctx, sqlc.UpdateServiceAccountSecretParams{
+		ID:         id,
+		SecretHash: secretHash,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (r *authRepo) DeleteServiceAccount(ctx context.Context, id int64) (bool, error) {
+	rows, err := r.q.DeleteServiceAccount(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// CreateWebAuthnChallenge also drops expired challenges, like
+// CreateOAuthState.
+func (r *authRepo) CreateWebAuthnChallenge(ctx context.Context, arg sqlc.CreateWebAuthnChallengeParams) error {
+	if err := r.q.DeleteExpiredWebAuthnChallenges(ctx); err != nil {
+		return err
+	}
+	return r.q.CreateWebAuthnChallenge(ctx, arg)
+}
+
+func (r *authRepo) ConsumeWebAuthnChallenge(ctx context.Context, challengeHash, ceremony string) (sqlc.WebauthnChallenge, error) {
+	challenge, err := r.q.ConsumeWebAuthnChallenge(ctx, sqlc.ConsumeWebAuthnChallengeParams{
+		ChallengeHash: challengeHash,
+		Ceremony:      ceremony,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.WebauthnChallenge{}, ErrNotFound
+		}
+		return sqlc.WebauthnChallenge{}, err
+	}
+	return challenge, nil
+}
+
+func (r *authRepo) CreateWebAuthnCredential(ctx context.Context, arg sqlc.CreateWebAuthnCredentialParams) (sqlc.WebauthnCredential, error) {
+	credential, err := r.q.CreateWebAuthnCredential(ctx, arg)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return sqlc.WebauthnCredential{}, ErrPasskeyExists
+		}
+		return sqlc.WebauthnCredential{}, err
+	}
+	return credential, nil
+}
+
+func (r *authRepo) GetWebAuthnCredentialByCredentialID(ctx context.Context, credentialID []byte) (sqlc.WebauthnCredential, error) {
+	credential, err := r.q.GetWebAuthnCredentialByCredentialID(ctx, credentialID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.WebauthnCredential{}, ErrNotFound
+		}
+		return sqlc.WebauthnCredential{}, err
+	}
+	return credential, nil
+}
+
+func (r *authRepo) ListWebAuthnCredentials(ctx context.Context, userID int64) ([]sqlc.WebauthnCredential, error) {
+	return r.q.ListWebAuthnCredentials(ctx, userID)
+}
+
+// UpdateWebAuthnSignCount reports false when a concurrent assertion already
+// stored the same or a higher counter.
+func (r *authRepo) UpdateWebAuthnSignCount(ctx context.Context, id, signCount int64) (bool, error) {
+	rows, err := r.q.UpdateWebAuthnSignCount(ctx, sqlc.UpdateWebAuthnSignCountParams{
+		ID:        id,
+		SignCount: signCount,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (r *authRepo) DeleteWebAuthnCredential(ctx context.Context, userID, id int64) (bool, error) {
+	rows, err := r.q.DeleteWebAuthnCredential(ctx, sqlc.DeleteWebAuthnCredentialParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
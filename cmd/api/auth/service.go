@@ -2,7 +2,8 @@ package auth
 
 import (
 	"context"
-	"database/sql"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
@@ -12,24 +13,118 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/mifaabiyyu/backend-go/internal/auth"
 	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/lockout"
+	"github.com/mifaabiyyu/backend-go/internal/mailer"
 	"github.com/mifaabiyyu/backend-go/internal/password"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/mifaabiyyu/backend-go/internal/store/cache"
+	"github.com/mifaabiyyu/backend-go/internal/webauthn"
 )
 
+var (
+	ErrNotFound           = errors.New("record not found")
+	ErrEmailExists        = errors.New("email already exists")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrNotVerified        = errors.New("account is not activated, please check your email")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrTokenReused        = errors.New("refresh token reuse detected, please login again")
+	ErrInvalidMFACode     = errors.New("invalid authentication code")
+	ErrMFAAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled      = errors.New("two-factor authentication is not enabled")
+)
+
+const passwordResetExp = time.Hour
+
+// defaultRoleName is the role every new account starts with.
+const defaultRoleName = "user"
+
+type Config struct {
+	FrontendURL  string
+	MailExp      time.Duration
+	IsProdEnv    bool
+	CacheEnabled bool
+	TokenExp     time.Duration
+	RefreshExp   time.Duration
+	TokenIss     string
+	TokenAud     string
+	// PasswordPolicy is checked whenever a password is set, nil disables
+	// it.
+	PasswordPolicy *password.Policy
+	// OAuthProviders are the enabled social logins keyed by the name used
+	// in /auth/oauth/{provider}.
+	OAuthProviders map[string]OAuthProvider
+	// ImpersonationExp is how long an impersonation token is valid, they
+	// cannot be refreshed.
+	ImpersonationExp time.Duration
+	// WebAuthn is the passkey relying party, nil disables passkeys.
+	WebAuthn *webauthn.RelyingParty
+}
+
 type Service interface {
 	Register(ctx context.Context, req RegisterRequest) (*sqlc.User, error)
-	Login(ctx context.Context, email, password string) (string, error)
+	Login(ctx context.Context, email, password string, client ClientMeta) (*LoginResponse, error)
+	Refresh(ctx context.Context, refreshToken string, client ClientMeta) (*LoginResponse, error)
+	Logout(ctx context.Context, claims *auth.Claims, refreshToken string) error
+	LogoutAll(ctx context.Context, userID int64) error
+	Activate(ctx context.Context, token string) error
+	ResendActivation(ctx context.Context, email string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	EnrollMFA(ctx context.Context, userID int64) (*MFAEnrollResponse, error)
+	EnableMFA(ctx context.Context, userID int64, code string) (*MFARecoveryCodesResponse, error)
+	DisableMFA(ctx context.Context, userID int64, code string, client ClientMeta) error
+	VerifyMFA(ctx context.Context, mfaToken, code string, client ClientMeta) (*LoginResponse, error)
+	UnlockAccount(ctx context.Context, token string) error
+	ClearLockout(ctx context.Context, userID int64) error
+	OAuthStart(ctx context.Context, provider string) (*OAuthStartResponse, error)
+	OAuthLinkStart(ctx context.Context, userID int64, provider string) (*OAuthStartResponse, error)
+	OAuthCallback(ctx context.Context, provider, code, state string, client ClientMeta) (*LoginResponse, error)
+	RequestMagicLink(ctx context.Context, email string) error
+	ConsumeMagicLink(ctx context.Context, token string, client ClientMeta) (*LoginResponse, error)
+	CreateToken(ctx context.Context, userID int64, req CreateTokenRequest) (*TokenResponse, error)
+	ListTokens(ctx context.Context, userID int64) ([]TokenResponse, error)
+	RevokeToken(ctx context.Context, userID, tokenID int64) error
+	ListSessions(ctx context.Context, userID int64, currentSessionID string) ([]SessionResponse, error)
+	RevokeSession(ctx context.Context, userID int64, sessionID string) error
+	Impersonate(ctx context.Context, actor *auth.Claims, userID int64, reason string, client ClientMeta) (*LoginResponse, error)
+	CreateServiceAccount(ctx context.Context, creatorID int64, req CreateServiceAccountRequest) (*ServiceAccountResponse, error)
+	ListServiceAccounts(ctx context.Context) ([]ServiceAccountResponse, error)
+	RotateServiceAccountSecret(ctx context.Context, id int64) (*ServiceAccountResponse, error)
+	DeleteServiceAccount(ctx context.Context, id int64) error
+	BeginWebAuthnRegistration(ctx context.Context, userID int64) (*webauthn.CreationOptions, error)
+	FinishWebAuthnRegistration(ctx context.Context, userID int64, req WebAuthnRegisterRequest) (*WebAuthnCredentialResponse, error)
+	BeginWebAuthnLogin(ctx context.Context) (*webauthn.RequestOptions, error)
+	FinishWebAuthnLogin(ctx context.Context, req WebAuthnLoginRequest, client ClientMeta) (*LoginResponse, error)
+	ListWebAuthnCredentials(ctx context.Context, userID int64) ([]WebAuthnCredentialResponse, error)
+	DeleteWebAuthnCredential(ctx context.Context, userID, id int64) error
+	UpdateProfile(ctx context.Context, userID int64, req UpdateProfileRequest) (*ProfileResponse, error)
+	ChangePassword(ctx context.Context, userID int64, req ChangePasswordRequest, client ClientMeta) (*LoginResponse, error)
+	RequestEmailChange(ctx context.Context, userID int64, req ChangeEmailRequest) error
+	ConfirmEmailChange(ctx context.Context, token string) error
 }
 
 type authService struct {
 	repo          Repository
 	authenticator auth.Authenticator
+	revocations   auth.RevocationStore
+	mailer        mailer.Client
+	cache         cache.Storage
+	lockout       *lockout.Guard
+	cfg           Config
+	// now is the service clock, replaced in tests to control TOTP steps
+	// and token lifetimes.
+	now func() time.Time
 }
 
-func NewAuthService(repo Repository, auth auth.Authenticator) Service {
+func NewAuthService(repo Repository, auth auth.Authenticator, revocations auth.RevocationStore, mailer mailer.Client, cache cache.Storage, lockout *lockout.Guard, cfg Config) Service {
 	return &authService{
 		repo:          repo,
 		authenticator: auth,
+		revocations:   revocations,
+		mailer:        mailer,
+		cache:         cache,
+		lockout:       lockout,
+		cfg:           cfg,
+		now:           time.Now,
 	}
 }
 
@@ -39,7 +134,12 @@ func (s *authService) Register(ctx context.Context, req RegisterRequest) (*sqlc.
 		return nil, err
 	}
 	if exists {
-		return nil, errors.New("email already exists")
+		return nil, ErrEmailExists
+	}
+
+	err = s.checkPassword(ctx, req.Password, password.UserInfo{Email: req.Email, Username: req.Username})
+	if err != nil {
+		return nil, err
 	}
 
 	hashedPassword, err := password.Hash(req.Password)
@@ -47,11 +147,27 @@ func (s *authService) Register(ctx context.Context, req RegisterRequest) (*sqlc.
 		return nil, err
 	}
 
-	user, err := s.repo.CreateUser(ctx, sqlc.CreateUserParams{
-		Email:    req.Email,
-		Password: hashedPassword,
-		Username: req.Username,
-		RoleID:   pgtype.Int4{Int32: 2, Valid: true},
+	var user sqlc.User
+	// The user and its invitation share one transaction so a failed send
+	// leaves nothing behind and the email can register again.
+	err = s.repo.WithTx(ctx, func(repo Repository) error {
+		roleID, err := defaultRoleID(ctx, repo)
+		if err != nil {
+			return err
+		}
+
+		user, err = repo.CreateUser(ctx, sqlc.CreateUserParams{
+			Email:    req.Email,
+			Password: hashedPassword,
+			Username: req.Username,
+			FullName: req.FullName,
+			RoleID:   roleID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return s.sendInvitation(ctx, repo, user)
 	})
 	if err != nil {
 		return nil, err
@@ -60,37 +176,542 @@ func (s *authService) Register(ctx context.Context, req RegisterRequest) (*sqlc.
 	return &user, nil
 }
 
-func (s *authService) Login(ctx context.Context, email, password string) (string, error) {
+func (s *authService) Activate(ctx context.Context, token string) error {
+	return s.repo.WithTx(ctx, func(repo Repository) error {
+		user, err := repo.GetUserByInvitationToken(ctx, auth.HashOpaqueToken(token))
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrInvalidToken
+			}
+			return err
+		}
+
+		if err := repo.VerifyUser(ctx, user.ID); err != nil {
+			return err
+		}
+
+		return repo.DeleteUserInvitations(ctx, user.ID)
+	})
+}
+
+// ResendActivation replaces any pending invitation with a fresh one. Unknown
+// or already activated emails are ignored so the response never reveals
+// whether an account exists.
+func (s *authService) ResendActivation(ctx context.Context, email string) error {
 	user, err := s.repo.GetUserByEmail(ctx, email)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return "", errors.New("invalid email or password")
+		if errors.Is(err, ErrNotFound) {
+			return nil
 		}
-		return "", err
+		return err
+	}
+
+	if user.Verified {
+		return nil
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	return s.repo.WithTx(ctx, func(repo Repository) error {
+		if err := repo.DeleteUserInvitations(ctx, user.ID); err != nil {
+			return err
+		}
+
+		return s.sendInvitation(ctx, repo, user)
+	})
+}
+
+func (s *authService) sendInvitation(ctx context.Context, repo Repository, user sqlc.User) error {
+	plainToken, hashToken, err := auth.GenerateOpaqueToken()
 	if err != nil {
-		log.Printf("Password mismatch for email %s: %v", email, err)
-		return "", errors.New("invalid email or password")
+		return err
 	}
 
-	token, err := s.authenticator.GenerateToken(&auth.Claims{
-		UserID: user.ID,
-		RoleID: user.RoleID.Int32,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			Issuer:    "backend-apps",
-			Audience:  []string{"rahasia"},
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Subject:   fmt.Sprintf("%d", user.ID),
-		},
+	err = repo.CreateUserInvitation(ctx, sqlc.CreateUserInvitationParams{
+		TokenHash: hashToken,
+		UserID:    user.ID,
+		ExpiresAt: pgtype.Timestamptz{Time: s.now().Add(s.cfg.MailExp), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	vars := struct {
+		Username      string
+		ActivationURL string
+	}{
+		Username:      user.Username,
+		ActivationURL: fmt.Sprintf("%s/confirm/%s", s.cfg.FrontendURL, plainToken),
+	}
+
+	if _, err := s.mailer.Send(mailer.UserWelcomeTemplate, user.Username, user.Email, vars, !s.cfg.IsProdEnv); err != nil {
+		return fmt.Errorf("failed to send invitation email: %w", err)
+	}
+
+	return nil
+}
+
+// ForgotPassword mails a single-use reset link. Unknown emails are ignored
+// so the response never reveals whether an account exists.
+func (s *authService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	plainToken, hashToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.CreatePasswordReset(ctx, sqlc.CreatePasswordResetParams{
+		UserID:    user.ID,
+		TokenHash: hashToken,
+		ExpiresAt: pgtype.Timestamptz{Time: s.now().Add(passwordResetExp), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	vars := struct {
+		Username  string
+		ResetURL  string
+		ExpiresIn string
+	}{
+		Username:  user.Username,
+		ResetURL:  fmt.Sprintf("%s/reset-password/%s", s.cfg.FrontendURL, plainToken),
+		ExpiresIn: passwordResetExp.String(),
+	}
+
+	if _, err := s.mailer.Send(mailer.PasswordResetTemplate, user.Username, user.Email, vars, !s.cfg.IsProdEnv); err != nil {
+		return fmt.Errorf("failed to send password reset email: %w", err)
+	}
+
+	return nil
+}
+
+// ResetPassword consumes a reset token, stores the new password and bumps
+// the user's token version so every previously issued JWT stops working.
+func (s *authService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	var userID int64
+	err := s.repo.WithTx(ctx, func(repo Repository) error {
+		var err error
+		userID, err = repo.ConsumePasswordReset(ctx, auth.HashOpaqueToken(token))
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrInvalidToken
+			}
+			return err
+		}
+
+		user, err := repo.GetUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		// A rejected password rolls the transaction back, the token stays
+		// usable for the next attempt.
+		err = s.checkPassword(ctx, newPassword, password.UserInfo{Email: user.Email, Username: user.Username})
+		if err != nil {
+			return err
+		}
+
+		hashedPassword, err := password.Hash(newPassword)
+		if err != nil {
+			return err
+		}
+
+		if err := repo.UpdateUserPassword(ctx, userID, hashedPassword); err != nil {
+			return err
+		}
+
+		if err := repo.InvalidatePasswordResets(ctx, userID); err != nil {
+			return err
+		}
+
+		if err := repo.RevokeUserRefreshTokens(ctx, userID); err != nil {
+			return err
+		}
+
+		if err := repo.RevokeUserSessions(ctx, userID); err != nil {
+			return err
+		}
+
+		// Whoever knew the old password may have created tokens with it.
+		if err := repo.RevokeUserPersonalAccessTokens(ctx, userID); err != nil {
+			return err
+		}
+
+		return repo.IncrementTokenVersion(ctx, userID)
 	})
+	if err != nil {
+		return err
+	}
+
+	s.invalidateUserCache(ctx, userID)
+
+	return nil
+}
+
+// checkPassword runs the password policy, policy violations come back as
+// *password.PolicyError.
+func (s *authService) checkPassword(ctx context.Context, plain string, user password.UserInfo) error {
+	if s.cfg.PasswordPolicy == nil {
+		return nil
+	}
+	return s.cfg.PasswordPolicy.Check(ctx, plain, user)
+}
+
+// defaultRoleID looks up the role new accounts are created with.
+func defaultRoleID(ctx context.Context, repo Repository) (pgtype.Int4, error) {
+	role, err := repo.GetRoleByName(ctx, defaultRoleName)
+	if err != nil {
+		return pgtype.Int4{}, fmt.Errorf("default role %q: %w", defaultRoleName, err)
+	}
+	return pgtype.Int4{Int32: int32(role.ID), Valid: true}, nil
+}
+
+func (s *authService) invalidateUserCache(ctx context.Context, userID int64) {
+	if s.cfg.CacheEnabled {
+		s.cache.Users.Delete(ctx, userID)
+	}
+}
 
+// rehashPassword upgrades a hash made with an old algorithm or weaker
+// parameters. It runs after a successful login, the only time the plain
+// password is known, and must never fail the login itself.
+func (s *authService) rehashPassword(ctx context.Context, user sqlc.User, plain string) {
+	hashed, err := password.Hash(plain)
+	if err != nil {
+		log.Printf("Error rehashing password for user %d: %v", user.ID, err)
+		return
+	}
+
+	if err := s.repo.RehashUserPassword(ctx, user.ID, user.Password, hashed); err != nil {
+		log.Printf("Error rehashing password for user %d: %v", user.ID, err)
+		return
+	}
+
+	s.invalidateUserCache(ctx, user.ID)
+}
+
+func (s *authService) Login(ctx context.Context, email, plainPassword string, client ClientMeta) (*LoginResponse, error) {
+	if err := s.lockout.Check(ctx, email, client.IP); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			// Unknown emails are counted too, otherwise the lockout would
+			// reveal which accounts exist.
+			return nil, s.loginFailed(ctx, nil, email, client)
+		}
+		return nil, err
+	}
+
+	ok, needsRehash, err := password.Verify(user.Password, plainPassword)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		log.Printf("Password mismatch for email %s", email)
+		return nil, s.loginFailed(ctx, &user, email, client)
+	}
+
+	if needsRehash {
+		s.rehashPassword(ctx, user, plainPassword)
+	}
+
+	if err := s.lockout.Succeed(ctx, email); err != nil {
+		return nil, err
+	}
+
+	if !user.Verified {
+		return nil, ErrNotVerified
+	}
+
+	res, err := s.completeLogin(ctx, user, client)
 	if err != nil {
 		log.Printf("Error generating token for user %s: %v", email, err)
-		return "", err
+		return nil, err
 	}
 
-	return token, nil
+	return res, nil
+}
+
+// completeLogin is called once the user has proven who they are with their
+// first factor. Accounts with MFA enabled only get a short-lived MFA token,
+// everyone else starts a new session.
+func (s *authService) completeLogin(ctx context.Context, user sqlc.User, client ClientMeta) (*LoginResponse, error) {
+	mfa, err := s.repo.GetUserMFA(ctx, user.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	if err == nil && mfa.Enabled {
+		return s.issueMFAToken(user)
+	}
+
+	return s.startSession(ctx, user, client)
+}
+
+// startSession issues the first access and refresh token pair of a new
+// session.
+func (s *authService) startSession(ctx context.Context, user sqlc.User, client ClientMeta) (*LoginResponse, error) {
+	familyID, err := newFamilyID()
+	if err != nil {
+		return nil, err
+	}
+
+	var res *LoginResponse
+	err = s.repo.WithTx(ctx, func(repo Repository) error {
+		res, err = s.issueTokens(ctx, repo, user, familyID, pgtype.Int8{}, client)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Refresh rotates a refresh token: the presented token is revoked and a new
+// one is issued in the same family. Presenting a token that was already
+// rotated means it leaked, so the whole family is revoked.
+func (s *authService) Refresh(ctx context.Context, refreshToken string, client ClientMeta) (*LoginResponse, error) {
+	current, err := s.repo.GetRefreshTokenByHash(ctx, auth.HashOpaqueToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if current.RevokedAt.Valid {
+		return nil, s.revokeFamily(ctx, current.FamilyID)
+	}
+
+	if s.now().After(current.ExpiresAt.Time) {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := s.repo.GetUserByID(ctx, current.UserID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if !user.Verified {
+		return nil, ErrNotVerified
+	}
+
+	var res *LoginResponse
+	err = s.repo.WithTx(ctx, func(repo Repository) error {
+		revoked, err := repo.RevokeRefreshToken(ctx, current.ID)
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			// Another request rotated this token between the read and now.
+			return ErrTokenReused
+		}
+
+		res, err = s.issueTokens(ctx, repo, user, current.FamilyID, pgtype.Int8{Int64: current.ID, Valid: true}, client)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrTokenReused) {
+			return nil, s.revokeFamily(ctx, current.FamilyID)
+		}
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Logout revokes the access token identified by claims and its session,
+// and, when given, the refresh token family it was issued with.
+func (s *authService) Logout(ctx context.Context, claims *auth.Claims, refreshToken string) error {
+	expiresAt := s.now().Add(s.cfg.TokenExp)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	if err := s.revocations.Revoke(ctx, claims.ID, expiresAt); err != nil {
+		return err
+	}
+
+	if claims.SessionID != "" {
+		err := s.RevokeSession(ctx, claims.UserID, claims.SessionID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	current, err := s.repo.GetRefreshTokenByHash(ctx, auth.HashOpaqueToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if current.UserID != claims.UserID {
+		return nil
+	}
+
+	return s.repo.RevokeRefreshTokenFamily(ctx, current.FamilyID)
+}
+
+// LogoutAll bumps the user's token version, which invalidates every access
+// token issued so far, and revokes all of their refresh tokens and personal
+// access tokens.
+func (s *authService) LogoutAll(ctx context.Context, userID int64) error {
+	err := s.repo.WithTx(ctx, func(repo Repository) error {
+		if err := repo.IncrementTokenVersion(ctx, userID); err != nil {
+			return err
+		}
+
+		if err := repo.RevokeUserSessions(ctx, userID); err != nil {
+			return err
+		}
+
+		if err := repo.RevokeUserPersonalAccessTokens(ctx, userID); err != nil {
+			return err
+		}
+
+		return repo.RevokeUserRefreshTokens(ctx, userID)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.invalidateUserCache(ctx, userID)
+
+	return nil
+}
+
+func (s *authService) revokeFamily(ctx context.Context, familyID string) error {
+	if err := s.repo.RevokeRefreshTokenFamily(ctx, familyID); err != nil {
+		return err
+	}
+	return ErrTokenReused
+}
+
+// issueTokens signs a short-lived access token and persists a new refresh
+// token belonging to familyID. The family is the session, which is created
+// or refreshed with the client's details here.
+func (s *authService) issueTokens(ctx context.Context, repo Repository, user sqlc.User, familyID string, parentID pgtype.Int8, client ClientMeta) (*LoginResponse, error) {
+	refreshExpiresAt := pgtype.Timestamptz{Time: s.now().Add(s.cfg.RefreshExp), Valid: true}
+
+	session, err := repo.UpsertSession(ctx, sqlc.UpsertSessionParams{
+		ID:        familyID,
+		UserID:    user.ID,
+		UserAgent: client.UserAgent,
+		Ip:        client.IP,
+		ExpiresAt: refreshExpiresAt,
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if session.RevokedAt.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	token, claims, err := s.signToken(user, "", familyID, s.cfg.TokenExp)
+	if err != nil {
+		return nil, err
+	}
+
+	plainRefresh, hashRefresh, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = repo.CreateRefreshToken(ctx, sqlc.CreateRefreshTokenParams{
+		UserID:    user.ID,
+		TokenHash: hashRefresh,
+		FamilyID:  familyID,
+		ParentID:  parentID,
+		ExpiresAt: refreshExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		Token:        token,
+		RefreshToken: plainRefresh,
+		ExpiresAt:    claims.ExpiresAt.Time,
+	}, nil
+}
+
+// signToken signs a JWT for user valid for ttl. Access tokens have an empty
+// purpose and belong to a session.
+func (s *authService) signToken(user sqlc.User, purpose, sessionID string, ttl time.Duration) (string, *auth.Claims, error) {
+	claims, err := s.newClaims(user, purpose, sessionID, ttl)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, err := s.authenticator.GenerateToken(claims)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, claims, nil
+}
+
+func (s *authService) newClaims(user sqlc.User, purpose, sessionID string, ttl time.Duration) (*auth.Claims, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	claims := &auth.Claims{
+		UserID:       user.ID,
+		RoleID:       user.RoleID.Int32,
+		TokenVersion: user.TokenVersion,
+		Purpose:      purpose,
+		SessionID:    sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			Issuer:    s.cfg.TokenIss,
+			Audience:  []string{s.cfg.TokenAud},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Subject:   fmt.Sprintf("%d", user.ID),
+			ID:        jti,
+		},
+	}
+
+	return claims, nil
+}
+
+func newFamilyID() (string, error) {
+	return randomID()
+}
+
+func newTokenID() (string, error) {
+	return randomID()
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
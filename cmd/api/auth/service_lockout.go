@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/mailer"
+)
+
+// loginFailed records a failed attempt and always returns the error the
+// caller should hand back, ErrInvalidCredentials unless recording failed.
+// user is nil when the email is unknown.
+func (s *authService) loginFailed(ctx context.Context, user *sqlc.User, email string, client ClientMeta) error {
+	res, err := s.lockout.Fail(ctx, email, client.IP)
+	if err != nil {
+		return err
+	}
+
+	if res.AccountLocked && user != nil {
+		// The lock has already been stored, a mail failure must not turn
+		// the response into a 500.
+		if err := s.sendUnlockEmail(ctx, *user); err != nil {
+			log.Printf("Error sending unlock email to %s: %v", email, err)
+		}
+	}
+
+	return ErrInvalidCredentials
+}
+
+func (s *authService) sendUnlockEmail(ctx context.Context, user sqlc.User) error {
+	plainToken, hashToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	exp := s.lockout.LockoutDuration()
+
+	err = s.repo.CreateAccountUnlock(ctx, sqlc.CreateAccountUnlockParams{
+		UserID:    user.ID,
+		TokenHash: hashToken,
+		ExpiresAt: pgtype.Timestamptz{Time: s.now().Add(exp), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	vars := struct {
+		Username  string
+		UnlockURL string
+		ExpiresIn string
+	}{
+		Username:  user.Username,
+		UnlockURL: fmt.Sprintf("%s/unlock/%s", s.cfg.FrontendURL, plainToken),
+		ExpiresIn: exp.String(),
+	}
+
+	if _, err := s.mailer.Send(mailer.AccountLockedTemplate, user.Username, user.Email, vars, !s.cfg.IsProdEnv); err != nil {
+		return fmt.Errorf("failed to send account locked email: %w", err)
+	}
+
+	return nil
+}
+
+// UnlockAccount consumes the token from the account locked email and clears
+// the lockout of that account.
+func (s *authService) UnlockAccount(ctx context.Context, token string) error {
+	userID, err := s.repo.ConsumeAccountUnlock(ctx, auth.HashOpaqueToken(token))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	return s.ClearLockout(ctx, userID)
+}
+
+// ClearLockout removes the lockout of a user, used by admins.
+func (s *authService) ClearLockout(ctx context.Context, userID int64) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.lockout.UnlockAccount(ctx, user.Email)
+}
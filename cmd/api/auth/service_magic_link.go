@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/mailer"
+)
+
+const (
+	magicLinkExp = 15 * time.Minute
+	// At most magicLinkLimit links are mailed per account within
+	// magicLinkWindow.
+	magicLinkLimit  = 3
+	magicLinkWindow = time.Hour
+)
+
+// RequestMagicLink mails a single-use sign-in link. Like ForgotPassword it
+// never reveals whether the email belongs to an account, requests over the
+// limit are dropped silently for the same reason.
+func (s *authService) RequestMagicLink(ctx context.Context, email string) error {
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if !user.Verified {
+		return nil
+	}
+
+	sent, err := s.repo.CountRecentMagicLinks(ctx, user.ID, s.now().Add(-magicLinkWindow))
+	if err != nil {
+		return err
+	}
+	if sent >= magicLinkLimit {
+		log.Printf("Magic link limit reached for user %d", user.ID)
+		return nil
+	}
+
+	plainToken, hashToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.CreateMagicLink(ctx, sqlc.CreateMagicLinkParams{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: hashToken,
+		ExpiresAt: pgtype.Timestamptz{Time: s.now().Add(magicLinkExp), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	vars := struct {
+		Username  string
+		LoginURL  string
+		ExpiresIn string
+	}{
+		Username:  user.Username,
+		LoginURL:  fmt.Sprintf("%s/magic-link/%s", s.cfg.FrontendURL, plainToken),
+		ExpiresIn: magicLinkExp.String(),
+	}
+
+	if _, err := s.mailer.Send(mailer.MagicLinkTemplate, user.Username, user.Email, vars, !s.cfg.IsProdEnv); err != nil {
+		return fmt.Errorf("failed to send magic link email: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeMagicLink signs the user in. Using a link invalidates every other
+// outstanding link of the account, and a link is only honoured while the
+// account still has the email it was sent to.
+func (s *authService) ConsumeMagicLink(ctx context.Context, token string, client ClientMeta) (*LoginResponse, error) {
+	var user sqlc.User
+	err := s.repo.WithTx(ctx, func(repo Repository) error {
+		link, err := repo.ConsumeMagicLink(ctx, auth.HashOpaqueToken(token))
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrInvalidToken
+			}
+			return err
+		}
+
+		if err := repo.InvalidateMagicLinks(ctx, link.UserID); err != nil {
+			return err
+		}
+
+		user, err = repo.GetUserByID(ctx, link.UserID)
+		if err != nil {
+			return err
+		}
+
+		if user.Email != link.Email {
+			return ErrInvalidToken
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.Verified {
+		return nil, ErrNotVerified
+	}
+
+	return s.completeLogin(ctx, user, client)
+}
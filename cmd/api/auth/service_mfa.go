@@ -0,0 +1,309 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/mailer"
+	"github.com/mifaabiyyu/backend-go/internal/totp"
+)
+
+const (
+	mfaTokenExp       = 5 * time.Minute
+	mfaSkew           = 1
+	recoveryCodeCount = 10
+	recoveryCodeSize  = 10
+
+	// mfaMaxAttempts is how many wrong codes a user may send, across all of
+	// their MFA tokens, before the token in use is revoked.
+	mfaMaxAttempts = 5
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// EnrollMFA generates a new TOTP secret for the user. MFA stays disabled
+// until the user proves their authenticator works by calling EnableMFA.
+func (s *authService) EnrollMFA(ctx context.Context, userID int64) (*MFAEnrollResponse, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	mfa, err := s.repo.GetUserMFA(ctx, userID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if err == nil && mfa.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpsertUserMFA(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+
+	return &MFAEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(mailer.FromName, user.Email, secret),
+	}, nil
+}
+
+// EnableMFA turns MFA on once code matches the enrolled secret and returns
+// a fresh set of recovery codes. The codes are only stored hashed, so this
+// is the only time they can be shown.
+func (s *authService) EnableMFA(ctx context.Context, userID int64, code string) (*MFARecoveryCodesResponse, error) {
+	mfa, err := s.repo.GetUserMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrMFANotEnabled
+		}
+		return nil, err
+	}
+	if mfa.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(mfa.Secret, code, s.now(), mfaSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+
+	err = s.repo.WithTx(ctx, func(repo Repository) error {
+		if err := repo.EnableUserMFA(ctx, userID, step); err != nil {
+			return err
+		}
+
+		if err := repo.DeleteMFARecoveryCodes(ctx, userID); err != nil {
+			return err
+		}
+
+		for i := 0; i < recoveryCodeCount; i++ {
+			code, err := newRecoveryCode()
+			if err != nil {
+				return err
+			}
+
+			if err := repo.CreateMFARecoveryCode(ctx, userID, hashRecoveryCode(code)); err != nil {
+				return err
+			}
+			codes = append(codes, code)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableMFA removes the secret and all recovery codes. A valid TOTP or
+// recovery code is required so a stolen access token alone cannot turn
+// MFA off, and wrong codes count towards the same lockout as VerifyMFA so
+// the token cannot be used to guess them either.
+func (s *authService) DisableMFA(ctx context.Context, userID int64, code string, client ClientMeta) error {
+	account := mfaAccount(userID)
+	if err := s.lockout.Check(ctx, account, client.IP); err != nil {
+		return err
+	}
+
+	mfa, err := s.repo.GetUserMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrMFANotEnabled
+		}
+		return err
+	}
+	if !mfa.Enabled {
+		return ErrMFANotEnabled
+	}
+
+	if err := s.checkMFACode(ctx, mfa, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if _, err := s.lockout.Fail(ctx, account, client.IP); err != nil {
+				return err
+			}
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+
+	if err := s.lockout.Succeed(ctx, account); err != nil {
+		return err
+	}
+
+	return s.repo.WithTx(ctx, func(repo Repository) error {
+		if err := repo.DeleteMFARecoveryCodes(ctx, userID); err != nil {
+			return err
+		}
+		return repo.DeleteUserMFA(ctx, userID)
+	})
+}
+
+// VerifyMFA exchanges the MFA token returned by Login plus a TOTP or
+// recovery code for an access and refresh token pair. The MFA token is
+// revoked on success so it cannot be used twice.
+//
+// Every login hands out a new MFA token, so wrong codes are counted per
+// user by the lockout guard rather than per token. Past the free attempts
+// each failure delays the next try, and after mfaMaxAttempts the token is
+// revoked so the attacker has to get through the password check again.
+func (s *authService) VerifyMFA(ctx context.Context, mfaToken, code string, client ClientMeta) (*LoginResponse, error) {
+	token, err := s.authenticator.ValidateToken(mfaToken)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(*auth.Claims)
+	if !ok || claims.Purpose != auth.PurposeMFA || claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	revoked, err := s.revocations.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrInvalidToken
+	}
+
+	account := mfaAccount(claims.UserID)
+	if err := s.lockout.Check(ctx, account, client.IP); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if claims.TokenVersion != user.TokenVersion {
+		return nil, ErrInvalidToken
+	}
+
+	mfa, err := s.repo.GetUserMFA(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if !mfa.Enabled {
+		return nil, ErrInvalidToken
+	}
+
+	if err := s.checkMFACode(ctx, mfa, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			return nil, s.mfaFailed(ctx, claims, account, client)
+		}
+		return nil, err
+	}
+
+	if err := s.lockout.Succeed(ctx, account); err != nil {
+		return nil, err
+	}
+
+	if err := s.revocations.Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return nil, err
+	}
+
+	return s.startSession(ctx, user, client)
+}
+
+// checkMFACode accepts either a TOTP code, which must be newer than the
+// last one used, or an unused recovery code.
+func (s *authService) checkMFACode(ctx context.Context, mfa sqlc.UserMfa, code string) error {
+	code = strings.TrimSpace(code)
+
+	if step, ok := totp.Validate(mfa.Secret, code, s.now(), mfaSkew); ok {
+		fresh, err := s.repo.UpdateMFALastUsedStep(ctx, mfa.UserID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := s.repo.UseMFARecoveryCode(ctx, mfa.UserID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+// mfaFailed records a wrong code and returns ErrInvalidMFACode unless
+// recording failed.
+func (s *authService) mfaFailed(ctx context.Context, claims *auth.Claims, account string, client ClientMeta) error {
+	res, err := s.lockout.Fail(ctx, account, client.IP)
+	if err != nil {
+		return err
+	}
+
+	if res.Failures >= mfaMaxAttempts {
+		if err := s.revocations.Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			return err
+		}
+	}
+
+	return ErrInvalidMFACode
+}
+
+// mfaAccount is the lockout key for MFA failures. Login failures are keyed
+// by email, so the two counters never mix.
+func mfaAccount(userID int64) string {
+	return fmt.Sprintf("mfa:%d", userID)
+}
+
+func (s *authService) issueMFAToken(user sqlc.User) (*LoginResponse, error) {
+	token, claims, err := s.signToken(user, auth.PurposeMFA, "", mfaTokenExp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		ExpiresAt:   claims.ExpiresAt.Time,
+		MFARequired: true,
+		MFAToken:    token,
+	}, nil
+}
+
+// newRecoveryCode returns a code formatted as xxxxx-xxxxx so it is easy to
+// read back from paper.
+func newRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:recoveryCodeSize]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// hashRecoveryCode normalises the code before hashing so users may type it
+// with or without the dash and in any case.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return auth.HashOpaqueToken(code)
+}
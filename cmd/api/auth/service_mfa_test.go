@@ -0,0 +1,292 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/lockout"
+	"github.com/mifaabiyyu/backend-go/internal/totp"
+)
+
+const mfaTestSecret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+
+// mfaRepo keeps the MFA rows of a single user in memory. Calling a method
+// it does not override panics on the nil embedded Repository.
+type mfaRepo struct {
+	Repository
+
+	mu            sync.Mutex
+	user          sqlc.User
+	mfa           sqlc.UserMfa
+	recoveryCodes map[string]bool
+}
+
+func newMFARepo() *mfaRepo {
+	return &mfaRepo{
+		user: sqlc.User{ID: 7, Email: "mfa@example.com", Verified: true, TokenVersion: 3},
+		mfa: sqlc.UserMfa{
+			UserID:  7,
+			Secret:  mfaTestSecret,
+			Enabled: true,
+		},
+		recoveryCodes: make(map[string]bool),
+	}
+}
+
+func (r *mfaRepo) GetUserByID(ctx context.Context, userID int64) (sqlc.User, error) {
+	if userID != r.user.ID {
+		return sqlc.User{}, ErrNotFound
+	}
+	return r.user, nil
+}
+
+func (r *mfaRepo) GetUserMFA(ctx context.Context, userID int64) (sqlc.UserMfa, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if userID != r.mfa.UserID {
+		return sqlc.UserMfa{}, ErrNotFound
+	}
+	return r.mfa, nil
+}
+
+func (r *mfaRepo) UpdateMFALastUsedStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if step <= r.mfa.LastUsedStep {
+		return false, nil
+	}
+	r.mfa.LastUsedStep = step
+	return true, nil
+}
+
+func (r *mfaRepo) UseMFARecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.recoveryCodes[codeHash] {
+		return false, nil
+	}
+	delete(r.recoveryCodes, codeHash)
+	return true, nil
+}
+
+func codeAt(t *testing.T, at time.Time) string {
+	t.Helper()
+
+	code, err := totp.Code(mfaTestSecret, at)
+	if err != nil {
+		t.Fatalf("totp.Code: %v", err)
+	}
+	return code
+}
+
+func TestCheckMFACodeWindow(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset time.Duration
+		ok     bool
+	}{
+		{"current step", 0, true},
+		{"previous step", -totp.Period, true},
+		{"next step", totp.Period, true},
+		{"two steps behind", -2 * totp.Period, false},
+		{"two steps ahead", 2 * totp.Period, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMFARepo()
+			s, now := newTestService(repo, lockoutDisabled)
+
+			err := s.checkMFACode(context.Background(), repo.mfa, codeAt(t, now.Add(tt.offset)))
+			if tt.ok && err != nil {
+				t.Fatalf("expected the code to be accepted, got %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidMFACode) {
+				t.Fatalf("expected ErrInvalidMFACode, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckMFACodeReplay(t *testing.T) {
+	repo := newMFARepo()
+	s, now := newTestService(repo, lockoutDisabled)
+	ctx := context.Background()
+
+	code := codeAt(t, *now)
+	if err := s.checkMFACode(ctx, repo.mfa, code); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := s.checkMFACode(ctx, repo.mfa, code); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("replay in the same step: expected ErrInvalidMFACode, got %v", err)
+	}
+
+	// An older code is still inside the window but must not be accepted
+	// once a newer step has been used.
+	if err := s.checkMFACode(ctx, repo.mfa, codeAt(t, now.Add(-totp.Period))); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("older step: expected ErrInvalidMFACode, got %v", err)
+	}
+
+	*now = now.Add(totp.Period)
+	if err := s.checkMFACode(ctx, repo.mfa, codeAt(t, *now)); err != nil {
+		t.Fatalf("code of the next step: %v", err)
+	}
+}
+
+func TestCheckMFACodeRecoveryCodes(t *testing.T) {
+	repo := newMFARepo()
+	s, _ := newTestService(repo, lockoutDisabled)
+	ctx := context.Background()
+
+	code, err := newRecoveryCode()
+	if err != nil {
+		t.Fatalf("newRecoveryCode: %v", err)
+	}
+	other, err := newRecoveryCode()
+	if err != nil {
+		t.Fatalf("newRecoveryCode: %v", err)
+	}
+	repo.recoveryCodes[hashRecoveryCode(code)] = true
+	repo.recoveryCodes[hashRecoveryCode(other)] = true
+
+	if err := s.checkMFACode(ctx, repo.mfa, code); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := s.checkMFACode(ctx, repo.mfa, code); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("second use: expected ErrInvalidMFACode, got %v", err)
+	}
+
+	// Typed back from paper without the dash and in upper case.
+	typed := " " + strings.ToUpper(strings.ReplaceAll(other, "-", "")) + " "
+	if err := s.checkMFACode(ctx, repo.mfa, typed); err != nil {
+		t.Fatalf("normalised code: %v", err)
+	}
+
+	if err := s.checkMFACode(ctx, repo.mfa, "aaaaa-bbbbb"); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("unknown code: expected ErrInvalidMFACode, got %v", err)
+	}
+}
+
+func TestVerifyMFARevokesTokenAfterMaxAttempts(t *testing.T) {
+	repo := newMFARepo()
+	s, now := newTestService(repo, lockout.Config{
+		Enabled:            true,
+		MaxAccountFailures: 100,
+		MaxIPFailures:      100,
+		FreeAttempts:       100,
+		Window:             time.Hour,
+		LockoutDuration:    time.Hour,
+	})
+	ctx := context.Background()
+	client := ClientMeta{IP: "192.0.2.1"}
+
+	res, err := s.issueMFAToken(repo.user)
+	if err != nil {
+		t.Fatalf("issueMFAToken: %v", err)
+	}
+
+	wrong := codeAt(t, now.Add(-5*totp.Period))
+	for i := 1; i <= mfaMaxAttempts; i++ {
+		if _, err := s.VerifyMFA(ctx, res.MFAToken, wrong, client); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("attempt %d: expected ErrInvalidMFACode, got %v", i, err)
+		}
+	}
+
+	// Even the right code is refused now, a new password login is needed.
+	if _, err := s.VerifyMFA(ctx, res.MFAToken, codeAt(t, *now), client); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken after %d failures, got %v", mfaMaxAttempts, err)
+	}
+
+	// The counter is per user, a fresh token does not reset it.
+	fresh, err := s.issueMFAToken(repo.user)
+	if err != nil {
+		t.Fatalf("issueMFAToken: %v", err)
+	}
+	if _, err := s.VerifyMFA(ctx, fresh.MFAToken, wrong, client); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("expected ErrInvalidMFACode, got %v", err)
+	}
+	if _, err := s.VerifyMFA(ctx, fresh.MFAToken, codeAt(t, *now), client); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected the fresh token to be revoked after one more failure, got %v", err)
+	}
+}
+
+func TestVerifyMFALocksUser(t *testing.T) {
+	repo := newMFARepo()
+	s, now := newTestService(repo, lockout.Config{
+		Enabled:            true,
+		MaxAccountFailures: 3,
+		MaxIPFailures:      100,
+		FreeAttempts:       3,
+		Window:             time.Hour,
+		LockoutDuration:    time.Hour,
+	})
+	ctx := context.Background()
+	client := ClientMeta{IP: "192.0.2.1"}
+
+	wrong := codeAt(t, now.Add(-5*totp.Period))
+	for i := 0; i < 3; i++ {
+		res, err := s.issueMFAToken(repo.user)
+		if err != nil {
+			t.Fatalf("issueMFAToken: %v", err)
+		}
+		if _, err := s.VerifyMFA(ctx, res.MFAToken, wrong, client); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("attempt %d: expected ErrInvalidMFACode, got %v", i+1, err)
+		}
+	}
+
+	res, err := s.issueMFAToken(repo.user)
+	if err != nil {
+		t.Fatalf("issueMFAToken: %v", err)
+	}
+
+	var locked *lockout.LockedError
+	if _, err := s.VerifyMFA(ctx, res.MFAToken, codeAt(t, *now), client); !errors.As(err, &locked) {
+		t.Fatalf("expected a lockout, got %v", err)
+	}
+}
+
+func TestDisableMFALocksUser(t *testing.T) {
+	repo := newMFARepo()
+	s, now := newTestService(repo, lockout.Config{
+		Enabled:            true,
+		MaxAccountFailures: 3,
+		MaxIPFailures:      100,
+		FreeAttempts:       3,
+		Window:             time.Hour,
+		LockoutDuration:    time.Hour,
+	})
+	ctx := context.Background()
+	client := ClientMeta{IP: "192.0.2.1"}
+
+	wrong := codeAt(t, now.Add(-5*totp.Period))
+	for i := 0; i < 3; i++ {
+		if err := s.DisableMFA(ctx, repo.user.ID, wrong, client); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("attempt %d: expected ErrInvalidMFACode, got %v", i+1, err)
+		}
+	}
+
+	var locked *lockout.LockedError
+	if err := s.DisableMFA(ctx, repo.user.ID, codeAt(t, *now), client); !errors.As(err, &locked) {
+		t.Fatalf("expected a lockout, got %v", err)
+	}
+	if !repo.mfa.Enabled {
+		t.Fatal("MFA was disabled")
+	}
+
+	// The counter is the one VerifyMFA uses, so the login is locked too.
+	res, err := s.issueMFAToken(repo.user)
+	if err != nil {
+		t.Fatalf("issueMFAToken: %v", err)
+	}
+	if _, err := s.VerifyMFA(ctx, res.MFAToken, codeAt(t, *now), client); !errors.As(err, &locked) {
+		t.Fatalf("expected VerifyMFA to be locked, got %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/oidc"
+	"github.com/mifaabiyyu/backend-go/internal/password"
+)
+
+const oauthStateExp = 10 * time.Minute
+
+var (
+	ErrUnknownProvider      = errors.New("unknown identity provider")
+	ErrOAuthFailed          = errors.New("sign in with the identity provider failed")
+	ErrOAuthEmailUnverified = errors.New("the identity provider did not return a verified email")
+	ErrOAuthLinkRequired    = errors.New("sign in to your account and link this identity from there")
+	ErrIdentityLinked       = errors.New("this identity is already linked to another account")
+)
+
+// OAuthProvider is an OpenID Connect provider, implemented by
+// *oidc.Provider.
+type OAuthProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*oidc.Claims, error)
+}
+
+// OAuthStart creates the state, nonce and PKCE verifier of a new sign-in
+// and returns the provider URL the user has to visit.
+func (s *authService) OAuthStart(ctx context.Context, provider string) (*OAuthStartResponse, error) {
+	return s.startOAuth(ctx, provider, pgtype.Int8{})
+}
+
+// OAuthLinkStart starts a sign-in whose identity is linked to userID by the
+// callback, whatever email the provider returns.
+func (s *authService) OAuthLinkStart(ctx context.Context, userID int64, provider string) (*OAuthStartResponse, error) {
+	return s.startOAuth(ctx, provider, pgtype.Int8{Int64: userID, Valid: true})
+}
+
+func (s *authService) startOAuth(ctx context.Context, provider string, userID pgtype.Int8) (*OAuthStartResponse, error) {
+	p, ok := s.cfg.OAuthProviders[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.CreateOAuthState(ctx, sqlc.CreateOAuthStateParams{
+		StateHash:    auth.HashOpaqueToken(state),
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    pgtype.Timestamptz{Time: s.now().Add(oauthStateExp), Valid: true},
+		UserID:       userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &OAuthStartResponse{AuthURL: authURL, State: state}, nil
+}
+
+// OAuthCallback finishes a sign-in: the state is consumed, the code is
+// exchanged and the verified ID token is mapped to a local user, or linked
+// to the user who started it with OAuthLinkStart.
+func (s *authService) OAuthCallback(ctx context.Context, provider, code, state string, client ClientMeta) (*LoginResponse, error) {
+	p, ok := s.cfg.OAuthProviders[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	st, err := s.repo.ConsumeOAuthState(ctx, auth.HashOpaqueToken(state), provider)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	claims, err := p.Exchange(ctx, code, st.CodeVerifier, st.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOAuthFailed, err)
+	}
+
+	var user sqlc.User
+	if st.UserID.Valid {
+		user, err = s.linkIdentity(ctx, st.UserID.Int64, provider, claims)
+	} else {
+		user, err = s.userForIdentity(ctx, provider, claims)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(ctx, user, client)
+}
+
+// userForIdentity returns the user linked to the external subject. Unknown
+// subjects are linked to the account with the same verified email, or a
+// new account is created. Emails from a multi-tenant issuer are never
+// linked, any tenant admin can claim one, those identities have to be
+// linked with OAuthLinkStart.
+func (s *authService) userForIdentity(ctx context.Context, provider string, claims *oidc.Claims) (sqlc.User, error) {
+	email := strings.TrimSpace(strings.ToLower(claims.Email))
+
+	user, err := s.repo.GetUserByIdentity(ctx, provider, claims.Subject)
+	if err == nil {
+		if err := s.repo.TouchUserIdentity(ctx, provider, claims.Subject, email); err != nil {
+			return sqlc.User{}, err
+		}
+		return user, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return sqlc.User{}, err
+	}
+
+	// Linking by email is only safe when the provider vouches for it.
+	if email == "" || !claims.EmailVerified {
+		return sqlc.User{}, ErrOAuthEmailUnverified
+	}
+
+	// Accounts created or taken over here never get a usable password,
+	// the random one is only there because the column is required.
+	unusable, err := oidc.RandomString(32)
+	if err != nil {
+		return sqlc.User{}, err
+	}
+	unusableHash, err := password.Hash(unusable)
+	if err != nil {
+		return sqlc.User{}, err
+	}
+
+	var userID int64
+	err = s.repo.WithTx(ctx, func(repo Repository) error {
+		existing, err := repo.GetUserByEmail(ctx, email)
+		switch {
+		case err == nil && claims.MultiTenant:
+			return ErrOAuthLinkRequired
+		case err == nil:
+			userID = existing.ID
+
+			// Someone may have registered this email without being able
+			// to confirm it. The provider just proved who owns it, so the
+			// account is activated and the unconfirmed password dropped.
+			if !existing.Verified {
+				if err := repo.VerifyUser(ctx, userID); err != nil {
+					return err
+				}
+				if err := repo.UpdateUserPassword(ctx, userID, unusableHash); err != nil {
+					return err
+				}
+				if err := repo.DeleteUserInvitations(ctx, userID); err != nil {
+					return err
+				}
+				if err := repo.IncrementTokenVersion(ctx, userID); err != nil {
+					return err
+				}
+			}
+		case errors.Is(err, ErrNotFound):
+			roleID, err := defaultRoleID(ctx, repo)
+			if err != nil {
+				return err
+			}
+
+			created, err := repo.CreateUser(ctx, sqlc.CreateUserParams{
+				Email:    email,
+				Username: oauthUsername(claims, email),
+				FullName: claims.Name,
+				Password: unusableHash,
+				RoleID:   roleID,
+			})
+			if err != nil {
+				return err
+			}
+			userID = created.ID
+
+			if err := repo.VerifyUser(ctx, userID); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		return repo.CreateUserIdentity(ctx, sqlc.CreateUserIdentityParams{
+			UserID:   userID,
+			Provider: provider,
+			Subject:  claims.Subject,
+			Email:    email,
+		})
+	})
+	if err != nil {
+		return sqlc.User{}, err
+	}
+
+	s.invalidateUserCache(ctx, userID)
+
+	return s.repo.GetUserByID(ctx, userID)
+}
+
+// linkIdentity links the external subject to userID, who proved who they
+// are by starting the sign-in from their session.
+func (s *authService) linkIdentity(ctx context.Context, userID int64, provider string, claims *oidc.Claims) (sqlc.User, error) {
+	email := strings.TrimSpace(strings.ToLower(claims.Email))
+
+	linked, err := s.repo.GetUserByIdentity(ctx, provider, claims.Subject)
+	switch {
+	case err == nil && linked.ID != userID:
+		return sqlc.User{}, ErrIdentityLinked
+	case err == nil:
+		if err := s.repo.TouchUserIdentity(ctx, provider, claims.Subject, email); err != nil {
+			return sqlc.User{}, err
+		}
+	case errors.Is(err, ErrNotFound):
+		err := s.repo.CreateUserIdentity(ctx, sqlc.CreateUserIdentityParams{
+			UserID:   userID,
+			Provider: provider,
+			Subject:  claims.Subject,
+			Email:    email,
+		})
+		if err != nil {
+			return sqlc.User{}, err
+		}
+	default:
+		return sqlc.User{}, err
+	}
+
+	return s.repo.GetUserByID(ctx, userID)
+}
+
+func oauthUsername(claims *oidc.Claims, email string) string {
+	if name := strings.TrimSpace(claims.PreferredUsername); name != "" {
+		return name
+	}
+	if name := strings.TrimSpace(claims.Name); name != "" {
+		return name
+	}
+	local, _, _ := strings.Cut(email, "@")
+	return local
+}
@@ -0,0 +1,428 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/oidc"
+)
+
+// oauthRepo keeps users, identities and OAuth states in memory. Calling a
+// method it does not override panics on the nil embedded Repository.
+type oauthRepo struct {
+	Repository
+
+	users      map[int64]*sqlc.User
+	identities map[string]int64
+	states     map[string]sqlc.OauthState
+	nextID     int64
+
+	invitationsDeleted []int64
+}
+
+func newOAuthRepo(users ...sqlc.User) *oauthRepo {
+	r := &oauthRepo{
+		users:      make(map[int64]*sqlc.User),
+		identities: make(map[string]int64),
+		states:     make(map[string]sqlc.OauthState),
+		nextID:     100,
+	}
+	for i := range users {
+		r.users[users[i].ID] = &users[i]
+	}
+	return r
+}
+
+func (r *oauthRepo) WithTx(ctx context.Context, fn func(Repository) error) error {
+	return fn(r)
+}
+
+func (r *oauthRepo) CreateOAuthState(ctx context.Context, arg sqlc.CreateOAuthStateParams) error {
+	r.states[arg.StateHash] = sqlc.OauthState{
+		StateHash:    arg.StateHash,
+		Provider:     arg.Provider,
+		Nonce:        arg.Nonce,
+		CodeVerifier: arg.CodeVerifier,
+		ExpiresAt:    arg.ExpiresAt,
+		UserID:       arg.UserID,
+	}
+	return nil
+}
+
+func (r *oauthRepo) ConsumeOAuthState(ctx context.Context, stateHash, provider string) (sqlc.OauthState, error) {
+	st, ok := r.states[stateHash]
+	if !ok || st.Provider != provider {
+		return sqlc.OauthState{}, ErrNotFound
+	}
+	delete(r.states, stateHash)
+	return st, nil
+}
+
+func (r *oauthRepo) GetUserByIdentity(ctx context.Context, provider, subject string) (sqlc.User, error) {
+	id, ok := r.identities[provider+"|"+subject]
+	if !ok {
+		return sqlc.User{}, ErrNotFound
+	}
+	return *r.users[id], nil
+}
+
+func (r *oauthRepo) TouchUserIdentity(ctx context.Context, provider, subject, email string) error {
+	return nil
+}
+
+func (r *oauthRepo) CreateUserIdentity(ctx context.Context, arg sqlc.CreateUserIdentityParams) error {
+	r.identities[arg.Provider+"|"+arg.Subject] = arg.UserID
+	return nil
+}
+
+func (r *oauthRepo) GetUserByID(ctx context.Context, userID int64) (sqlc.User, error) {
+	user, ok := r.users[userID]
+	if !ok {
+		return sqlc.User{}, ErrNotFound
+	}
+	return *user, nil
+}
+
+func (r *oauthRepo) GetUserByEmail(ctx context.Context, email string) (sqlc.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return *user, nil
+		}
+	}
+	return sqlc.User{}, ErrNotFound
+}
+
+func (r *oauthRepo) GetRoleByName(ctx context.Context, name string) (sqlc.Role, error) {
+	return sqlc.Role{ID: 1, Name: name, Level: 1}, nil
+}
+
+func (r *oauthRepo) CreateUser(ctx context.Context, arg sqlc.CreateUserParams) (sqlc.User, error) {
+	r.nextID++
+	user := sqlc.User{
+		ID:       r.nextID,
+		Email:    arg.Email,
+		Username: arg.Username,
+		FullName: arg.FullName,
+		Password: arg.Password,
+		RoleID:   arg.RoleID,
+	}
+	r.users[user.ID] = &user
+	return user, nil
+}
+
+func (r *oauthRepo) VerifyUser(ctx context.Context, userID int64) error {
+	r.users[userID].Verified = true
+	return nil
+}
+
+func (r *oauthRepo) UpdateUserPassword(ctx context.Context, userID int64, hashedPassword string) error {
+	r.users[userID].Password = hashedPassword
+	return nil
+}
+
+func (r *oauthRepo) DeleteUserInvitations(ctx context.Context, userID int64) error {
+	r.invitationsDeleted = append(r.invitationsDeleted, userID)
+	return nil
+}
+
+func (r *oauthRepo) IncrementTokenVersion(ctx context.Context, userID int64) error {
+	r.users[userID].TokenVersion++
+	return nil
+}
+
+// stubProvider hands out claims only when Exchange gets the nonce and the
+// PKCE verifier that belong to the last AuthCodeURL.
+type stubProvider struct {
+	claims    oidc.Claims
+	nonce     string
+	challenge string
+}
+
+func (p *stubProvider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	p.nonce = nonce
+	p.challenge = codeChallenge
+	return "https://idp.example.com/authorize?state=" + url.QueryEscape(state), nil
+}
+
+func (p *stubProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*oidc.Claims, error) {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != p.challenge {
+		return nil, errors.New("invalid_grant")
+	}
+	if nonce != p.nonce {
+		return nil, oidc.ErrNonceMismatch
+	}
+
+	claims := p.claims
+	return &claims, nil
+}
+
+func TestOAuthCallbackState(t *testing.T) {
+	repo := newOAuthRepo()
+	// An unverified email stops the callback right after the exchange,
+	// before a session would be started.
+	provider := &stubProvider{claims: oidc.Claims{Email: "jane@example.com"}}
+	provider.claims.Subject = "subject-1"
+
+	s, _ := newTestService(repo, lockoutDisabled)
+	s.cfg.OAuthProviders = map[string]OAuthProvider{"fake": provider, "other": &stubProvider{}}
+	ctx := context.Background()
+
+	start, err := s.OAuthStart(ctx, "fake")
+	if err != nil {
+		t.Fatalf("OAuthStart: %v", err)
+	}
+
+	if _, err := s.OAuthCallback(ctx, "fake", "code", "made-up-state", ClientMeta{}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("unknown state: expected ErrInvalidToken, got %v", err)
+	}
+	if _, err := s.OAuthCallback(ctx, "other", "code", start.State, ClientMeta{}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("state of another provider: expected ErrInvalidToken, got %v", err)
+	}
+
+	// The refused attempt above must not have consumed the state.
+	if _, err := s.OAuthCallback(ctx, "fake", "code", start.State, ClientMeta{}); !errors.Is(err, ErrOAuthEmailUnverified) {
+		t.Fatalf("expected the exchange to get the stored nonce and verifier, got %v", err)
+	}
+	if _, err := s.OAuthCallback(ctx, "fake", "code", start.State, ClientMeta{}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("reused state: expected ErrInvalidToken, got %v", err)
+	}
+
+	if _, err := s.OAuthCallback(ctx, "unknown", "code", start.State, ClientMeta{}); !errors.Is(err, ErrUnknownProvider) {
+		t.Fatalf("expected ErrUnknownProvider, got %v", err)
+	}
+}
+
+func TestOAuthCallbackExchangeFailure(t *testing.T) {
+	repo := newOAuthRepo()
+	provider := &stubProvider{}
+
+	s, _ := newTestService(repo, lockoutDisabled)
+	s.cfg.OAuthProviders = map[string]OAuthProvider{"fake": provider}
+	ctx := context.Background()
+
+	start, err := s.OAuthStart(ctx, "fake")
+	if err != nil {
+		t.Fatalf("OAuthStart: %v", err)
+	}
+
+	// Another sign-in started in between, its PKCE challenge and nonce do
+	// not belong to the stored state.
+	provider.challenge = "another-challenge"
+
+	if _, err := s.OAuthCallback(ctx, "fake", "code", start.State, ClientMeta{}); !errors.Is(err, ErrOAuthFailed) {
+		t.Fatalf("expected ErrOAuthFailed, got %v", err)
+	}
+}
+
+func TestUserForIdentity(t *testing.T) {
+	const provider = "fake"
+
+	verified := sqlc.User{ID: 1, Email: "jane@example.com", Verified: true, Password: "jane-hash", TokenVersion: 1, RoleID: pgtype.Int4{Int32: 1, Valid: true}}
+	unverified := sqlc.User{ID: 2, Email: "squatter@example.com", Password: "squatter-hash", TokenVersion: 1}
+	linked := sqlc.User{ID: 3, Email: "linked@example.com", Verified: true, Password: "linked-hash"}
+
+	claims := func(subject, email string, emailVerified bool) *oidc.Claims {
+		c := &oidc.Claims{Email: email, EmailVerified: emailVerified, Name: "Jane"}
+		c.Subject = subject
+		return c
+	}
+	multiTenant := func(c *oidc.Claims) *oidc.Claims {
+		c.MultiTenant = true
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		claims  *oidc.Claims
+		wantErr error
+		check   func(t *testing.T, repo *oauthRepo, user sqlc.User)
+	}{
+		{
+			name:   "linked subject signs in without a verified email",
+			claims: claims("linked-subject", "", false),
+			check: func(t *testing.T, repo *oauthRepo, user sqlc.User) {
+				if user.ID != linked.ID {
+					t.Fatalf("signed in as %d, want %d", user.ID, linked.ID)
+				}
+			},
+		},
+		{
+			name:    "unverified email is not linked",
+			claims:  claims("new-subject", verified.Email, false),
+			wantErr: ErrOAuthEmailUnverified,
+		},
+		{
+			name:    "verified claim without an email",
+			claims:  claims("new-subject", "", true),
+			wantErr: ErrOAuthEmailUnverified,
+		},
+		{
+			name:   "verified email links the existing account",
+			claims: claims("new-subject", " Jane@Example.com ", true),
+			check: func(t *testing.T, repo *oauthRepo, user sqlc.User) {
+				if user.ID != verified.ID {
+					t.Fatalf("signed in as %d, want %d", user.ID, verified.ID)
+				}
+				if user.Password != verified.Password || user.TokenVersion != verified.TokenVersion {
+					t.Fatal("a verified account must be left as it is")
+				}
+				if repo.identities[provider+"|new-subject"] != verified.ID {
+					t.Fatal("identity was not linked")
+				}
+			},
+		},
+		{
+			name:    "multi-tenant email is not linked",
+			claims:  multiTenant(claims("new-subject", verified.Email, true)),
+			wantErr: ErrOAuthLinkRequired,
+		},
+		{
+			name:    "multi-tenant email does not take over an unconfirmed registration",
+			claims:  multiTenant(claims("new-subject", unverified.Email, true)),
+			wantErr: ErrOAuthLinkRequired,
+		},
+		{
+			name:   "multi-tenant linked subject signs in",
+			claims: multiTenant(claims("linked-subject", verified.Email, true)),
+			check: func(t *testing.T, repo *oauthRepo, user sqlc.User) {
+				if user.ID != linked.ID {
+					t.Fatalf("signed in as %d, want %d", user.ID, linked.ID)
+				}
+			},
+		},
+		{
+			name:   "multi-tenant unknown email creates an account",
+			claims: multiTenant(claims("new-subject", "new@example.com", true)),
+			check: func(t *testing.T, repo *oauthRepo, user sqlc.User) {
+				if user.Email != "new@example.com" || user.ID == verified.ID {
+					t.Fatalf("unexpected user %+v", user)
+				}
+			},
+		},
+		{
+			name:   "verified email takes over an unconfirmed registration",
+			claims: claims("new-subject", unverified.Email, true),
+			check: func(t *testing.T, repo *oauthRepo, user sqlc.User) {
+				if user.ID != unverified.ID || !user.Verified {
+					t.Fatalf("expected user %d to be verified, got %+v", unverified.ID, user)
+				}
+				if user.Password == unverified.Password {
+					t.Fatal("the unconfirmed password was kept")
+				}
+				if user.TokenVersion == unverified.TokenVersion {
+					t.Fatal("tokens of the unconfirmed account were not revoked")
+				}
+				if len(repo.invitationsDeleted) != 1 || repo.invitationsDeleted[0] != unverified.ID {
+					t.Fatal("invitations were not deleted")
+				}
+			},
+		},
+		{
+			name:   "verified unknown email creates an account",
+			claims: claims("new-subject", "new@example.com", true),
+			check: func(t *testing.T, repo *oauthRepo, user sqlc.User) {
+				if user.Email != "new@example.com" || !user.Verified || !user.RoleID.Valid {
+					t.Fatalf("unexpected new user %+v", user)
+				}
+				if repo.identities[provider+"|new-subject"] != user.ID {
+					t.Fatal("identity was not linked")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newOAuthRepo(verified, unverified, linked)
+			repo.identities[provider+"|linked-subject"] = linked.ID
+			s, _ := newTestService(repo, lockoutDisabled)
+
+			user, err := s.userForIdentity(context.Background(), provider, tt.claims)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				if len(repo.identities) != 1 {
+					t.Fatal("an identity was linked")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("userForIdentity: %v", err)
+			}
+			tt.check(t, repo, user)
+		})
+	}
+}
+
+func TestOAuthLinkStart(t *testing.T) {
+	const userID = 1
+	repo := newOAuthRepo(sqlc.User{ID: userID, Email: "jane@example.com", Verified: true})
+	repo.users[2] = &sqlc.User{ID: 2, Email: "john@example.com", Verified: true}
+	repo.identities["fake|taken-subject"] = 2
+
+	// Whatever email a multi-tenant provider returns, the identity goes
+	// to the user who started the link.
+	provider := &stubProvider{claims: oidc.Claims{Email: "john@example.com", EmailVerified: true, MultiTenant: true}}
+	provider.claims.Subject = "taken-subject"
+
+	s, _ := newTestService(repo, lockoutDisabled)
+	s.cfg.OAuthProviders = map[string]OAuthProvider{"fake": provider}
+	ctx := context.Background()
+
+	start, err := s.OAuthLinkStart(ctx, userID, "fake")
+	if err != nil {
+		t.Fatalf("OAuthLinkStart: %v", err)
+	}
+	for _, st := range repo.states {
+		if !st.UserID.Valid || st.UserID.Int64 != userID {
+			t.Fatalf("state is not bound to the user: %+v", st.UserID)
+		}
+	}
+
+	if _, err := s.OAuthCallback(ctx, "fake", "code", start.State, ClientMeta{}); !errors.Is(err, ErrIdentityLinked) {
+		t.Fatalf("expected ErrIdentityLinked, got %v", err)
+	}
+	if repo.identities["fake|taken-subject"] != 2 {
+		t.Fatal("the identity of another user was moved")
+	}
+
+	tests := []struct {
+		name    string
+		subject string
+		userID  int64
+		wantErr error
+	}{
+		{"new subject", "new-subject", userID, nil},
+		{"already linked to the user", "new-subject", userID, nil},
+		{"linked to another user", "taken-subject", userID, ErrIdentityLinked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := provider.claims
+			claims.Subject = tt.subject
+
+			user, err := s.linkIdentity(ctx, tt.userID, "fake", &claims)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("linkIdentity: %v", err)
+			}
+			if user.ID != tt.userID || repo.identities["fake|"+tt.subject] != tt.userID {
+				t.Fatalf("identity linked to %d, want %d", repo.identities["fake|"+tt.subject], tt.userID)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/mailer"
+	"github.com/mifaabiyyu/backend-go/internal/password"
+)
+
+const emailChangeExp = 24 * time.Hour
+
+var ErrSameEmail = errors.New("this is already your email")
+
+// UpdateProfile changes the fields set in req. The email is not one of
+// them, it only changes through RequestEmailChange.
+func (s *authService) UpdateProfile(ctx context.Context, userID int64, req UpdateProfileRequest) (*ProfileResponse, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := sqlc.UpdateUserParams{
+		ID:       user.ID,
+		Email:    user.Email,
+		Username: user.Username,
+		FullName: user.FullName,
+	}
+	if req.Username != nil {
+		params.Username = strings.TrimSpace(*req.Username)
+	}
+	if req.FullName != nil {
+		params.FullName = strings.TrimSpace(*req.FullName)
+	}
+
+	if err := s.repo.UpdateUser(ctx, params); err != nil {
+		return nil, err
+	}
+
+	s.invalidateUserCache(ctx, userID)
+
+	user, err = s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := profileResponse(user)
+	return &res, nil
+}
+
+// ChangePassword replaces the password after checking the current one.
+// Like ResetPassword every session is signed out, the caller gets a fresh
+// one in return so changing the password does not log them out.
+func (s *authService) ChangePassword(ctx context.Context, userID int64, req ChangePasswordRequest, client ClientMeta) (*LoginResponse, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.verifyCurrentPassword(user, req.CurrentPassword); err != nil {
+		return nil, err
+	}
+
+	err = s.checkPassword(ctx, req.NewPassword, password.UserInfo{Email: user.Email, Username: user.Username})
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := password.Hash(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.WithTx(ctx, func(repo Repository) error {
+		if err := repo.UpdateUserPassword(ctx, userID, hashedPassword); err != nil {
+			return err
+		}
+
+		if err := repo.InvalidatePasswordResets(ctx, userID); err != nil {
+			return err
+		}
+
+		if err := repo.RevokeUserRefreshTokens(ctx, userID); err != nil {
+			return err
+		}
+
+		if err := repo.RevokeUserSessions(ctx, userID); err != nil {
+			return err
+		}
+
+		if err := repo.RevokeUserPersonalAccessTokens(ctx, userID); err != nil {
+			return err
+		}
+
+		return repo.IncrementTokenVersion(ctx, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateUserCache(ctx, userID)
+
+	// Reload for the new token version.
+	user, err = s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.startSession(ctx, user, client)
+}
+
+// RequestEmailChange mails a confirmation link to the new address. The
+// account keeps its email until the link is used.
+func (s *authService) RequestEmailChange(ctx context.Context, userID int64, req ChangeEmailRequest) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.verifyCurrentPassword(user, req.Password); err != nil {
+		return err
+	}
+
+	if req.NewEmail == user.Email {
+		return ErrSameEmail
+	}
+
+	exists, err := s.repo.IsEmailExists(ctx, req.NewEmail)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrEmailExists
+	}
+
+	plainToken, hashToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	// Only the latest request can be confirmed.
+	err = s.repo.WithTx(ctx, func(repo Repository) error {
+		if err := repo.InvalidateEmailChanges(ctx, userID); err != nil {
+			return err
+		}
+
+		return repo.CreateEmailChange(ctx, sqlc.CreateEmailChangeParams{
+			UserID:    userID,
+			NewEmail:  req.NewEmail,
+			TokenHash: hashToken,
+			ExpiresAt: pgtype.Timestamptz{Time: s.now().Add(emailChangeExp), Valid: true},
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	vars := struct {
+		Username   string
+		ConfirmURL string
+		ExpiresIn  string
+	}{
+		Username:   user.Username,
+		ConfirmURL: fmt.Sprintf("%s/confirm-email/%s", s.cfg.FrontendURL, plainToken),
+		ExpiresIn:  emailChangeExp.String(),
+	}
+
+	if _, err := s.mailer.Send(mailer.EmailChangeTemplate, user.Username, req.NewEmail, vars, !s.cfg.IsProdEnv); err != nil {
+		return fmt.Errorf("failed to send email change confirmation: %w", err)
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange moves the account to the confirmed address. Password
+// reset links mailed to the old address stop working.
+func (s *authService) ConfirmEmailChange(ctx context.Context, token string) error {
+	var userID int64
+	err := s.repo.WithTx(ctx, func(repo Repository) error {
+		change, err := repo.ConsumeEmailChange(ctx, auth.HashOpaqueToken(token))
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrInvalidToken
+			}
+			return err
+		}
+		userID = change.UserID
+
+		user, err := repo.GetUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		err = repo.UpdateUser(ctx, sqlc.UpdateUserParams{
+			ID:       user.ID,
+			Email:    change.NewEmail,
+			Username: user.Username,
+			FullName: user.FullName,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := repo.InvalidateEmailChanges(ctx, userID); err != nil {
+			return err
+		}
+
+		return repo.InvalidatePasswordResets(ctx, userID)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.invalidateUserCache(ctx, userID)
+
+	return nil
+}
+
+func (s *authService) verifyCurrentPassword(user sqlc.User, plain string) error {
+	ok, _, err := password.Verify(user.Password, plain)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+func profileResponse(user sqlc.User) ProfileResponse {
+	return ProfileResponse{
+		ID:        user.ID,
+		Email:     user.Email,
+		Username:  user.Username,
+		FullName:  user.FullName,
+		Verified:  user.Verified,
+		RoleID:    user.RoleID.Int32,
+		CreatedAt: user.CreatedAt.Time,
+		UpdatedAt: user.UpdatedAt.Time,
+	}
+}
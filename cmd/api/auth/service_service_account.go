@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+)
+
+const serviceAccountClientIDPrefix = "sa_"
+
+var (
+	ErrServiceAccountExists = errors.New("a service account with this name already exists")
+	ErrUnknownRole          = errors.New("role does not exist")
+	ErrRoleTooHigh          = errors.New("you cannot assign a role with a higher level than your own")
+)
+
+// CreateServiceAccount creates a machine account authenticating with HTTP
+// Basic auth. It gets the permissions of req.Role, which may not rank above
+// the creator's own role. The client secret is only part of this response.
+func (s *authService) CreateServiceAccount(ctx context.Context, creatorID int64, req CreateServiceAccountRequest) (*ServiceAccountResponse, error) {
+	role, err := s.repo.GetRoleByName(ctx, req.Role)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrUnknownRole
+		}
+		return nil, err
+	}
+
+	creator, err := s.repo.GetUserByID(ctx, creatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	creatorRole, err := s.repo.GetRoleByID(ctx, creator.RoleID.Int32)
+	if err != nil {
+		return nil, err
+	}
+
+	if role.Level > creatorRole.Level {
+		return nil, ErrRoleTooHigh
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+
+	plainSecret, hashSecret, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := s.repo.CreateServiceAccount(ctx, sqlc.CreateServiceAccountParams{
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		ClientID:    serviceAccountClientIDPrefix + id,
+		SecretHash:  hashSecret,
+		RoleID:      int32(role.ID),
+		CreatedBy:   pgtype.Int8{Int64: creatorID, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := serviceAccountResponse(account)
+	res.ClientSecret = plainSecret
+
+	return &res, nil
+}
+
+func (s *authService) ListServiceAccounts(ctx context.Context) ([]ServiceAccountResponse, error) {
+	accounts, err := s.repo.ListServiceAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]ServiceAccountResponse, 0, len(accounts))
+	for _, a := range accounts {
+		res = append(res, serviceAccountResponse(a))
+	}
+
+	return res, nil
+}
+
+// RotateServiceAccountSecret replaces the secret, the old one stops working
+// immediately.
+func (s *authService) RotateServiceAccountSecret(ctx context.Context, id int64) (*ServiceAccountResponse, error) {
+	plainSecret, hashSecret, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.UpdateServiceAccountSecret(ctx, id, hashSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrNotFound
+	}
+
+	account, err := s.repo.GetServiceAccountByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := serviceAccountResponse(account)
+	res.ClientSecret = plainSecret
+
+	return &res, nil
+}
+
+func (s *authService) DeleteServiceAccount(ctx context.Context, id int64) error {
+	deleted, err := s.repo.DeleteServiceAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func serviceAccountResponse(a sqlc.ServiceAccount) ServiceAccountResponse {
+	res := ServiceAccountResponse{
+		ID:          a.ID,
+		Name:        a.Name,
+		Description: a.Description,
+		ClientID:    a.ClientID,
+		RoleID:      a.RoleID,
+		CreatedAt:   a.CreatedAt.Time,
+	}
+	if a.LastUsedAt.Valid {
+		res.LastUsedAt = &a.LastUsedAt.Time
+	}
+
+	return res
+}
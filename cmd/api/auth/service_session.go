@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"context"
+)
+
+// ListSessions returns the user's active sessions, most recently used
+// first. currentSessionID is flagged as the caller's own session.
+func (s *authService) ListSessions(ctx context.Context, userID int64, currentSessionID string) ([]SessionResponse, error) {
+	sessions, err := s.repo.ListUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		res = append(res, SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IP:         session.Ip,
+			CreatedAt:  session.CreatedAt.Time,
+			LastSeenAt: session.LastSeenAt.Time,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+
+	return res, nil
+}
+
+// RevokeSession signs a session out. Its access tokens are rejected from
+// the next request on and its refresh token family can no longer be used.
+func (s *authService) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	return s.repo.WithTx(ctx, func(repo Repository) error {
+		revoked, err := repo.RevokeSession(ctx, userID, sessionID)
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return ErrNotFound
+		}
+
+		return repo.RevokeRefreshTokenFamily(ctx, sessionID)
+	})
+}
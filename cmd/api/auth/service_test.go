@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/lockout"
+	"github.com/mifaabiyyu/backend-go/internal/password"
+	"github.com/mifaabiyyu/backend-go/internal/store/cache"
+	"github.com/mifaabiyyu/backend-go/internal/totp"
+)
+
+var lockoutDisabled = lockout.Config{}
+
+type memoryRevocations struct {
+	mu  sync.Mutex
+	ids map[string]bool
+}
+
+func (m *memoryRevocations) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ids[jti] = true
+	return nil
+}
+
+func (m *memoryRevocations) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.ids[jti], nil
+}
+
+// newTestService returns a service whose clock is stopped ten seconds into
+// the current TOTP step. Tokens are still checked against the real time,
+// so the clock stays close to it.
+func newTestService(repo Repository, cfg lockout.Config) (*authService, *time.Time) {
+	now := time.Now().Truncate(totp.Period).Add(10 * time.Second)
+
+	s := NewAuthService(
+		repo,
+		auth.NewJWTAuthenticator("0123456789abcdef0123456789abcdef", "auth-test", "auth-test", 30*time.Second),
+		&memoryRevocations{ids: make(map[string]bool)},
+		nil,
+		cache.Storage{},
+		lockout.New(cache.NewMemoryLoginAttemptStore(), cfg),
+		Config{TokenAud: "auth-test", TokenIss: "auth-test"},
+	).(*authService)
+	s.now = func() time.Time { return now }
+
+	return s, &now
+}
+
+// revocationRepo records what a password reset, a password change or a
+// logout of every device revokes.
+type revocationRepo struct {
+	Repository
+
+	user    sqlc.User
+	revoked []string
+}
+
+func (r *revocationRepo) WithTx(ctx context.Context, fn func(Repository) error) error {
+	return fn(r)
+}
+
+func (r *revocationRepo) ConsumePasswordReset(ctx context.Context, tokenHash string) (int64, error) {
+	if tokenHash != auth.HashOpaqueToken("reset-token") {
+		return 0, ErrNotFound
+	}
+	return r.user.ID, nil
+}
+
+func (r *revocationRepo) GetUserByID(ctx context.Context, userID int64) (sqlc.User, error) {
+	return r.user, nil
+}
+
+func (r *revocationRepo) UpdateUserPassword(ctx context.Context, userID int64, hashedPassword string) error {
+	r.revoked = append(r.revoked, "password")
+	return nil
+}
+
+func (r *revocationRepo) InvalidatePasswordResets(ctx context.Context, userID int64) error {
+	r.revoked = append(r.revoked, "password resets")
+	return nil
+}
+
+func (r *revocationRepo) RevokeUserRefreshTokens(ctx context.Context, userID int64) error {
+	r.revoked = append(r.revoked, "refresh tokens")
+	return nil
+}
+
+func (r *revocationRepo) RevokeUserSessions(ctx context.Context, userID int64) error {
+	r.revoked = append(r.revoked, "sessions")
+	return nil
+}
+
+func (r *revocationRepo) RevokeUserPersonalAccessTokens(ctx context.Context, userID int64) error {
+	r.revoked = append(r.revoked, "personal access tokens")
+	return nil
+}
+
+func (r *revocationRepo) IncrementTokenVersion(ctx context.Context, userID int64) error {
+	r.revoked = append(r.revoked, "access tokens")
+	return nil
+}
+
+// The methods below let a password change start its new session.
+
+func (r *revocationRepo) GetUserMFA(ctx context.Context, userID int64) (sqlc.UserMfa, error) {
+	return sqlc.UserMfa{}, ErrNotFound
+}
+
+func (r *revocationRepo) UpsertSession(ctx context.Context, arg sqlc.UpsertSessionParams) (sqlc.Session, error) {
+	return sqlc.Session{ID: arg.ID, UserID: arg.UserID}, nil
+}
+
+func (r *revocationRepo) CreateRefreshToken(ctx context.Context, arg sqlc.CreateRefreshTokenParams) (sqlc.RefreshToken, error) {
+	return sqlc.RefreshToken{UserID: arg.UserID, FamilyID: arg.FamilyID}, nil
+}
+
+func TestCredentialResetRevokesEveryToken(t *testing.T) {
+	want := []string{"sessions", "refresh tokens", "personal access tokens", "access tokens"}
+
+	oldHash, err := password.Hash("the old password")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		run  func(s *authService) error
+	}{
+		{
+			name: "reset password",
+			run: func(s *authService) error {
+				return s.ResetPassword(context.Background(), "reset-token", "a new password")
+			},
+		},
+		{
+			name: "change password",
+			run: func(s *authService) error {
+				_, err := s.ChangePassword(context.Background(), 7, ChangePasswordRequest{
+					CurrentPassword: "the old password",
+					NewPassword:     "a new password",
+				}, ClientMeta{})
+				return err
+			},
+		},
+		{
+			name: "logout all",
+			run: func(s *authService) error {
+				return s.LogoutAll(context.Background(), 7)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &revocationRepo{user: sqlc.User{ID: 7, Email: "jane@example.com", Username: "jane", Password: oldHash}}
+			s, _ := newTestService(repo, lockoutDisabled)
+
+			if err := tt.run(s); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, name := range want {
+				if !slices.Contains(repo.revoked, name) {
+					t.Errorf("%s were not revoked, got %v", name, repo.revoked)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/permission"
+)
+
+const defaultTokenExpDays = 30
+
+var ErrScopeNotAllowed = errors.New("scopes exceed your permissions")
+
+// CreateToken issues a personal access token limited to req.Scopes, which
+// must all be permissions the user's role currently has. The plain token
+// is only part of this response.
+func (s *authService) CreateToken(ctx context.Context, userID int64, req CreateTokenRequest) (*TokenResponse, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.repo.GetEffectivePermissionsByRoleID(ctx, user.RoleID.Int32)
+	if err != nil {
+		return nil, err
+	}
+
+	grants := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		grants = append(grants, p.Name)
+	}
+	granted := permission.Compile(grants)
+
+	// A wildcard scope is checked like a name, so "user:*" needs a grant
+	// at least as broad, e.g. "user:*" or "*". Deny scopes only narrow
+	// the token and are always accepted.
+	var scopes, denied []string
+	for _, scope := range req.Scopes {
+		scope = strings.TrimSpace(scope)
+		if slices.Contains(scopes, scope) {
+			continue
+		}
+		if !permission.Valid(scope) || (!strings.HasPrefix(scope, "!") && !granted.Allows(scope)) {
+			denied = append(denied, scope)
+			continue
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(denied) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrScopeNotAllowed, strings.Join(denied, ", "))
+	}
+
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultTokenExpDays
+	}
+
+	plainToken, lookup, hashToken, err := auth.GeneratePersonalAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.repo.CreatePersonalAccessToken(ctx, sqlc.CreatePersonalAccessTokenParams{
+		UserID:    userID,
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    lookup,
+		TokenHash: hashToken,
+		Scopes:    scopes,
+		ExpiresAt: pgtype.Timestamptz{Time: s.now().Add(time.Duration(days) * 24 * time.Hour), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := tokenResponse(token)
+	res.Token = plainToken
+
+	return &res, nil
+}
+
+// ListTokens returns the user's tokens that have not been revoked, expired
+// ones included so they can be told apart from missing ones.
+func (s *authService) ListTokens(ctx context.Context, userID int64) ([]TokenResponse, error) {
+	tokens, err := s.repo.ListPersonalAccessTokens(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]TokenResponse, 0, len(tokens))
+	for _, t := range tokens {
+		res = append(res, tokenResponse(t))
+	}
+
+	return res, nil
+}
+
+func (s *authService) RevokeToken(ctx context.Context, userID, tokenID int64) error {
+	revoked, err := s.repo.RevokePersonalAccessToken(ctx, userID, tokenID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func tokenResponse(t sqlc.PersonalAccessToken) TokenResponse {
+	res := TokenResponse{
+		ID:        t.ID,
+		Name:      t.Name,
+		Prefix:    t.Prefix,
+		Scopes:    t.Scopes,
+		ExpiresAt: t.ExpiresAt.Time,
+		CreatedAt: t.CreatedAt.Time,
+	}
+	if res.Scopes == nil {
+		res.Scopes = []string{}
+	}
+	if t.LastUsedAt.Valid {
+		res.LastUsedAt = &t.LastUsedAt.Time
+	}
+
+	return res
+}
@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/webauthn"
+)
+
+const (
+	webAuthnRegisterCeremony = "register"
+	webAuthnLoginCeremony    = "login"
+)
+
+var (
+	ErrPasskeysDisabled = errors.New("passkeys are not enabled")
+	ErrPasskeyInvalid   = errors.New("passkey verification failed")
+	ErrPasskeyExists    = errors.New("this passkey is already registered")
+)
+
+// BeginWebAuthnRegistration starts adding a passkey to the user's account.
+// The returned options are passed to navigator.credentials.create.
+func (s *authService) BeginWebAuthnRegistration(ctx context.Context, userID int64) (*webauthn.CreationOptions, error) {
+	if s.cfg.WebAuthn == nil {
+		return nil, ErrPasskeysDisabled
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := s.repo.ListWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude := make([]webauthn.CredentialDescriptor, 0, len(credentials))
+	for _, c := range credentials {
+		exclude = append(exclude, webauthn.CredentialDescriptor{
+			Type:       "public-key",
+			ID:         webauthn.EncodeID(c.CredentialID),
+			Transports: c.Transports,
+		})
+	}
+
+	challenge, err := s.newWebAuthnChallenge(ctx, webAuthnRegisterCeremony, pgtype.Int8{Int64: userID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+
+	displayName := user.FullName
+	if displayName == "" {
+		displayName = user.Username
+	}
+
+	return s.cfg.WebAuthn.CreationOptions(challenge, webauthn.User{
+		ID:          webAuthnUserHandle(user.ID),
+		Name:        user.Email,
+		DisplayName: displayName,
+	}, exclude), nil
+}
+
+// FinishWebAuthnRegistration verifies the authenticator's response and
+// stores the new passkey.
+func (s *authService) FinishWebAuthnRegistration(ctx context.Context, userID int64, req WebAuthnRegisterRequest) (*WebAuthnCredentialResponse, error) {
+	if s.cfg.WebAuthn == nil {
+		return nil, ErrPasskeysDisabled
+	}
+
+	challenge, err := s.consumeWebAuthnChallenge(ctx, webAuthnRegisterCeremony, req.Credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	// A challenge is bound to the user who asked for it.
+	if !challenge.UserID.Valid || challenge.UserID.Int64 != userID {
+		return nil, ErrInvalidToken
+	}
+
+	credential, err := s.cfg.WebAuthn.VerifyRegistration(&req.Credential, challenge.raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyInvalid, err)
+	}
+
+	transports := credential.Transports
+	if transports == nil {
+		transports = []string{}
+	}
+
+	created, err := s.repo.CreateWebAuthnCredential(ctx, sqlc.CreateWebAuthnCredentialParams{
+		UserID:       userID,
+		CredentialID: credential.ID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    int64(credential.SignCount),
+		Aaguid:       credential.AAGUID,
+		Transports:   transports,
+		Name:         strings.TrimSpace(req.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := webAuthnCredentialResponse(created)
+	return &res, nil
+}
+
+// BeginWebAuthnLogin starts a passkey sign-in. No user is named up front,
+// the authenticator picks one of its passkeys for this site.
+func (s *authService) BeginWebAuthnLogin(ctx context.Context) (*webauthn.RequestOptions, error) {
+	if s.cfg.WebAuthn == nil {
+		return nil, ErrPasskeysDisabled
+	}
+
+	challenge, err := s.newWebAuthnChallenge(ctx, webAuthnLoginCeremony, pgtype.Int8{})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.cfg.WebAuthn.RequestOptions(challenge), nil
+}
+
+// FinishWebAuthnLogin verifies the assertion and signs the passkey's owner
+// in. A passkey that verified the user, with a PIN or biometric, already
+// is a second factor so TOTP is not asked for on top.
+func (s *authService) FinishWebAuthnLogin(ctx context.Context, req WebAuthnLoginRequest, client ClientMeta) (*LoginResponse, error) {
+	if s.cfg.WebAuthn == nil {
+		return nil, ErrPasskeysDisabled
+	}
+
+	challenge, err := s.consumeWebAuthnChallenge(ctx, webAuthnLoginCeremony, req.Credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialID, err := webauthn.DecodeID(req.Credential.RawID)
+	if err != nil {
+		return nil, ErrPasskeyInvalid
+	}
+
+	credential, err := s.repo.GetWebAuthnCredentialByCredentialID(ctx, credentialID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrPasskeyInvalid
+		}
+		return nil, err
+	}
+
+	assertion, err := s.cfg.WebAuthn.VerifyAssertion(&req.Credential, challenge.raw, credential.PublicKey, uint32(credential.SignCount))
+	if err != nil {
+		if errors.Is(err, webauthn.ErrSignCount) {
+			log.Printf("Passkey %d of user %d reported a stale sign counter", credential.ID, credential.UserID)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyInvalid, err)
+	}
+
+	if assertion.UserHandle != nil && string(assertion.UserHandle) != string(webAuthnUserHandle(credential.UserID)) {
+		return nil, ErrPasskeyInvalid
+	}
+
+	// The conditional update loses against a concurrent assertion carrying
+	// the same counter, only one of them signs in.
+	updated, err := s.repo.UpdateWebAuthnSignCount(ctx, credential.ID, int64(assertion.SignCount))
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrPasskeyInvalid
+	}
+
+	user, err := s.repo.GetUserByID(ctx, credential.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.Verified {
+		return nil, ErrNotVerified
+	}
+
+	if assertion.UserVerified {
+		return s.startSession(ctx, user, client)
+	}
+
+	return s.completeLogin(ctx, user, client)
+}
+
+func (s *authService) ListWebAuthnCredentials(ctx context.Context, userID int64) ([]WebAuthnCredentialResponse, error) {
+	credentials, err := s.repo.ListWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]WebAuthnCredentialResponse, 0, len(credentials))
+	for _, c := range credentials {
+		res = append(res, webAuthnCredentialResponse(c))
+	}
+
+	return res, nil
+}
+
+func (s *authService) DeleteWebAuthnCredential(ctx context.Context, userID, id int64) error {
+	deleted, err := s.repo.DeleteWebAuthnCredential(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *authService) newWebAuthnChallenge(ctx context.Context, ceremony string, userID pgtype.Int8) (string, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.repo.CreateWebAuthnChallenge(ctx, sqlc.CreateWebAuthnChallengeParams{
+		ChallengeHash: auth.HashOpaqueToken(challenge),
+		Ceremony:      ceremony,
+		UserID:        userID,
+		ExpiresAt:     pgtype.Timestamptz{Time: s.now().Add(s.cfg.WebAuthn.Timeout()), Valid: true},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+type webAuthnChallenge struct {
+	sqlc.WebauthnChallenge
+	raw string
+}
+
+// consumeWebAuthnChallenge takes the challenge out of the client data and
+// deletes it, every challenge answers one ceremony at most.
+func (s *authService) consumeWebAuthnChallenge(ctx context.Context, ceremony, clientDataJSON string) (*webAuthnChallenge, error) {
+	raw, err := webauthn.Challenge(clientDataJSON)
+	if err != nil {
+		return nil, ErrPasskeyInvalid
+	}
+
+	challenge, err := s.repo.ConsumeWebAuthnChallenge(ctx, auth.HashOpaqueToken(raw), ceremony)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	return &webAuthnChallenge{WebauthnChallenge: challenge, raw: raw}, nil
+}
+
+// webAuthnUserHandle is the user handle stored on the authenticator. It is
+// the user id, which carries no personal information.
+func webAuthnUserHandle(userID int64) []byte {
+	return []byte(strconv.FormatInt(userID, 10))
+}
+
+func webAuthnCredentialResponse(c sqlc.WebauthnCredential) WebAuthnCredentialResponse {
+	res := WebAuthnCredentialResponse{
+		ID:         c.ID,
+		Name:       c.Name,
+		Transports: c.Transports,
+		CreatedAt:  c.CreatedAt.Time,
+	}
+	if c.LastUsedAt.Valid {
+		res.LastUsedAt = &c.LastUsedAt.Time
+	}
+	return res
+}
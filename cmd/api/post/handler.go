@@ -0,0 +1,153 @@
+package post
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	"github.com/mifaabiyyu/backend-go/internal/policy"
+	"github.com/mifaabiyyu/backend-go/utils"
+)
+
+type Handler struct {
+	Service Service
+	*utils.AppWrapper
+}
+
+func (h *Handler) CreatePost(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("user not authenticated"))
+		return
+	}
+
+	var input CreatePostRequest
+	if !h.readInput(w, r, &input) {
+		return
+	}
+
+	res, err := h.Service.Create(r.Context(), user.ID, input)
+	if err != nil {
+		h.InternalServerError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, res)
+}
+
+func (h *Handler) GetPost(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.urlID(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := h.Service.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			h.NotFoundResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+// UpdatePost and DeletePost run behind a policy, the post it loaded is
+// taken from the context.
+func (h *Handler) UpdatePost(w http.ResponseWriter, r *http.Request) {
+	post, ok := policy.ResourceFromContext[*PostResponse](r.Context())
+	if !ok {
+		h.InternalServerError(w, r, errors.New("post policy is missing on the route"))
+		return
+	}
+
+	var input UpdatePostRequest
+	if !h.readInput(w, r, &input) {
+		return
+	}
+
+	res, err := h.Service.Update(r.Context(), post, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			h.NotFoundResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) DeletePost(w http.ResponseWriter, r *http.Request) {
+	post, ok := policy.ResourceFromContext[*PostResponse](r.Context())
+	if !ok {
+		h.InternalServerError(w, r, errors.New("post policy is missing on the route"))
+		return
+	}
+
+	if err := h.Service.Delete(r.Context(), post.ID); err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			h.NotFoundResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LoadPost is the policy.Loader for routes on a single post.
+func (h *Handler) LoadPost(r *http.Request) (policy.Resource, error) {
+	id, err := parseID(r)
+	if err != nil {
+		return nil, policy.ErrNotFound
+	}
+
+	post, err := h.Service.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, policy.ErrNotFound
+		}
+		return nil, err
+	}
+	return post, nil
+}
+
+func (h *Handler) readInput(w http.ResponseWriter, r *http.Request, input any) bool {
+	if err := utils.ReadJSON(w, r, input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return false
+	}
+	if err := utils.Validate.Struct(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return false
+	}
+	return true
+}
+
+func (h *Handler) urlID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := parseID(r)
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return 0, false
+	}
+	return id, true
+}
+
+// parseID reads the post id, posts.id is a SERIAL column.
+func parseID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil || id < 1 {
+		return 0, errors.New("invalid post id")
+	}
+	return id, nil
+}
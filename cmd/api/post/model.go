@@ -0,0 +1,31 @@
+package post
+
+import "time"
+
+type PostResponse struct {
+	ID        int64     `json:"id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	UserID    int64     `json:"user_id"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OwnerID makes a post a policy.Resource, owned by its author.
+func (p *PostResponse) OwnerID() int64 {
+	return p.UserID
+}
+
+type CreatePostRequest struct {
+	Title   string   `json:"title" validate:"required,max=255"`
+	Content string   `json:"content" validate:"required,max=50000"`
+	Tags    []string `json:"tags" validate:"max=20,dive,required,max=50"`
+}
+
+// UpdatePostRequest only changes the fields that are sent.
+type UpdatePostRequest struct {
+	Title   *string   `json:"title" validate:"omitempty,min=1,max=255"`
+	Content *string   `json:"content" validate:"omitempty,min=1,max=50000"`
+	Tags    *[]string `json:"tags" validate:"omitempty,max=20,dive,required,max=50"`
+}
@@ -0,0 +1,17 @@
+package post
+
+import (
+	"github.com/mifaabiyyu/backend-go/internal/store"
+	"github.com/mifaabiyyu/backend-go/utils"
+)
+
+func InitPostModule(store *store.Store, wrapper *utils.AppWrapper) *Handler {
+	repo := NewPostRepository(store)
+
+	service := NewPostService(repo)
+
+	return &Handler{
+		Service:    service,
+		AppWrapper: wrapper,
+	}
+}
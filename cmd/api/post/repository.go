@@ -0,0 +1,61 @@
+package post
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/store"
+)
+
+type Repository interface {
+	CreatePost(ctx context.Context, arg sqlc.CreatePostParams) (sqlc.Post, error)
+	GetPostByID(ctx context.Context, id int64) (sqlc.Post, error)
+	UpdatePost(ctx context.Context, arg sqlc.UpdatePostParams) (sqlc.Post, error)
+	DeletePost(ctx context.Context, id int64) (bool, error)
+}
+
+type postRepo struct {
+	q *sqlc.Queries
+}
+
+func NewPostRepository(store *store.Store) Repository {
+	return &postRepo{q: store.Queries}
+}
+
+func (r *postRepo) CreatePost(ctx context.Context, arg sqlc.CreatePostParams) (sqlc.Post, error) {
+	return r.q.CreatePost(ctx, arg)
+}
+
+// posts.id and posts.user_id are INT columns, the handler only accepts
+// ids that fit.
+func (r *postRepo) GetPostByID(ctx context.Context, id int64) (sqlc.Post, error) {
+	post, err := r.q.GetPostByID(ctx, int32(id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.Post{}, ErrNotFound
+		}
+		return sqlc.Post{}, err
+	}
+	return post, nil
+}
+
+func (r *postRepo) UpdatePost(ctx context.Context, arg sqlc.UpdatePostParams) (sqlc.Post, error) {
+	post, err := r.q.UpdatePost(ctx, arg)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.Post{}, ErrNotFound
+		}
+		return sqlc.Post{}, err
+	}
+	return post, nil
+}
+
+func (r *postRepo) DeletePost(ctx context.Context, id int64) (bool, error) {
+	n, err := r.q.DeletePost(ctx, int32(id))
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
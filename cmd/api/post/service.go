@@ -0,0 +1,110 @@
+package post
+
+import (
+	"context"
+	"errors"
+
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+)
+
+var ErrNotFound = errors.New("post not found")
+
+type Service interface {
+	Create(ctx context.Context, userID int64, req CreatePostRequest) (*PostResponse, error)
+	Get(ctx context.Context, id int64) (*PostResponse, error)
+	Update(ctx context.Context, post *PostResponse, req UpdatePostRequest) (*PostResponse, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+// Who may change which post is decided by the policy on the route, the
+// service trusts its caller.
+type postService struct {
+	repo Repository
+}
+
+func NewPostService(repo Repository) Service {
+	return &postService{repo: repo}
+}
+
+func (s *postService) Create(ctx context.Context, userID int64, req CreatePostRequest) (*PostResponse, error) {
+	post, err := s.repo.CreatePost(ctx, sqlc.CreatePostParams{
+		Title:   req.Title,
+		Content: req.Content,
+		UserID:  int32(userID),
+		Tags:    tags(req.Tags),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := postResponse(post)
+	return &res, nil
+}
+
+func (s *postService) Get(ctx context.Context, id int64) (*PostResponse, error) {
+	post, err := s.repo.GetPostByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := postResponse(post)
+	return &res, nil
+}
+
+// Update applies req on top of post, as loaded by the route policy.
+func (s *postService) Update(ctx context.Context, post *PostResponse, req UpdatePostRequest) (*PostResponse, error) {
+	arg := sqlc.UpdatePostParams{
+		ID:      int32(post.ID),
+		Title:   post.Title,
+		Content: post.Content,
+		Tags:    post.Tags,
+	}
+	if req.Title != nil {
+		arg.Title = *req.Title
+	}
+	if req.Content != nil {
+		arg.Content = *req.Content
+	}
+	if req.Tags != nil {
+		arg.Tags = tags(*req.Tags)
+	}
+
+	updated, err := s.repo.UpdatePost(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	res := postResponse(updated)
+	return &res, nil
+}
+
+func (s *postService) Delete(ctx context.Context, id int64) error {
+	deleted, err := s.repo.DeletePost(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// tags keeps posts.tags an empty array rather than NULL.
+func tags(t []string) []string {
+	if t == nil {
+		return []string{}
+	}
+	return t
+}
+
+func postResponse(post sqlc.Post) PostResponse {
+	return PostResponse{
+		ID:        int64(post.ID),
+		Title:     post.Title,
+		Content:   post.Content,
+		UserID:    int64(post.UserID),
+		Tags:      tags(post.Tags),
+		CreatedAt: post.CreatedAt.Time,
+		UpdatedAt: post.UpdatedAt.Time,
+	}
+}
@@ -0,0 +1,243 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+)
+
+// The archive types below decide what leaves the database. Password
+// hashes, token hashes and key material are deliberately left out.
+
+type exportProfile struct {
+	ID         int64      `json:"id"`
+	Email      string     `json:"email"`
+	Username   string     `json:"username"`
+	FullName   string     `json:"full_name"`
+	Verified   bool       `json:"verified"`
+	VerifiedAt *time.Time `json:"verified_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type exportPost struct {
+	ID        int32     `json:"id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type exportSession struct {
+	ID         string     `json:"id"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+type exportAccessToken struct {
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type exportPasskey struct {
+	Name       string     `json:"name"`
+	Transports []string   `json:"transports"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type exportIdentity struct {
+	Provider    string     `json:"provider"`
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type exportImpersonation struct {
+	ActorID   int64     `json:"actor_id"`
+	Reason    string    `json:"reason"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type exportImpersonationRequest struct {
+	ActorID   int64     `json:"actor_id"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    *int32    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// buildArchive collects everything stored about the user into a ZIP
+// archive with one JSON file per kind of record.
+func buildArchive(ctx context.Context, repo Repository, userID int64) ([]byte, error) {
+	user, err := repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := repo.ListPostsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := repo.ListAllUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := repo.ListPersonalAccessTokens(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	passkeys, err := repo.ListWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := repo.ListUserIdentities(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := repo.GetUserAttributes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	impersonations, err := repo.ListImpersonationsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	requests, err := repo.ListImpersonationRequestsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", exportProfile{
+			ID:         user.ID,
+			Email:      user.Email,
+			Username:   user.Username,
+			FullName:   user.FullName,
+			Verified:   user.Verified,
+			VerifiedAt: optionalTime(user.VerifiedAt),
+			CreatedAt:  user.CreatedAt.Time,
+			UpdatedAt:  user.UpdatedAt.Time,
+		}},
+		{"posts.json", mapSlice(posts, func(p sqlc.Post) exportPost {
+			return exportPost{
+				ID:        p.ID,
+				Title:     p.Title,
+				Content:   p.Content,
+				Tags:      p.Tags,
+				CreatedAt: p.CreatedAt.Time,
+				UpdatedAt: p.UpdatedAt.Time,
+			}
+		})},
+		{"sessions.json", mapSlice(sessions, func(s sqlc.Session) exportSession {
+			return exportSession{
+				ID:         s.ID,
+				UserAgent:  s.UserAgent,
+				IP:         s.Ip,
+				CreatedAt:  s.CreatedAt.Time,
+				LastSeenAt: s.LastSeenAt.Time,
+				RevokedAt:  optionalTime(s.RevokedAt),
+			}
+		})},
+		{"access_tokens.json", mapSlice(tokens, func(t sqlc.PersonalAccessToken) exportAccessToken {
+			return exportAccessToken{
+				Name:       t.Name,
+				Prefix:     t.Prefix,
+				Scopes:     t.Scopes,
+				ExpiresAt:  t.ExpiresAt.Time,
+				LastUsedAt: optionalTime(t.LastUsedAt),
+				CreatedAt:  t.CreatedAt.Time,
+			}
+		})},
+		{"passkeys.json", mapSlice(passkeys, func(c sqlc.WebauthnCredential) exportPasskey {
+			return exportPasskey{
+				Name:       c.Name,
+				Transports: c.Transports,
+				LastUsedAt: optionalTime(c.LastUsedAt),
+				CreatedAt:  c.CreatedAt.Time,
+			}
+		})},
+		{"identities.json", mapSlice(identities, func(i sqlc.UserIdentity) exportIdentity {
+			return exportIdentity{
+				Provider:    i.Provider,
+				Email:       i.Email,
+				LastLoginAt: optionalTime(i.LastLoginAt),
+				CreatedAt:   i.CreatedAt.Time,
+			}
+		})},
+		{"attributes.json", attributes},
+		{"audit/impersonations.json", mapSlice(impersonations, func(i sqlc.Impersonation) exportImpersonation {
+			return exportImpersonation{
+				ActorID:   i.ActorID,
+				Reason:    i.Reason,
+				ExpiresAt: i.ExpiresAt.Time,
+				CreatedAt: i.CreatedAt.Time,
+			}
+		})},
+		{"audit/impersonation_requests.json", mapSlice(requests, func(r sqlc.ImpersonationRequest) exportImpersonationRequest {
+			item := exportImpersonationRequest{
+				ActorID:   r.ActorID,
+				Method:    r.Method,
+				Path:      r.Path,
+				CreatedAt: r.CreatedAt.Time,
+			}
+			if r.Status.Valid {
+				item.Status = &r.Status.Int32
+			}
+			return item
+		})},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// mapSlice converts rows to their archive type. The result is never nil
+// so empty lists are written as [] instead of null.
+func mapSlice[T, U any](rows []T, fn func(T) U) []U {
+	res := make([]U, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, fn(row))
+	}
+	return res
+}
+
+func optionalTime(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
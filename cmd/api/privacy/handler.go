@@ -0,0 +1,171 @@
+package privacy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	"github.com/mifaabiyyu/backend-go/utils"
+)
+
+type Handler struct {
+	Service Service
+	*utils.AppWrapper
+}
+
+func (h *Handler) RequestExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.sessionUserID(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := h.Service.RequestExport(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrExportInProgress):
+			h.ConflictResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, res)
+}
+
+func (h *Handler) ListExports(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.sessionUserID(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := h.Service.ListExports(r.Context(), userID)
+	if err != nil {
+		h.InternalServerError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.sessionUserID(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id < 1 {
+		h.BadRequestResponse(w, r, errors.New("invalid export id"))
+		return
+	}
+
+	archive, err := h.Service.DownloadExport(r.Context(), userID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			h.NotFoundResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%d.zip"`, id))
+	w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
+
+func (h *Handler) RequestErasure(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.sessionClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var input ErasureRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	res, err := h.Service.RequestErasure(r.Context(), claims.UserID, claims.SessionID, input.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidCredentials), errors.Is(err, ErrReauthRequired):
+			h.ForbiddenResponse(w, r, err)
+		case errors.Is(err, ErrErasurePending), errors.Is(err, ErrLastSuperAdmin):
+			h.ConflictResponse(w, r, err)
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, res)
+}
+
+func (h *Handler) GetErasure(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.sessionUserID(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := h.Service.GetErasure(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			h.NotFoundResponse(w, r, errors.New("no account erasure is scheduled"))
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) CancelErasure(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.sessionUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.CancelErasure(r.Context(), userID); err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			h.NotFoundResponse(w, r, errors.New("no account erasure is scheduled"))
+		default:
+			h.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sessionUserID returns the caller's user id for requests made with a
+// login session. Access tokens and service accounts cannot export or
+// erase an account.
+func (h *Handler) sessionUserID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	claims, ok := h.sessionClaims(w, r)
+	if !ok {
+		return 0, false
+	}
+	return claims.UserID, true
+}
+
+func (h *Handler) sessionClaims(w http.ResponseWriter, r *http.Request) (*auth.Claims, bool) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("this action requires a login session"))
+		return nil, false
+	}
+	return claims, true
+}
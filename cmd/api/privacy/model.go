@@ -0,0 +1,24 @@
+package privacy
+
+import "time"
+
+type DataExportResponse struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+	// Error is set when Status is failed.
+	Error       string     `json:"error,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type ErasureResponse struct {
+	ScheduledFor time.Time `json:"scheduled_for"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ErasureRequest confirms the erasure with the password. Accounts without
+// one leave it empty and sign in again right before.
+type ErasureRequest struct {
+	Password string `json:"password"`
+}
@@ -0,0 +1,35 @@
+package privacy
+
+import (
+	"time"
+
+	"github.com/mifaabiyyu/backend-go/internal/store"
+	"github.com/mifaabiyyu/backend-go/internal/store/cache"
+	"github.com/mifaabiyyu/backend-go/utils"
+	"go.uber.org/zap"
+)
+
+func InitPrivacyModule(store *store.Store, wrapper *utils.AppWrapper, cacheStorage cache.Storage, cfg Config) *Handler {
+	repo := NewPrivacyRepository(store)
+
+	service := NewPrivacyService(repo, cacheStorage, cfg)
+
+	return &Handler{
+		Service:    service,
+		AppWrapper: wrapper,
+	}
+}
+
+// NewWorker builds the background job that produces exports and carries
+// out due erasures, see Worker.Run.
+func NewWorker(store *store.Store, cacheStorage cache.Storage, logger *zap.SugaredLogger, cfg Config) *Worker {
+	repo := NewPrivacyRepository(store)
+
+	return &Worker{
+		repo:   repo,
+		cache:  cacheStorage,
+		logger: logger,
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
@@ -0,0 +1,257 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/store"
+)
+
+const uniqueViolation = "23505"
+
+type Repository interface {
+	WithTx(ctx context.Context, fn func(Repository) error) error
+	GetUserByID(ctx context.Context, userID int64) (sqlc.User, error)
+	DeleteUser(ctx context.Context, userID int64) error
+	GetSession(ctx context.Context, id string) (sqlc.Session, error)
+	GetRoleByIDForUpdate(ctx context.Context, id int64) (sqlc.Role, error)
+	CountUsersByRole(ctx context.Context, roleID int64) (int64, error)
+	CreateDataExport(ctx context.Context, userID int64) (sqlc.CreateDataExportRow, error)
+	CountActiveDataExports(ctx context.Context, userID int64) (int64, error)
+	ListDataExports(ctx context.Context, userID int64) ([]sqlc.ListDataExportsRow, error)
+	GetDataExportArchive(ctx context.Context, userID, id int64) ([]byte, error)
+	ClaimDataExport(ctx context.Context) (sqlc.ClaimDataExportRow, error)
+	CompleteDataExport(ctx context.Context, id int64, archive []byte, expiresAt time.Time) error
+	FailDataExport(ctx context.Context, id int64, reason string) error
+	RequeueStaleDataExports(ctx context.Context, startedBefore time.Time) error
+	ExpireDataExports(ctx context.Context) error
+	CreateErasureRequest(ctx context.Context, userID int64, scheduledFor time.Time) (sqlc.ErasureRequest, error)
+	GetPendingErasureRequest(ctx context.Context, userID int64) (sqlc.ErasureRequest, error)
+	CancelErasureRequest(ctx context.Context, userID int64) (bool, error)
+	ClaimDueErasureRequest(ctx context.Context) (sqlc.ErasureRequest, error)
+	ListPostsByUser(ctx context.Context, userID int64) ([]sqlc.Post, error)
+	DeletePostsByUser(ctx context.Context, userID int64) error
+	ListAllUserSessions(ctx context.Context, userID int64) ([]sqlc.Session, error)
+	ListPersonalAccessTokens(ctx context.Context, userID int64) ([]sqlc.PersonalAccessToken, error)
+	ListWebAuthnCredentials(ctx context.Context, userID int64) ([]sqlc.WebauthnCredential, error)
+	ListUserIdentities(ctx context.Context, userID int64) ([]sqlc.UserIdentity, error)
+	GetUserAttributes(ctx context.Context, userID int64) (json.RawMessage, error)
+	ListImpersonationsByUser(ctx context.Context, userID int64) ([]sqlc.Impersonation, error)
+	ListImpersonationRequestsByUser(ctx context.Context, userID int64) ([]sqlc.ImpersonationRequest, error)
+}
+
+type privacyRepo struct {
+	q     *sqlc.Queries
+	store *store.Store
+}
+
+func NewPrivacyRepository(store *store.Store) Repository {
+	return &privacyRepo{q: store.Queries, store: store}
+}
+
+func (r *privacyRepo) WithTx(ctx context.Context, fn func(Repository) error) error {
+	return r.store.WithTx(ctx, func(q *sqlc.Queries) error {
+		return fn(&privacyRepo{q: q, store: r.store})
+	})
+}
+
+func (r *privacyRepo) GetUserByID(ctx context.Context, userID int64) (sqlc.User, error) {
+	user, err := r.q.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.User{}, ErrNotFound
+		}
+		return sqlc.User{}, err
+	}
+	return user, nil
+}
+
+func (r *privacyRepo) DeleteUser(ctx context.Context, userID int64) error {
+	return r.q.DeleteUser(ctx, userID)
+}
+
+func (r *privacyRepo) GetSession(ctx context.Context, id string) (sqlc.Session, error) {
+	session, err := r.q.GetSession(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.Session{}, ErrNotFound
+		}
+		return sqlc.Session{}, err
+	}
+	return session, nil
+}
+
+// GetRoleByIDForUpdate locks the role row until the transaction ends.
+func (r *privacyRepo) GetRoleByIDForUpdate(ctx context.Context, id int64) (sqlc.Role, error) {
+	role, err := r.q.GetRoleByIDForUpdate(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.Role{}, ErrNotFound
+		}
+		return sqlc.Role{}, err
+	}
+	return role, nil
+}
+
+func (r *privacyRepo) CountUsersByRole(ctx context.Context, roleID int64) (int64, error) {
+	return r.q.CountUsersByRole(ctx, pgtype.Int4{Int32: int32(roleID), Valid: true})
+}
+
+func (r *privacyRepo) CreateDataExport(ctx context.Context, userID int64) (sqlc.CreateDataExportRow, error) {
+	return r.q.CreateDataExport(ctx, userID)
+}
+
+func (r *privacyRepo) CountActiveDataExports(ctx context.Context, userID int64) (int64, error) {
+	return r.q.CountActiveDataExports(ctx, userID)
+}
+
+func (r *privacyRepo) ListDataExports(ctx context.Context, userID int64) ([]sqlc.ListDataExportsRow, error) {
+	return r.q.ListDataExports(ctx, userID)
+}
+
+func (r *privacyRepo) GetDataExportArchive(ctx context.Context, userID, id int64) ([]byte, error) {
+	archive, err := r.q.GetDataExportArchive(ctx, sqlc.GetDataExportArchiveParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return archive, nil
+}
+
+func (r *privacyRepo) ClaimDataExport(ctx context.Context) (sqlc.ClaimDataExportRow, error) {
+	export, err := r.q.ClaimDataExport(ctx)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.ClaimDataExportRow{}, ErrNotFound
+		}
+		return sqlc.ClaimDataExportRow{}, err
+	}
+	return export, nil
+}
+
+func (r *privacyRepo) CompleteDataExport(ctx context.Context, id int64, archive []byte, expiresAt time.Time) error {
+	return r.q.CompleteDataExport(ctx, sqlc.CompleteDataExportParams{
+		ID:        id,
+		Archive:   archive,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+}
+
+func (r *privacyRepo) FailDataExport(ctx context.Context, id int64, reason string) error {
+	return r.q.FailDataExport(ctx, sqlc.FailDataExportParams{
+		ID:    id,
+		Error: reason,
+	})
+}
+
+func (r *privacyRepo) RequeueStaleDataExports(ctx context.Context, startedBefore time.Time) error {
+	return r.q.RequeueStaleDataExports(ctx, pgtype.Timestamptz{Time: startedBefore, Valid: true})
+}
+
+func (r *privacyRepo) ExpireDataExports(ctx context.Context) error {
+	return r.q.ExpireDataExports(ctx)
+}
+
+// CreateErasureRequest maps the unique index on pending requests to
+// ErrErasurePending.
+func (r *privacyRepo) CreateErasureRequest(ctx context.Context, userID int64, scheduledFor time.Time) (sqlc.ErasureRequest, error) {
+	request, err := r.q.CreateErasureRequest(ctx, sqlc.CreateErasureRequestParams{
+		UserID:       userID,
+		ScheduledFor: pgtype.Timestamptz{Time: scheduledFor, Valid: true},
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return sqlc.ErasureRequest{}, ErrErasurePending
+		}
+		return sqlc.ErasureRequest{}, err
+	}
+	return request, nil
+}
+
+func (r *privacyRepo) GetPendingErasureRequest(ctx context.Context, userID int64) (sqlc.ErasureRequest, error) {
+	request, err := r.q.GetPendingErasureRequest(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.ErasureRequest{}, ErrNotFound
+		}
+		return sqlc.ErasureRequest{}, err
+	}
+	return request, nil
+}
+
+func (r *privacyRepo) CancelErasureRequest(ctx context.Context, userID int64) (bool, error) {
+	n, err := r.q.CancelErasureRequest(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *privacyRepo) ClaimDueErasureRequest(ctx context.Context) (sqlc.ErasureRequest, error) {
+	request, err := r.q.ClaimDueErasureRequest(ctx)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.ErasureRequest{}, ErrNotFound
+		}
+		return sqlc.ErasureRequest{}, err
+	}
+	return request, nil
+}
+
+// posts.user_id is an INTEGER, unlike the BIGINT user ids elsewhere.
+func (r *privacyRepo) ListPostsByUser(ctx context.Context, userID int64) ([]sqlc.Post, error) {
+	return r.q.ListPostsByUser(ctx, int32(userID))
+}
+
+func (r *privacyRepo) DeletePostsByUser(ctx context.Context, userID int64) error {
+	return r.q.DeletePostsByUser(ctx, int32(userID))
+}
+
+func (r *privacyRepo) ListAllUserSessions(ctx context.Context, userID int64) ([]sqlc.Session, error) {
+	return r.q.ListAllUserSessions(ctx, userID)
+}
+
+func (r *privacyRepo) ListPersonalAccessTokens(ctx context.Context, userID int64) ([]sqlc.PersonalAccessToken, error) {
+	return r.q.ListPersonalAccessTokens(ctx, userID)
+}
+
+func (r *privacyRepo) ListWebAuthnCredentials(ctx context.Context, userID int64) ([]sqlc.WebauthnCredential, error) {
+	return r.q.ListWebAuthnCredentials(ctx, userID)
+}
+
+func (r *privacyRepo) ListUserIdentities(ctx context.Context, userID int64) ([]sqlc.UserIdentity, error) {
+	return r.q.ListUserIdentities(ctx, userID)
+}
+
+// GetUserAttributes returns the ABAC attributes of the user as stored,
+// an empty object when none were set.
+func (r *privacyRepo) GetUserAttributes(ctx context.Context, userID int64) (json.RawMessage, error) {
+	attributes, err := r.q.GetUserAttributes(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return json.RawMessage("{}"), nil
+		}
+		return nil, err
+	}
+	return attributes, nil
+}
+
+func (r *privacyRepo) ListImpersonationsByUser(ctx context.Context, userID int64) ([]sqlc.Impersonation, error) {
+	return r.q.ListImpersonationsByUser(ctx, userID)
+}
+
+func (r *privacyRepo) ListImpersonationRequestsByUser(ctx context.Context, userID int64) ([]sqlc.ImpersonationRequest, error) {
+	return r.q.ListImpersonationRequestsByUser(ctx, userID)
+}
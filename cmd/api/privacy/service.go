@@ -0,0 +1,235 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/password"
+	"github.com/mifaabiyyu/backend-go/internal/store/cache"
+)
+
+var (
+	ErrNotFound           = errors.New("record not found")
+	ErrInvalidCredentials = errors.New("invalid password")
+	ErrExportInProgress   = errors.New("a data export is already being prepared")
+	ErrErasurePending     = errors.New("account erasure is already scheduled")
+	ErrReauthRequired     = errors.New("confirm with your password or sign in again")
+	ErrLastSuperAdmin     = errors.New("the last super admin cannot erase their account")
+)
+
+const (
+	superRoleName = "super"
+	// freshLoginWindow is how recent the login of the session must be to
+	// stand in for the password when requesting an erasure.
+	freshLoginWindow = 10 * time.Minute
+)
+
+type Config struct {
+	CacheEnabled bool
+	// ErasureGracePeriod is how long an erasure request can still be
+	// cancelled before the account is deleted.
+	ErasureGracePeriod time.Duration
+	// ExportExp is how long a finished export can be downloaded.
+	ExportExp time.Duration
+	// PollInterval is how often the worker looks for pending exports and
+	// due erasures.
+	PollInterval time.Duration
+}
+
+type Service interface {
+	RequestExport(ctx context.Context, userID int64) (*DataExportResponse, error)
+	ListExports(ctx context.Context, userID int64) ([]DataExportResponse, error)
+	DownloadExport(ctx context.Context, userID, id int64) ([]byte, error)
+	RequestErasure(ctx context.Context, userID int64, sessionID, password string) (*ErasureResponse, error)
+	GetErasure(ctx context.Context, userID int64) (*ErasureResponse, error)
+	CancelErasure(ctx context.Context, userID int64) error
+}
+
+type privacyService struct {
+	repo  Repository
+	cache cache.Storage
+	cfg   Config
+	now   func() time.Time
+}
+
+func NewPrivacyService(repo Repository, cache cache.Storage, cfg Config) Service {
+	return &privacyService{
+		repo:  repo,
+		cache: cache,
+		cfg:   cfg,
+		now:   time.Now,
+	}
+}
+
+// RequestExport queues an export for the worker. Only one export per user
+// can be waiting or running at a time.
+func (s *privacyService) RequestExport(ctx context.Context, userID int64) (*DataExportResponse, error) {
+	active, err := s.repo.CountActiveDataExports(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if active > 0 {
+		return nil, ErrExportInProgress
+	}
+
+	export, err := s.repo.CreateDataExport(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataExportResponse{
+		ID:        export.ID,
+		Status:    export.Status,
+		CreatedAt: export.CreatedAt.Time,
+	}, nil
+}
+
+func (s *privacyService) ListExports(ctx context.Context, userID int64) ([]DataExportResponse, error) {
+	exports, err := s.repo.ListDataExports(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]DataExportResponse, 0, len(exports))
+	for _, export := range exports {
+		item := DataExportResponse{
+			ID:        export.ID,
+			Status:    export.Status,
+			Error:     export.Error,
+			CreatedAt: export.CreatedAt.Time,
+		}
+		if export.CompletedAt.Valid {
+			item.CompletedAt = &export.CompletedAt.Time
+		}
+		if export.ExpiresAt.Valid {
+			item.ExpiresAt = &export.ExpiresAt.Time
+		}
+		res = append(res, item)
+	}
+
+	return res, nil
+}
+
+// DownloadExport returns the ZIP archive of a ready export. Exports of
+// other users, unfinished and expired ones are all ErrNotFound.
+func (s *privacyService) DownloadExport(ctx context.Context, userID, id int64) ([]byte, error) {
+	return s.repo.GetDataExportArchive(ctx, userID, id)
+}
+
+// RequestErasure schedules the account for deletion once the grace period
+// has passed. The account keeps working until then so the request can be
+// cancelled.
+//
+// The caller confirms with the password or, since social login accounts
+// have none, by having signed in again within freshLoginWindow.
+func (s *privacyService) RequestErasure(ctx context.Context, userID int64, sessionID, plain string) (*ErasureResponse, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.reauthenticate(ctx, user, sessionID, plain); err != nil {
+		return nil, err
+	}
+
+	// The worker checks again before deleting, this only saves the user a
+	// grace period for an erasure that cannot happen.
+	last, err := isLastSuperAdmin(ctx, s.repo, user)
+	if err != nil {
+		return nil, err
+	}
+	if last {
+		return nil, ErrLastSuperAdmin
+	}
+
+	request, err := s.repo.CreateErasureRequest(ctx, userID, s.now().Add(s.cfg.ErasureGracePeriod))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ErasureResponse{
+		ScheduledFor: request.ScheduledFor.Time,
+		CreatedAt:    request.CreatedAt.Time,
+	}, nil
+}
+
+// reauthenticate checks plain against the password, or without one that
+// sessionID belongs to user and was started within freshLoginWindow.
+// Refreshing a session keeps its creation time, only a new login resets it.
+func (s *privacyService) reauthenticate(ctx context.Context, user sqlc.User, sessionID, plain string) error {
+	if plain != "" {
+		ok, _, err := password.Verify(user.Password, plain)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidCredentials
+		}
+		return nil
+	}
+
+	if sessionID == "" {
+		return ErrReauthRequired
+	}
+
+	session, err := s.repo.GetSession(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrReauthRequired
+		}
+		return err
+	}
+	if session.UserID != user.ID || session.RevokedAt.Valid || s.now().Sub(session.CreatedAt.Time) > freshLoginWindow {
+		return ErrReauthRequired
+	}
+
+	return nil
+}
+
+// isLastSuperAdmin reports whether user is the only holder of the super
+// role. The role row is locked until repo's transaction ends, so two super
+// admins erased at the same time cannot leave the system without one.
+func isLastSuperAdmin(ctx context.Context, repo Repository, user sqlc.User) (bool, error) {
+	if !user.RoleID.Valid {
+		return false, nil
+	}
+
+	role, err := repo.GetRoleByIDForUpdate(ctx, int64(user.RoleID.Int32))
+	if err != nil {
+		return false, err
+	}
+	if role.Name != superRoleName {
+		return false, nil
+	}
+
+	supers, err := repo.CountUsersByRole(ctx, role.ID)
+	if err != nil {
+		return false, err
+	}
+	return supers <= 1, nil
+}
+
+func (s *privacyService) GetErasure(ctx context.Context, userID int64) (*ErasureResponse, error) {
+	request, err := s.repo.GetPendingErasureRequest(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ErasureResponse{
+		ScheduledFor: request.ScheduledFor.Time,
+		CreatedAt:    request.CreatedAt.Time,
+	}, nil
+}
+
+func (s *privacyService) CancelErasure(ctx context.Context, userID int64) error {
+	cancelled, err := s.repo.CancelErasureRequest(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/password"
+	"github.com/mifaabiyyu/backend-go/internal/store/cache"
+	"go.uber.org/zap"
+)
+
+const (
+	userRole  int64 = 1
+	superRole int64 = 2
+)
+
+// erasureRepo keeps users, sessions and erasure requests in memory.
+// Calling a method it does not override panics on the nil embedded
+// Repository.
+type erasureRepo struct {
+	Repository
+
+	users    map[int64]sqlc.User
+	sessions map[string]sqlc.Session
+	requests []sqlc.ErasureRequest
+	deleted  []int64
+}
+
+func newErasureRepo() *erasureRepo {
+	return &erasureRepo{
+		users:    make(map[int64]sqlc.User),
+		sessions: make(map[string]sqlc.Session),
+	}
+}
+
+func (r *erasureRepo) addUser(id, roleID int64, hash string) {
+	r.users[id] = sqlc.User{ID: id, Password: hash, RoleID: pgtype.Int4{Int32: int32(roleID), Valid: true}}
+}
+
+// WithTx rolls back the erasure requests, the only rows a failed
+// transaction could have changed.
+func (r *erasureRepo) WithTx(ctx context.Context, fn func(Repository) error) error {
+	requests := slices.Clone(r.requests)
+	if err := fn(r); err != nil {
+		r.requests = requests
+		return err
+	}
+	return nil
+}
+
+func (r *erasureRepo) GetUserByID(ctx context.Context, userID int64) (sqlc.User, error) {
+	user, ok := r.users[userID]
+	if !ok {
+		return sqlc.User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+func (r *erasureRepo) GetSession(ctx context.Context, id string) (sqlc.Session, error) {
+	session, ok := r.sessions[id]
+	if !ok {
+		return sqlc.Session{}, ErrNotFound
+	}
+	return session, nil
+}
+
+func (r *erasureRepo) GetRoleByIDForUpdate(ctx context.Context, id int64) (sqlc.Role, error) {
+	if id == superRole {
+		return sqlc.Role{ID: id, Name: superRoleName}, nil
+	}
+	return sqlc.Role{ID: id, Name: "user"}, nil
+}
+
+func (r *erasureRepo) CountUsersByRole(ctx context.Context, roleID int64) (int64, error) {
+	var n int64
+	for _, user := range r.users {
+		if int64(user.RoleID.Int32) == roleID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *erasureRepo) CreateErasureRequest(ctx context.Context, userID int64, scheduledFor time.Time) (sqlc.ErasureRequest, error) {
+	request := sqlc.ErasureRequest{
+		UserID:       userID,
+		ScheduledFor: pgtype.Timestamptz{Time: scheduledFor, Valid: true},
+	}
+	r.requests = append(r.requests, request)
+	return request, nil
+}
+
+func (r *erasureRepo) ClaimDueErasureRequest(ctx context.Context) (sqlc.ErasureRequest, error) {
+	for i, request := range r.requests {
+		if !request.CancelledAt.Valid && !request.CompletedAt.Valid {
+			r.requests[i].CompletedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			return r.requests[i], nil
+		}
+	}
+	return sqlc.ErasureRequest{}, ErrNotFound
+}
+
+func (r *erasureRepo) CancelErasureRequest(ctx context.Context, userID int64) (bool, error) {
+	for i, request := range r.requests {
+		if request.UserID == userID && !request.CancelledAt.Valid && !request.CompletedAt.Valid {
+			r.requests[i].CancelledAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *erasureRepo) DeletePostsByUser(ctx context.Context, userID int64) error {
+	return nil
+}
+
+func (r *erasureRepo) DeleteUser(ctx context.Context, userID int64) error {
+	delete(r.users, userID)
+	r.deleted = append(r.deleted, userID)
+	return nil
+}
+
+func TestRequestErasureReauthentication(t *testing.T) {
+	now := time.Now()
+
+	hash, err := password.Hash("the password")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		session  string
+		want     error
+	}{
+		{name: "password", password: "the password", session: "old"},
+		{name: "wrong password", password: "not the password", session: "fresh", want: ErrInvalidCredentials},
+		{name: "fresh login without a password", session: "fresh"},
+		{name: "old login without a password", session: "old", want: ErrReauthRequired},
+		{name: "revoked fresh login", session: "revoked", want: ErrReauthRequired},
+		{name: "fresh login of another user", session: "other", want: ErrReauthRequired},
+		{name: "unknown session", session: "missing", want: ErrReauthRequired},
+		{name: "no session", want: ErrReauthRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newErasureRepo()
+			repo.addUser(7, userRole, hash)
+			at := func(d time.Duration) pgtype.Timestamptz {
+				return pgtype.Timestamptz{Time: now.Add(-d), Valid: true}
+			}
+			repo.sessions["fresh"] = sqlc.Session{ID: "fresh", UserID: 7, CreatedAt: at(time.Minute)}
+			repo.sessions["old"] = sqlc.Session{ID: "old", UserID: 7, CreatedAt: at(time.Hour)}
+			repo.sessions["revoked"] = sqlc.Session{ID: "revoked", UserID: 7, CreatedAt: at(time.Minute), RevokedAt: at(0)}
+			repo.sessions["other"] = sqlc.Session{ID: "other", UserID: 8, CreatedAt: at(time.Minute)}
+
+			s := NewPrivacyService(repo, cache.Storage{}, Config{ErasureGracePeriod: time.Hour}).(*privacyService)
+			s.now = func() time.Time { return now }
+
+			_, err := s.RequestErasure(context.Background(), 7, tt.session, tt.password)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+			if scheduled := len(repo.requests) == 1; scheduled != (tt.want == nil) {
+				t.Fatalf("erasure scheduled = %v", scheduled)
+			}
+		})
+	}
+}
+
+func TestRequestErasureLastSuperAdmin(t *testing.T) {
+	hash, err := password.Hash("the password")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	repo := newErasureRepo()
+	repo.addUser(7, superRole, hash)
+	s := NewPrivacyService(repo, cache.Storage{}, Config{})
+
+	if _, err := s.RequestErasure(context.Background(), 7, "", "the password"); !errors.Is(err, ErrLastSuperAdmin) {
+		t.Fatalf("expected ErrLastSuperAdmin, got %v", err)
+	}
+
+	repo.addUser(8, superRole, hash)
+	if _, err := s.RequestErasure(context.Background(), 7, "", "the password"); err != nil {
+		t.Fatalf("RequestErasure: %v", err)
+	}
+}
+
+func TestProcessErasureKeepsLastSuperAdmin(t *testing.T) {
+	repo := newErasureRepo()
+	repo.addUser(7, superRole, "")
+	repo.addUser(8, superRole, "")
+	repo.addUser(9, userRole, "")
+
+	// Both super admins asked while the other one was still around.
+	for _, id := range []int64{7, 8, 9} {
+		if _, err := repo.CreateErasureRequest(context.Background(), id, time.Now()); err != nil {
+			t.Fatalf("CreateErasureRequest: %v", err)
+		}
+	}
+
+	w := &Worker{repo: repo, logger: zap.NewNop().Sugar(), now: time.Now}
+	for {
+		done, err := w.processErasure(context.Background())
+		if err != nil {
+			t.Fatalf("processErasure: %v", err)
+		}
+		if done {
+			break
+		}
+	}
+
+	if _, ok := repo.users[8]; !ok {
+		t.Fatal("the last super admin was erased")
+	}
+	if len(repo.deleted) != 2 {
+		t.Fatalf("expected users 7 and 9 to be erased, got %v", repo.deleted)
+	}
+	if !repo.requests[1].CancelledAt.Valid {
+		t.Fatal("the request of the last super admin was not cancelled")
+	}
+}
@@ -0,0 +1,159 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mifaabiyyu/backend-go/internal/store/cache"
+	"go.uber.org/zap"
+)
+
+// staleExportAge is how long an export may stay running before it is
+// assumed the worker building it died and the export is queued again.
+const staleExportAge = time.Minute * 15
+
+// Worker builds requested exports and carries out erasures whose grace
+// period has passed. Claims use SKIP LOCKED, several instances can run
+// side by side.
+type Worker struct {
+	repo   Repository
+	cache  cache.Storage
+	logger *zap.SugaredLogger
+	cfg    Config
+	now    func() time.Time
+}
+
+// Run polls until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) tick(ctx context.Context) {
+	if err := w.repo.RequeueStaleDataExports(ctx, w.now().Add(-staleExportAge)); err != nil {
+		w.logger.Errorw("requeue stale data exports failed", "error", err.Error())
+	}
+	if err := w.repo.ExpireDataExports(ctx); err != nil {
+		w.logger.Errorw("expire data exports failed", "error", err.Error())
+	}
+
+	for ctx.Err() == nil {
+		done, err := w.processExport(ctx)
+		if err != nil {
+			w.logger.Errorw("data export failed", "error", err.Error())
+		}
+		if done {
+			break
+		}
+	}
+
+	for ctx.Err() == nil {
+		done, err := w.processErasure(ctx)
+		if err != nil {
+			w.logger.Errorw("account erasure failed", "error", err.Error())
+			break
+		}
+		if done {
+			break
+		}
+	}
+}
+
+// processExport builds the oldest pending export. done reports that the
+// queue is empty.
+func (w *Worker) processExport(ctx context.Context) (done bool, err error) {
+	export, err := w.repo.ClaimDataExport(ctx)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return true, nil
+		}
+		return true, err
+	}
+
+	archive, err := buildArchive(ctx, w.repo, export.UserID)
+	if err != nil {
+		// The reason is shown to the user, the details only go to the log.
+		if failErr := w.repo.FailDataExport(ctx, export.ID, "the export could not be created, please try again"); failErr != nil {
+			return false, failErr
+		}
+		return false, err
+	}
+
+	if err := w.repo.CompleteDataExport(ctx, export.ID, archive, w.now().Add(w.cfg.ExportExp)); err != nil {
+		return false, err
+	}
+
+	w.logger.Infow("data export ready", "export_id", export.ID, "user_id", export.UserID, "bytes", len(archive))
+	return false, nil
+}
+
+// processErasure deletes the account of the oldest due erasure request.
+// Claiming the request and deleting the data share a transaction, a
+// failure leaves the request due for the next run. The last super admin
+// is never deleted, their request is cancelled instead so it does not
+// hold up the queue.
+func (w *Worker) processErasure(ctx context.Context) (done bool, err error) {
+	var userID int64
+	err = w.repo.WithTx(ctx, func(repo Repository) error {
+		request, err := repo.ClaimDueErasureRequest(ctx)
+		if err != nil {
+			return err
+		}
+		userID = request.UserID
+
+		user, err := repo.GetUserByID(ctx, userID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				// Already deleted, completing the request is all that is
+				// left to do.
+				return nil
+			}
+			return err
+		}
+		last, err := isLastSuperAdmin(ctx, repo, user)
+		if err != nil {
+			return err
+		}
+		if last {
+			return ErrLastSuperAdmin
+		}
+
+		// posts has no ON DELETE CASCADE, every other table holding user
+		// data goes with the user row.
+		if err := repo.DeletePostsByUser(ctx, userID); err != nil {
+			return err
+		}
+		return repo.DeleteUser(ctx, userID)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			return true, nil
+		case errors.Is(err, ErrLastSuperAdmin):
+			if _, err := w.repo.CancelErasureRequest(ctx, userID); err != nil {
+				return true, err
+			}
+			w.logger.Warnw("account erasure cancelled, the user is the last super admin", "user_id", userID)
+			return false, nil
+		}
+		return true, err
+	}
+
+	if w.cfg.CacheEnabled {
+		w.cache.Users.Delete(ctx, userID)
+	}
+
+	w.logger.Infow("account erased", "user_id", userID)
+	return false, nil
+}
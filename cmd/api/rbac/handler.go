@@ -0,0 +1,301 @@
+package rbac
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mifaabiyyu/backend-go/internal/auth"
+	"github.com/mifaabiyyu/backend-go/internal/hierarchy"
+	"github.com/mifaabiyyu/backend-go/utils"
+)
+
+type Handler struct {
+	Service Service
+	*utils.AppWrapper
+}
+
+func (h *Handler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	res, err := h.Service.ListRoles(r.Context())
+	if err != nil {
+		h.InternalServerError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) GetRole(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.urlID(w, r, "id", "id")
+	if !ok {
+		return
+	}
+
+	res, err := h.Service.GetRole(r.Context(), id)
+	if err != nil {
+		h.errorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	actorRoleID, ok := h.actorRoleID(w, r)
+	if !ok {
+		return
+	}
+
+	var input CreateRoleRequest
+	if !h.readInput(w, r, &input) {
+		return
+	}
+
+	res, err := h.Service.CreateRole(r.Context(), actorRoleID, input)
+	if err != nil {
+		h.errorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, res)
+}
+
+func (h *Handler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	actorRoleID, ok := h.actorRoleID(w, r)
+	if !ok {
+		return
+	}
+
+	id, ok := h.urlID(w, r, "id", "id")
+	if !ok {
+		return
+	}
+
+	var input UpdateRoleRequest
+	if !h.readInput(w, r, &input) {
+		return
+	}
+
+	res, err := h.Service.UpdateRole(r.Context(), actorRoleID, id, input)
+	if err != nil {
+		h.errorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	actorRoleID, ok := h.actorRoleID(w, r)
+	if !ok {
+		return
+	}
+
+	id, ok := h.urlID(w, r, "id", "id")
+	if !ok {
+		return
+	}
+
+	if err := h.Service.DeleteRole(r.Context(), actorRoleID, id); err != nil {
+		h.errorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	res, err := h.Service.ListPermissions(r.Context())
+	if err != nil {
+		h.InternalServerError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) CreatePermission(w http.ResponseWriter, r *http.Request) {
+	actorRoleID, ok := h.actorRoleID(w, r)
+	if !ok {
+		return
+	}
+
+	var input CreatePermissionRequest
+	if !h.readInput(w, r, &input) {
+		return
+	}
+
+	res, err := h.Service.CreatePermission(r.Context(), actorRoleID, input)
+	if err != nil {
+		h.errorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, res)
+}
+
+func (h *Handler) UpdatePermission(w http.ResponseWriter, r *http.Request) {
+	actorRoleID, ok := h.actorRoleID(w, r)
+	if !ok {
+		return
+	}
+
+	id, ok := h.urlID(w, r, "id", "id")
+	if !ok {
+		return
+	}
+
+	var input UpdatePermissionRequest
+	if !h.readInput(w, r, &input) {
+		return
+	}
+
+	res, err := h.Service.UpdatePermission(r.Context(), actorRoleID, id, input)
+	if err != nil {
+		h.errorResponse(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) DeletePermission(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.urlID(w, r, "id", "id")
+	if !ok {
+		return
+	}
+
+	if err := h.Service.DeletePermission(r.Context(), id); err != nil {
+		h.errorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) AttachPermission(w http.ResponseWriter, r *http.Request) {
+	actorRoleID, ok := h.actorRoleID(w, r)
+	if !ok {
+		return
+	}
+
+	roleID, ok := h.urlID(w, r, "id", "id")
+	if !ok {
+		return
+	}
+	permissionID, ok := h.urlID(w, r, "permissionID", "permission id")
+	if !ok {
+		return
+	}
+
+	if err := h.Service.AttachPermission(r.Context(), actorRoleID, roleID, permissionID); err != nil {
+		h.errorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) DetachPermission(w http.ResponseWriter, r *http.Request) {
+	actorRoleID, ok := h.actorRoleID(w, r)
+	if !ok {
+		return
+	}
+
+	roleID, ok := h.urlID(w, r, "id", "id")
+	if !ok {
+		return
+	}
+	permissionID, ok := h.urlID(w, r, "permissionID", "permission id")
+	if !ok {
+		return
+	}
+
+	if err := h.Service.DetachPermission(r.Context(), actorRoleID, roleID, permissionID); err != nil {
+		h.errorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) AssignRole(w http.ResponseWriter, r *http.Request) {
+	actorRoleID, ok := h.actorRoleID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || userID < 1 {
+		h.BadRequestResponse(w, r, errors.New("invalid user id"))
+		return
+	}
+
+	var input AssignRoleRequest
+	if !h.readInput(w, r, &input) {
+		return
+	}
+
+	if err := h.Service.AssignRole(r.Context(), actorRoleID, userID, input.RoleID); err != nil {
+		h.errorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errorResponse maps the service errors shared by every endpoint.
+func (h *Handler) errorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		h.NotFoundResponse(w, r, err)
+	case errors.Is(err, hierarchy.ErrOutranked), errors.Is(err, hierarchy.ErrLevelTooHigh),
+		errors.Is(err, ErrPermissionNotHeld):
+		h.ForbiddenResponse(w, r, err)
+	case errors.Is(err, ErrInvalidRoleName), errors.Is(err, ErrInvalidPermissionName):
+		h.BadRequestResponse(w, r, err)
+	case errors.Is(err, ErrRoleExists), errors.Is(err, ErrPermissionExists),
+		errors.Is(err, ErrRoleInUse), errors.Is(err, ErrBuiltinRole),
+		errors.Is(err, ErrProtectedPermission), errors.Is(err, ErrLastSuperAdmin):
+		h.ConflictResponse(w, r, err)
+	default:
+		h.InternalServerError(w, r, err)
+	}
+}
+
+// actorRoleID returns the role of the admin making the request, the
+// hierarchy checks are made against it.
+func (h *Handler) actorRoleID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		h.UnauthorizedErrorResponse(w, r, errors.New("user not authenticated"))
+		return 0, false
+	}
+	return int64(user.RoleID.Int32), true
+}
+
+// readInput decodes and validates the request body, writing a 400 when
+// either fails.
+func (h *Handler) readInput(w http.ResponseWriter, r *http.Request, input any) bool {
+	if err := utils.ReadJSON(w, r, input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return false
+	}
+	if err := utils.Validate.Struct(input); err != nil {
+		h.BadRequestResponse(w, r, err)
+		return false
+	}
+	return true
+}
+
+// urlID parses a role or permission id. Both are referenced from INT
+// columns, larger values cannot exist.
+func (h *Handler) urlID(w http.ResponseWriter, r *http.Request, param, label string) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, param), 10, 32)
+	if err != nil || id < 1 {
+		h.BadRequestResponse(w, r, errors.New("invalid "+label))
+		return 0, false
+	}
+	return id, true
+}
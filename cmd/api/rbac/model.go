@@ -0,0 +1,50 @@
+package rbac
+
+import "time"
+
+type RoleResponse struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Level       int32  `json:"level"`
+	Description string `json:"description"`
+	// Permissions is only filled in when a single role is requested.
+	Permissions []PermissionResponse `json:"permissions,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+}
+
+type PermissionResponse struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CreateRoleRequest struct {
+	Name        string `json:"name" validate:"required,max=255"`
+	Level       int32  `json:"level" validate:"min=0"`
+	Description string `json:"description" validate:"max=500"`
+}
+
+// UpdateRoleRequest only changes the fields that are sent.
+type UpdateRoleRequest struct {
+	Name        *string `json:"name" validate:"omitempty,max=255"`
+	Level       *int32  `json:"level" validate:"omitempty,min=0"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+}
+
+type CreatePermissionRequest struct {
+	Name        string `json:"name" validate:"required,max=255"`
+	Description string `json:"description" validate:"max=500"`
+}
+
+// UpdatePermissionRequest only changes the fields that are sent.
+type UpdatePermissionRequest struct {
+	Name        *string `json:"name" validate:"omitempty,max=255"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+}
+
+type AssignRoleRequest struct {
+	RoleID int64 `json:"role_id" validate:"required,min=1"`
+}
@@ -0,0 +1,19 @@
+package rbac
+
+import (
+	"github.com/mifaabiyyu/backend-go/internal/hierarchy"
+	"github.com/mifaabiyyu/backend-go/internal/store"
+	"github.com/mifaabiyyu/backend-go/internal/store/cache"
+	"github.com/mifaabiyyu/backend-go/utils"
+)
+
+func InitRBACModule(store *store.Store, wrapper *utils.AppWrapper, roles *hierarchy.Guard, cacheStorage cache.Storage, cfg Config) *Handler {
+	repo := NewRBACRepository(store)
+
+	service := NewRBACService(repo, roles, cacheStorage, cfg)
+
+	return &Handler{
+		Service:    service,
+		AppWrapper: wrapper,
+	}
+}
@@ -0,0 +1,223 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/store"
+)
+
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+type Repository interface {
+	WithTx(ctx context.Context, fn func(Repository) error) error
+	ListRoles(ctx context.Context) ([]sqlc.Role, error)
+	GetRoleByID(ctx context.Context, id int64) (sqlc.Role, error)
+	GetRoleByIDForUpdate(ctx context.Context, id int64) (sqlc.Role, error)
+	CreateRole(ctx context.Context, arg sqlc.CreateRoleParams) (sqlc.Role, error)
+	UpdateRole(ctx context.Context, arg sqlc.UpdateRoleParams) (sqlc.Role, error)
+	DeleteRole(ctx context.Context, id int64) (bool, error)
+	ListPermissions(ctx context.Context) ([]sqlc.Permission, error)
+	GetPermissionByID(ctx context.Context, id int64) (sqlc.Permission, error)
+	GetPermissionsByRoleID(ctx context.Context, roleID int64) ([]sqlc.Permission, error)
+	GetEffectivePermissionsByRoleID(ctx context.Context, roleID int64) ([]sqlc.Permission, error)
+	CreatePermission(ctx context.Context, arg sqlc.CreatePermissionParams) (sqlc.Permission, error)
+	UpdatePermission(ctx context.Context, arg sqlc.UpdatePermissionParams) (sqlc.Permission, error)
+	DeletePermission(ctx context.Context, id int64) (bool, error)
+	AttachPermission(ctx context.Context, roleID, permissionID int64) error
+	DetachPermission(ctx context.Context, roleID, permissionID int64) (bool, error)
+	GetUserByID(ctx context.Context, userID int64) (sqlc.User, error)
+	UpdateUserRole(ctx context.Context, userID, roleID int64) error
+	CountUsersByRole(ctx context.Context, roleID int64) (int64, error)
+}
+
+type rbacRepo struct {
+	q     *sqlc.Queries
+	store *store.Store
+}
+
+func NewRBACRepository(store *store.Store) Repository {
+	return &rbacRepo{q: store.Queries, store: store}
+}
+
+func (r *rbacRepo) WithTx(ctx context.Context, fn func(Repository) error) error {
+	return r.store.WithTx(ctx, func(q *sqlc.Queries) error {
+		return fn(&rbacRepo{q: q, store: r.store})
+	})
+}
+
+func (r *rbacRepo) ListRoles(ctx context.Context) ([]sqlc.Role, error) {
+	return r.q.ListRoles(ctx)
+}
+
+func (r *rbacRepo) GetRoleByID(ctx context.Context, id int64) (sqlc.Role, error) {
+	role, err := r.q.GetRoleByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.Role{}, ErrNotFound
+		}
+		return sqlc.Role{}, err
+	}
+	return role, nil
+}
+
+// GetRoleByIDForUpdate locks the role row until the transaction ends.
+func (r *rbacRepo) GetRoleByIDForUpdate(ctx context.Context, id int64) (sqlc.Role, error) {
+	role, err := r.q.GetRoleByIDForUpdate(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.Role{}, ErrNotFound
+		}
+		return sqlc.Role{}, err
+	}
+	return role, nil
+}
+
+func (r *rbacRepo) CreateRole(ctx context.Context, arg sqlc.CreateRoleParams) (sqlc.Role, error) {
+	role, err := r.q.CreateRole(ctx, arg)
+	if err != nil {
+		if isViolation(err, uniqueViolation) {
+			return sqlc.Role{}, ErrRoleExists
+		}
+		return sqlc.Role{}, err
+	}
+	return role, nil
+}
+
+func (r *rbacRepo) UpdateRole(ctx context.Context, arg sqlc.UpdateRoleParams) (sqlc.Role, error) {
+	role, err := r.q.UpdateRole(ctx, arg)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return sqlc.Role{}, ErrNotFound
+		case isViolation(err, uniqueViolation):
+			return sqlc.Role{}, ErrRoleExists
+		}
+		return sqlc.Role{}, err
+	}
+	return role, nil
+}
+
+// DeleteRole maps a role still referenced by users or service accounts to
+// ErrRoleInUse.
+func (r *rbacRepo) DeleteRole(ctx context.Context, id int64) (bool, error) {
+	n, err := r.q.DeleteRole(ctx, id)
+	if err != nil {
+		if isViolation(err, foreignKeyViolation) {
+			return false, ErrRoleInUse
+		}
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *rbacRepo) ListPermissions(ctx context.Context) ([]sqlc.Permission, error) {
+	return r.q.ListPermissions(ctx)
+}
+
+func (r *rbacRepo) GetPermissionByID(ctx context.Context, id int64) (sqlc.Permission, error) {
+	permission, err := r.q.GetPermissionByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.Permission{}, ErrNotFound
+		}
+		return sqlc.Permission{}, err
+	}
+	return permission, nil
+}
+
+func (r *rbacRepo) GetPermissionsByRoleID(ctx context.Context, roleID int64) ([]sqlc.Permission, error) {
+	return r.q.GetPermissionsByRoleID(ctx, int32(roleID))
+}
+
+func (r *rbacRepo) GetEffectivePermissionsByRoleID(ctx context.Context, roleID int64) ([]sqlc.Permission, error) {
+	return r.q.GetEffectivePermissionsByRoleID(ctx, roleID)
+}
+
+func (r *rbacRepo) CreatePermission(ctx context.Context, arg sqlc.CreatePermissionParams) (sqlc.Permission, error) {
+	permission, err := r.q.CreatePermission(ctx, arg)
+	if err != nil {
+		if isViolation(err, uniqueViolation) {
+			return sqlc.Permission{}, ErrPermissionExists
+		}
+		return sqlc.Permission{}, err
+	}
+	return permission, nil
+}
+
+func (r *rbacRepo) UpdatePermission(ctx context.Context, arg sqlc.UpdatePermissionParams) (sqlc.Permission, error) {
+	permission, err := r.q.UpdatePermission(ctx, arg)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return sqlc.Permission{}, ErrNotFound
+		case isViolation(err, uniqueViolation):
+			return sqlc.Permission{}, ErrPermissionExists
+		}
+		return sqlc.Permission{}, err
+	}
+	return permission, nil
+}
+
+func (r *rbacRepo) DeletePermission(ctx context.Context, id int64) (bool, error) {
+	n, err := r.q.DeletePermission(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// roles_permissions and users.role_id are INT columns while the ids they
+// reference are BIGSERIAL, ids are narrowed here.
+
+func (r *rbacRepo) AttachPermission(ctx context.Context, roleID, permissionID int64) error {
+	return r.q.AttachPermissionToRole(ctx, sqlc.AttachPermissionToRoleParams{
+		RoleID:       int32(roleID),
+		PermissionID: int32(permissionID),
+	})
+}
+
+func (r *rbacRepo) DetachPermission(ctx context.Context, roleID, permissionID int64) (bool, error) {
+	n, err := r.q.DetachPermissionFromRole(ctx, sqlc.DetachPermissionFromRoleParams{
+		RoleID:       int32(roleID),
+		PermissionID: int32(permissionID),
+	})
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *rbacRepo) GetUserByID(ctx context.Context, userID int64) (sqlc.User, error) {
+	user, err := r.q.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.User{}, ErrNotFound
+		}
+		return sqlc.User{}, err
+	}
+	return user, nil
+}
+
+func (r *rbacRepo) UpdateUserRole(ctx context.Context, userID, roleID int64) error {
+	return r.q.UpdateUserRole(ctx, sqlc.UpdateUserRoleParams{
+		ID:     userID,
+		RoleID: pgtype.Int4{Int32: int32(roleID), Valid: true},
+	})
+}
+
+func (r *rbacRepo) CountUsersByRole(ctx context.Context, roleID int64) (int64, error) {
+	return r.q.CountUsersByRole(ctx, pgtype.Int4{Int32: int32(roleID), Valid: true})
+}
+
+func isViolation(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
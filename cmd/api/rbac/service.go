@@ -0,0 +1,488 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/hierarchy"
+	perm "github.com/mifaabiyyu/backend-go/internal/permission"
+	"github.com/mifaabiyyu/backend-go/internal/store/cache"
+)
+
+var (
+	ErrNotFound              = errors.New("record not found")
+	ErrRoleExists            = errors.New("role already exists")
+	ErrPermissionExists      = errors.New("permission already exists")
+	ErrRoleInUse             = errors.New("role is still assigned to users or service accounts")
+	ErrBuiltinRole           = errors.New("built-in roles cannot be renamed or deleted")
+	ErrProtectedPermission   = errors.New("the rbac:manage permission cannot be renamed, deleted, denied or removed from the super role")
+	ErrLastSuperAdmin        = errors.New("the last super admin cannot be given another role")
+	ErrPermissionNotHeld     = errors.New("you cannot grant a permission your own role does not have")
+	ErrInvalidRoleName       = errors.New("role name must start with a letter and contain only lowercase letters, numbers, - and _")
+	ErrInvalidPermissionName = errors.New("permission name must look like resource:action using lowercase letters, numbers and _, with * as a wildcard segment and an optional leading ! to deny")
+)
+
+const (
+	superRoleName = "super"
+	// managePermission guards this module, losing it would lock every
+	// admin out of role management.
+	managePermission = "rbac:manage"
+)
+
+// builtinRoles are referenced by name in code, see auth.defaultRoleName.
+var builtinRoles = []string{"user", superRoleName}
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+type Config struct {
+	CacheEnabled bool
+}
+
+type Service interface {
+	ListRoles(ctx context.Context) ([]RoleResponse, error)
+	GetRole(ctx context.Context, id int64) (*RoleResponse, error)
+	CreateRole(ctx context.Context, actorRoleID int64, req CreateRoleRequest) (*RoleResponse, error)
+	UpdateRole(ctx context.Context, actorRoleID, id int64, req UpdateRoleRequest) (*RoleResponse, error)
+	DeleteRole(ctx context.Context, actorRoleID, id int64) error
+	ListPermissions(ctx context.Context) ([]PermissionResponse, error)
+	CreatePermission(ctx context.Context, actorRoleID int64, req CreatePermissionRequest) (*PermissionResponse, error)
+	UpdatePermission(ctx context.Context, actorRoleID, id int64, req UpdatePermissionRequest) (*PermissionResponse, error)
+	DeletePermission(ctx context.Context, id int64) error
+	AttachPermission(ctx context.Context, actorRoleID, roleID, permissionID int64) error
+	DetachPermission(ctx context.Context, actorRoleID, roleID, permissionID int64) error
+	AssignRole(ctx context.Context, actorRoleID, userID, roleID int64) error
+}
+
+// Every change is checked against the actor's role level, see the
+// hierarchy package for the rules.
+type rbacService struct {
+	repo  Repository
+	roles *hierarchy.Guard
+	cache cache.Storage
+	cfg   Config
+}
+
+func NewRBACService(repo Repository, roles *hierarchy.Guard, cache cache.Storage, cfg Config) Service {
+	return &rbacService{
+		repo:  repo,
+		roles: roles,
+		cache: cache,
+		cfg:   cfg,
+	}
+}
+
+func (s *rbacService) ListRoles(ctx context.Context) ([]RoleResponse, error) {
+	roles, err := s.repo.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		res = append(res, roleResponse(role))
+	}
+	return res, nil
+}
+
+// GetRole returns the role together with its permissions.
+func (s *rbacService) GetRole(ctx context.Context, id int64) (*RoleResponse, error) {
+	role, err := s.repo.GetRoleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.repo.GetPermissionsByRoleID(ctx, role.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := roleResponse(role)
+	res.Permissions = make([]PermissionResponse, 0, len(permissions))
+	for _, permission := range permissions {
+		res.Permissions = append(res.Permissions, permissionResponse(permission))
+	}
+	return &res, nil
+}
+
+func (s *rbacService) CreateRole(ctx context.Context, actorRoleID int64, req CreateRoleRequest) (*RoleResponse, error) {
+	name := normalizeName(req.Name)
+	if !roleNamePattern.MatchString(name) {
+		return nil, ErrInvalidRoleName
+	}
+
+	actorLevel, err := s.roles.Level(ctx, actorRoleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := hierarchy.CanGrantLevel(actorLevel, req.Level); err != nil {
+		return nil, err
+	}
+
+	role, err := s.repo.CreateRole(ctx, sqlc.CreateRoleParams{
+		Name:        name,
+		Level:       req.Level,
+		Description: optionalText(req.Description),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := roleResponse(role)
+	return &res, nil
+}
+
+func (s *rbacService) UpdateRole(ctx context.Context, actorRoleID, id int64, req UpdateRoleRequest) (*RoleResponse, error) {
+	role, err := s.repo.GetRoleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	actorLevel, err := s.roles.Level(ctx, actorRoleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := hierarchy.CanGrantLevel(actorLevel, role.Level); err != nil {
+		return nil, err
+	}
+
+	arg := sqlc.UpdateRoleParams{
+		ID:          role.ID,
+		Name:        role.Name,
+		Level:       role.Level,
+		Description: role.Description,
+	}
+	if req.Name != nil {
+		arg.Name = normalizeName(*req.Name)
+		if !roleNamePattern.MatchString(arg.Name) {
+			return nil, ErrInvalidRoleName
+		}
+		if arg.Name != role.Name && isBuiltinRole(role.Name) {
+			return nil, ErrBuiltinRole
+		}
+	}
+	if req.Level != nil {
+		if err := hierarchy.CanGrantLevel(actorLevel, *req.Level); err != nil {
+			return nil, err
+		}
+		arg.Level = *req.Level
+	}
+	if req.Description != nil {
+		arg.Description = optionalText(*req.Description)
+	}
+
+	updated, err := s.repo.UpdateRole(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidatePermissions(ctx)
+
+	res := roleResponse(updated)
+	return &res, nil
+}
+
+func (s *rbacService) DeleteRole(ctx context.Context, actorRoleID, id int64) error {
+	role, err := s.repo.GetRoleByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if isBuiltinRole(role.Name) {
+		return ErrBuiltinRole
+	}
+	if err := s.roles.CanManageRole(ctx, actorRoleID, role.ID); err != nil {
+		return err
+	}
+
+	deleted, err := s.repo.DeleteRole(ctx, role.ID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	s.invalidatePermissions(ctx)
+	return nil
+}
+
+func (s *rbacService) ListPermissions(ctx context.Context) ([]PermissionResponse, error) {
+	permissions, err := s.repo.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]PermissionResponse, 0, len(permissions))
+	for _, permission := range permissions {
+		res = append(res, permissionResponse(permission))
+	}
+	return res, nil
+}
+
+// CreatePermission adds a permission row. A new name grants nothing until
+// it is attached, but a wildcard covers existing permissions too and
+// needs to be held by the actor already.
+func (s *rbacService) CreatePermission(ctx context.Context, actorRoleID int64, req CreatePermissionRequest) (*PermissionResponse, error) {
+	name := normalizeName(req.Name)
+	if !perm.Valid(name) {
+		return nil, ErrInvalidPermissionName
+	}
+	if strings.Contains(name, "*") {
+		if err := s.requireHeld(ctx, actorRoleID, name); err != nil {
+			return nil, err
+		}
+	}
+
+	permission, err := s.repo.CreatePermission(ctx, sqlc.CreatePermissionParams{
+		Name:        name,
+		Description: optionalText(req.Description),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := permissionResponse(permission)
+	return &res, nil
+}
+
+// UpdatePermission renames a permission for every role that has it, so the
+// new name has to be one the actor could attach.
+func (s *rbacService) UpdatePermission(ctx context.Context, actorRoleID, id int64, req UpdatePermissionRequest) (*PermissionResponse, error) {
+	permission, err := s.repo.GetPermissionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	arg := sqlc.UpdatePermissionParams{
+		ID:          permission.ID,
+		Name:        permission.Name,
+		Description: permission.Description,
+	}
+	if req.Name != nil {
+		arg.Name = normalizeName(*req.Name)
+		if !perm.Valid(arg.Name) {
+			return nil, ErrInvalidPermissionName
+		}
+		if arg.Name != permission.Name {
+			if permission.Name == managePermission || deniesManage(arg.Name) {
+				return nil, ErrProtectedPermission
+			}
+			if err := s.requireHeld(ctx, actorRoleID, arg.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if req.Description != nil {
+		arg.Description = optionalText(*req.Description)
+	}
+
+	updated, err := s.repo.UpdatePermission(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidatePermissions(ctx)
+
+	res := permissionResponse(updated)
+	return &res, nil
+}
+
+func (s *rbacService) DeletePermission(ctx context.Context, id int64) error {
+	permission, err := s.repo.GetPermissionByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if permission.Name == managePermission {
+		return ErrProtectedPermission
+	}
+
+	deleted, err := s.repo.DeletePermission(ctx, permission.ID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	s.invalidatePermissions(ctx)
+	return nil
+}
+
+// AttachPermission grants a permission to a role, attaching it twice is
+// not an error. The role has to be below the actor's level and the actor
+// has to hold the permission, wildcards included, so nobody can hand out
+// more than they have.
+func (s *rbacService) AttachPermission(ctx context.Context, actorRoleID, roleID, permissionID int64) error {
+	if _, err := s.repo.GetRoleByID(ctx, roleID); err != nil {
+		return err
+	}
+	permission, err := s.repo.GetPermissionByID(ctx, permissionID)
+	if err != nil {
+		return err
+	}
+	// Denies are not inherited, but the same permission row may later be
+	// attached to the super role, or the role raised to its level.
+	if deniesManage(permission.Name) {
+		return ErrProtectedPermission
+	}
+	if err := s.roles.CanManageRole(ctx, actorRoleID, roleID); err != nil {
+		return err
+	}
+	if err := s.requireHeld(ctx, actorRoleID, permission.Name); err != nil {
+		return err
+	}
+
+	if err := s.repo.AttachPermission(ctx, roleID, permissionID); err != nil {
+		return err
+	}
+	s.invalidatePermissions(ctx)
+	return nil
+}
+
+func (s *rbacService) DetachPermission(ctx context.Context, actorRoleID, roleID, permissionID int64) error {
+	role, err := s.repo.GetRoleByID(ctx, roleID)
+	if err != nil {
+		return err
+	}
+	permission, err := s.repo.GetPermissionByID(ctx, permissionID)
+	if err != nil {
+		return err
+	}
+	if role.Name == superRoleName && permission.Name == managePermission {
+		return ErrProtectedPermission
+	}
+	if err := s.roles.CanManageRole(ctx, actorRoleID, role.ID); err != nil {
+		return err
+	}
+
+	detached, err := s.repo.DetachPermission(ctx, role.ID, permission.ID)
+	if err != nil {
+		return err
+	}
+	if !detached {
+		return ErrNotFound
+	}
+	s.invalidatePermissions(ctx)
+	return nil
+}
+
+// AssignRole moves a user to another role. The actor has to outrank the
+// user and cannot hand out a role above their own level. Moving a super
+// admin away locks the super role row first, so two admins demoting each
+// other at the same time cannot leave the system without one.
+func (s *rbacService) AssignRole(ctx context.Context, actorRoleID, userID, roleID int64) error {
+	err := s.repo.WithTx(ctx, func(repo Repository) error {
+		user, err := repo.GetUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		role, err := repo.GetRoleByID(ctx, roleID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.roles.CanAssignRole(ctx, actorRoleID, int64(user.RoleID.Int32), role.ID); err != nil {
+			return err
+		}
+
+		if user.RoleID.Valid && int64(user.RoleID.Int32) != role.ID {
+			current, err := repo.GetRoleByIDForUpdate(ctx, int64(user.RoleID.Int32))
+			if err != nil {
+				return err
+			}
+
+			if current.Name == superRoleName {
+				supers, err := repo.CountUsersByRole(ctx, current.ID)
+				if err != nil {
+					return err
+				}
+				if supers <= 1 {
+					return ErrLastSuperAdmin
+				}
+			}
+		}
+
+		return repo.UpdateUserRole(ctx, user.ID, role.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The middleware reads the role from the cached user.
+	if s.cfg.CacheEnabled {
+		s.cache.Users.Delete(ctx, userID)
+	}
+	return nil
+}
+
+// requireHeld checks that the actor's role, inherited grants included,
+// covers every permission grant matches. Denies only take permissions
+// away and are always accepted.
+func (s *rbacService) requireHeld(ctx context.Context, actorRoleID int64, grant string) error {
+	if strings.HasPrefix(grant, "!") {
+		return nil
+	}
+
+	permissions, err := s.repo.GetEffectivePermissionsByRoleID(ctx, actorRoleID)
+	if err != nil {
+		return err
+	}
+
+	grants := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		grants = append(grants, p.Name)
+	}
+	if !perm.Compile(grants).Covers(grant) {
+		return ErrPermissionNotHeld
+	}
+	return nil
+}
+
+// invalidatePermissions drops the cached role permissions right away. The
+// database triggers notify the other instances, a failure here only
+// delays this one until its listener catches up.
+func (s *rbacService) invalidatePermissions(ctx context.Context) {
+	s.cache.Permissions.Invalidate(ctx)
+}
+
+func roleResponse(role sqlc.Role) RoleResponse {
+	return RoleResponse{
+		ID:          role.ID,
+		Name:        role.Name,
+		Level:       role.Level,
+		Description: role.Description.String,
+		CreatedAt:   role.CreatedAt.Time,
+		UpdatedAt:   role.UpdatedAt.Time,
+	}
+}
+
+func permissionResponse(permission sqlc.Permission) PermissionResponse {
+	return PermissionResponse{
+		ID:          permission.ID,
+		Name:        permission.Name,
+		Description: permission.Description.String,
+		CreatedAt:   permission.CreatedAt.Time,
+		UpdatedAt:   permission.UpdatedAt.Time,
+	}
+}
+
+// deniesManage reports whether grant is a deny that covers rbac:manage.
+func deniesManage(grant string) bool {
+	name, ok := strings.CutPrefix(grant, "!")
+	return ok && perm.Compile([]string{name}).Allows(managePermission)
+}
+
+func isBuiltinRole(name string) bool {
+	return slices.Contains(builtinRoles, name)
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// optionalText stores an empty description as NULL.
+func optionalText(s string) pgtype.Text {
+	s = strings.TrimSpace(s)
+	return pgtype.Text{String: s, Valid: s != ""}
+}
@@ -0,0 +1,325 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/hierarchy"
+	"github.com/mifaabiyyu/backend-go/internal/store/cache"
+)
+
+const (
+	userRole int64 = iota + 1
+	moderatorRole
+	adminRole
+	otherAdminRole
+	superRole
+)
+
+var testRoles = []sqlc.Role{
+	{ID: userRole, Name: "user", Level: 1},
+	{ID: moderatorRole, Name: "moderator", Level: 2},
+	{ID: adminRole, Name: "admin", Level: 3},
+	{ID: otherAdminRole, Name: "support-admin", Level: 3},
+	{ID: superRole, Name: superRoleName, Level: 4},
+}
+
+// memoryRepo keeps roles and their permissions in memory. Calling a method
+// it does not override panics on the nil embedded Repository.
+type memoryRepo struct {
+	Repository
+
+	roles       map[int64]sqlc.Role
+	permissions map[int64]sqlc.Permission
+	attached    map[int64][]int64
+	nextID      int64
+}
+
+func newMemoryRepo() *memoryRepo {
+	r := &memoryRepo{
+		roles:       make(map[int64]sqlc.Role),
+		permissions: make(map[int64]sqlc.Permission),
+		attached:    make(map[int64][]int64),
+		nextID:      100,
+	}
+	for _, role := range testRoles {
+		r.roles[role.ID] = role
+	}
+	return r
+}
+
+// grant attaches name to the role, creating the permission when needed.
+func (r *memoryRepo) grant(roleID int64, name string) int64 {
+	id := r.permissionID(name)
+	if id == 0 {
+		r.nextID++
+		id = r.nextID
+		r.permissions[id] = sqlc.Permission{ID: id, Name: name}
+	}
+	if roleID != 0 {
+		r.attached[roleID] = append(r.attached[roleID], id)
+	}
+	return id
+}
+
+func (r *memoryRepo) permissionID(name string) int64 {
+	for id, p := range r.permissions {
+		if p.Name == name {
+			return id
+		}
+	}
+	return 0
+}
+
+func (r *memoryRepo) has(roleID int64, name string) bool {
+	return slices.Contains(r.attached[roleID], r.permissionID(name))
+}
+
+func (r *memoryRepo) GetRoleByID(ctx context.Context, id int64) (sqlc.Role, error) {
+	role, ok := r.roles[id]
+	if !ok {
+		return sqlc.Role{}, ErrNotFound
+	}
+	return role, nil
+}
+
+func (r *memoryRepo) GetPermissionByID(ctx context.Context, id int64) (sqlc.Permission, error) {
+	p, ok := r.permissions[id]
+	if !ok {
+		return sqlc.Permission{}, ErrNotFound
+	}
+	return p, nil
+}
+
+// GetEffectivePermissionsByRoleID mirrors the query: the role's own
+// permissions plus the allows of every lower level role.
+func (r *memoryRepo) GetEffectivePermissionsByRoleID(ctx context.Context, roleID int64) ([]sqlc.Permission, error) {
+	own := r.roles[roleID]
+
+	var res []sqlc.Permission
+	for id, ids := range r.attached {
+		for _, pid := range ids {
+			p := r.permissions[pid]
+			if id == roleID || (r.roles[id].Level < own.Level && !strings.HasPrefix(p.Name, "!")) {
+				res = append(res, p)
+			}
+		}
+	}
+	return res, nil
+}
+
+func (r *memoryRepo) CreatePermission(ctx context.Context, arg sqlc.CreatePermissionParams) (sqlc.Permission, error) {
+	if r.permissionID(arg.Name) != 0 {
+		return sqlc.Permission{}, ErrPermissionExists
+	}
+	return r.permissions[r.grant(0, arg.Name)], nil
+}
+
+func (r *memoryRepo) UpdatePermission(ctx context.Context, arg sqlc.UpdatePermissionParams) (sqlc.Permission, error) {
+	p := r.permissions[arg.ID]
+	p.Name = arg.Name
+	p.Description = arg.Description
+	r.permissions[arg.ID] = p
+	return p, nil
+}
+
+func (r *memoryRepo) AttachPermission(ctx context.Context, roleID, permissionID int64) error {
+	if !slices.Contains(r.attached[roleID], permissionID) {
+		r.attached[roleID] = append(r.attached[roleID], permissionID)
+	}
+	return nil
+}
+
+func newTestService(repo *memoryRepo) Service {
+	roles := hierarchy.NewMemoryRoleStore()
+	for _, role := range repo.roles {
+		roles.Put(hierarchy.Role{ID: role.ID, Name: role.Name, Level: role.Level})
+	}
+
+	return NewRBACService(repo, hierarchy.New(roles), cache.Storage{Permissions: cache.NewMemoryPermissionStore()}, Config{})
+}
+
+func TestAttachPermission(t *testing.T) {
+	tests := []struct {
+		name       string
+		actorGrant []string
+		// grants of a role below the actor, inherited by the actor
+		inherited  []string
+		role       int64
+		permission string
+		want       error
+	}{
+		{
+			name:       "held permission to a lower role",
+			actorGrant: []string{managePermission, "post:delete"},
+			role:       moderatorRole,
+			permission: "post:delete",
+		},
+		{
+			name:       "inherited permission to a lower role",
+			actorGrant: []string{managePermission},
+			inherited:  []string{"post:read"},
+			role:       userRole,
+			permission: "post:read",
+		},
+		{
+			name:       "permission the actor lacks",
+			actorGrant: []string{managePermission},
+			role:       moderatorRole,
+			permission: "user:delete",
+			want:       ErrPermissionNotHeld,
+		},
+		{
+			name:       "self grant",
+			actorGrant: []string{managePermission},
+			role:       adminRole,
+			permission: "user:delete",
+			want:       hierarchy.ErrLevelTooHigh,
+		},
+		{
+			name:       "held permission to the actor's own role",
+			actorGrant: []string{managePermission, "post:delete"},
+			role:       adminRole,
+			permission: "post:delete",
+			want:       hierarchy.ErrLevelTooHigh,
+		},
+		{
+			name:       "role at the actor's level",
+			actorGrant: []string{managePermission, "post:delete"},
+			role:       otherAdminRole,
+			permission: "post:delete",
+			want:       hierarchy.ErrLevelTooHigh,
+		},
+		{
+			name:       "everything to a lower role",
+			actorGrant: []string{managePermission, "post:*"},
+			role:       moderatorRole,
+			permission: "*",
+			want:       ErrPermissionNotHeld,
+		},
+		{
+			name:       "everything to the actor's own role",
+			actorGrant: []string{managePermission},
+			role:       adminRole,
+			permission: "*",
+			want:       hierarchy.ErrLevelTooHigh,
+		},
+		{
+			name:       "held wildcard",
+			actorGrant: []string{managePermission, "post:*"},
+			role:       moderatorRole,
+			permission: "post:*",
+		},
+		{
+			name:       "narrower wildcard",
+			actorGrant: []string{managePermission, "post:*"},
+			role:       moderatorRole,
+			permission: "post:comment:*",
+		},
+		{
+			name:       "wildcard the actor is partly denied",
+			actorGrant: []string{managePermission, "post:*", "!post:delete"},
+			role:       moderatorRole,
+			permission: "post:*",
+			want:       ErrPermissionNotHeld,
+		},
+		{
+			name:       "wildcard broader than the actor's",
+			actorGrant: []string{managePermission, "post:read"},
+			role:       moderatorRole,
+			permission: "post:*",
+			want:       ErrPermissionNotHeld,
+		},
+		{
+			name:       "deny the actor lacks",
+			actorGrant: []string{managePermission},
+			role:       moderatorRole,
+			permission: "!user:delete",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemoryRepo()
+			for _, grant := range tt.actorGrant {
+				repo.grant(adminRole, grant)
+			}
+			for _, grant := range tt.inherited {
+				repo.grant(moderatorRole, grant)
+			}
+			permissionID := repo.grant(0, tt.permission)
+			before := len(repo.attached[tt.role])
+			s := newTestService(repo)
+
+			err := s.AttachPermission(context.Background(), adminRole, tt.role, permissionID)
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("expected %v, got %v", tt.want, err)
+				}
+				if len(repo.attached[tt.role]) != before {
+					t.Fatal("the permission was attached")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AttachPermission: %v", err)
+			}
+			if !repo.has(tt.role, tt.permission) {
+				t.Fatal("the permission was not attached")
+			}
+		})
+	}
+}
+
+func TestCreatePermissionWildcard(t *testing.T) {
+	repo := newMemoryRepo()
+	repo.grant(adminRole, managePermission)
+	repo.grant(adminRole, "post:*")
+	s := newTestService(repo)
+	ctx := context.Background()
+
+	for _, name := range []string{"*", "*:delete", "user:*"} {
+		if _, err := s.CreatePermission(ctx, adminRole, CreatePermissionRequest{Name: name}); !errors.Is(err, ErrPermissionNotHeld) {
+			t.Fatalf("%q: expected ErrPermissionNotHeld, got %v", name, err)
+		}
+		if repo.permissionID(name) != 0 {
+			t.Fatalf("%q was created", name)
+		}
+	}
+
+	// A new name grants nothing until it is attached.
+	for _, name := range []string{"post:comment:*", "report:export", "!user:delete"} {
+		if _, err := s.CreatePermission(ctx, adminRole, CreatePermissionRequest{Name: name}); err != nil {
+			t.Fatalf("%q: %v", name, err)
+		}
+	}
+}
+
+func TestUpdatePermissionRename(t *testing.T) {
+	repo := newMemoryRepo()
+	repo.grant(adminRole, managePermission)
+	readID := repo.grant(adminRole, "post:read")
+	s := newTestService(repo)
+	ctx := context.Background()
+
+	// Renaming a permission the actor's role holds would grant the new
+	// name to the actor.
+	for _, name := range []string{"*", "user:delete"} {
+		if _, err := s.UpdatePermission(ctx, adminRole, readID, UpdatePermissionRequest{Name: &name}); !errors.Is(err, ErrPermissionNotHeld) {
+			t.Fatalf("%q: expected ErrPermissionNotHeld, got %v", name, err)
+		}
+	}
+	if repo.permissions[readID].Name != "post:read" {
+		t.Fatal("the permission was renamed")
+	}
+
+	same := "post:read"
+	description := "read posts"
+	if _, err := s.UpdatePermission(ctx, adminRole, readID, UpdatePermissionRequest{Name: &same, Description: &description}); err != nil {
+		t.Fatalf("UpdatePermission: %v", err)
+	}
+}
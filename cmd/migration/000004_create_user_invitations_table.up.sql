@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS user_invitations (
+  token_hash TEXT PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX IF NOT EXISTS user_invitations_user_id_idx ON user_invitations (user_id);
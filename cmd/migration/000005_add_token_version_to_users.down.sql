@@ -0,0 +1,4 @@
+ALTER TABLE
+  IF EXISTS users
+DROP
+  COLUMN IF EXISTS token_version;
@@ -0,0 +1,4 @@
+ALTER TABLE
+  IF EXISTS users
+ADD
+  COLUMN IF NOT EXISTS token_version INT NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE name = 'user:unlock';
+
+DROP TABLE IF EXISTS account_unlocks;
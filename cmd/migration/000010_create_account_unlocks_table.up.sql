@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS account_unlocks (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  token_hash TEXT NOT NULL UNIQUE,
+  expires_at timestamptz NOT NULL,
+  used_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX IF NOT EXISTS account_unlocks_user_id_idx ON account_unlocks (user_id);
+
+INSERT INTO
+  permissions (name, description)
+VALUES
+  (
+    'user:unlock',
+    'Clear the login lockout of any account'
+  ) ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO
+  roles_permissions (role_id, permission_id)
+SELECT
+  r.id,
+  p.id
+FROM
+  roles r,
+  permissions p
+WHERE
+  r.name = 'super'
+  AND p.name = 'user:unlock' ON CONFLICT (role_id, permission_id) DO NOTHING;
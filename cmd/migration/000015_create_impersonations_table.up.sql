@@ -0,0 +1,50 @@
+CREATE TABLE IF NOT EXISTS impersonations (
+  id BIGSERIAL PRIMARY KEY,
+  -- No foreign keys, the audit trail outlives deleted users.
+  actor_id BIGINT NOT NULL,
+  user_id BIGINT NOT NULL,
+  token_id TEXT NOT NULL UNIQUE,
+  reason TEXT NOT NULL,
+  ip TEXT NOT NULL,
+  user_agent TEXT NOT NULL,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX IF NOT EXISTS impersonations_actor_id_idx ON impersonations (actor_id);
+
+CREATE INDEX IF NOT EXISTS impersonations_user_id_idx ON impersonations (user_id);
+
+CREATE TABLE IF NOT EXISTS impersonation_requests (
+  id BIGSERIAL PRIMARY KEY,
+  token_id TEXT NOT NULL REFERENCES impersonations(token_id) ON DELETE RESTRICT,
+  actor_id BIGINT NOT NULL,
+  user_id BIGINT NOT NULL,
+  method TEXT NOT NULL,
+  path TEXT NOT NULL,
+  status INT,
+  ip TEXT NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX IF NOT EXISTS impersonation_requests_token_id_idx ON impersonation_requests (token_id);
+
+INSERT INTO
+  permissions (name, description)
+VALUES
+  (
+    'user:impersonate',
+    'Act as another user with the same or a lower role level'
+  ) ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO
+  roles_permissions (role_id, permission_id)
+SELECT
+  r.id,
+  p.id
+FROM
+  roles r,
+  permissions p
+WHERE
+  r.name = 'super'
+  AND p.name = 'user:impersonate' ON CONFLICT (role_id, permission_id) DO NOTHING;
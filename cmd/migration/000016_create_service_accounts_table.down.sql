@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE name = 'service_account:manage';
+
+DROP TABLE IF EXISTS service_accounts;
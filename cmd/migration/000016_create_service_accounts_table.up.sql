@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS service_accounts (
+  id BIGSERIAL PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE,
+  description TEXT NOT NULL DEFAULT '',
+  client_id TEXT NOT NULL UNIQUE,
+  secret_hash TEXT NOT NULL,
+  role_id INT NOT NULL REFERENCES roles(id),
+  created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  last_used_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT (now()),
+  updated_at timestamptz NOT NULL DEFAULT (now())
+);
+
+INSERT INTO
+  permissions (name, description)
+VALUES
+  (
+    'service_account:manage',
+    'Create, list, rotate and delete service accounts'
+  ) ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO
+  roles_permissions (role_id, permission_id)
+SELECT
+  r.id,
+  p.id
+FROM
+  roles r,
+  permissions p
+WHERE
+  r.name = 'super'
+  AND p.name = 'service_account:manage' ON CONFLICT (role_id, permission_id) DO NOTHING;
@@ -0,0 +1,19 @@
+INSERT INTO
+  permissions (name, description)
+VALUES
+  (
+    'rbac:manage',
+    'Manage roles, permissions and user role assignments'
+  ) ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO
+  roles_permissions (role_id, permission_id)
+SELECT
+  r.id,
+  p.id
+FROM
+  roles r,
+  permissions p
+WHERE
+  r.name = 'super'
+  AND p.name = 'rbac:manage' ON CONFLICT (role_id, permission_id) DO NOTHING;
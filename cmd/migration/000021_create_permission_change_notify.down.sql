@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS permissions_changed ON permissions;
+
+DROP TRIGGER IF EXISTS roles_changed ON roles;
+
+DROP TRIGGER IF EXISTS roles_permissions_changed ON roles_permissions;
+
+DROP FUNCTION IF EXISTS notify_role_permissions_changed();
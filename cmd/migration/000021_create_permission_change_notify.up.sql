@@ -0,0 +1,23 @@
+-- API instances cache role permissions and LISTEN on this channel to
+-- drop them. The triggers also catch changes made by hand in SQL.
+CREATE OR REPLACE FUNCTION notify_role_permissions_changed() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('role_permissions_changed', TG_TABLE_NAME);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS roles_permissions_changed ON roles_permissions;
+CREATE TRIGGER roles_permissions_changed
+AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON roles_permissions
+FOR EACH STATEMENT EXECUTE FUNCTION notify_role_permissions_changed();
+
+DROP TRIGGER IF EXISTS roles_changed ON roles;
+CREATE TRIGGER roles_changed
+AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON roles
+FOR EACH STATEMENT EXECUTE FUNCTION notify_role_permissions_changed();
+
+DROP TRIGGER IF EXISTS permissions_changed ON permissions;
+CREATE TRIGGER permissions_changed
+AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON permissions
+FOR EACH STATEMENT EXECUTE FUNCTION notify_role_permissions_changed();
@@ -0,0 +1 @@
+DELETE FROM permissions WHERE name IN ('post:update', 'post:delete');
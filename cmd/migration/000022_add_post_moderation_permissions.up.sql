@@ -0,0 +1,23 @@
+INSERT INTO
+  permissions (name, description)
+VALUES
+  (
+    'post:update',
+    'Edit posts written by other users'
+  ),
+  (
+    'post:delete',
+    'Delete posts written by other users'
+  ) ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO
+  roles_permissions (role_id, permission_id)
+SELECT
+  r.id,
+  p.id
+FROM
+  roles r,
+  permissions p
+WHERE
+  r.name = 'super'
+  AND p.name IN ('post:update', 'post:delete') ON CONFLICT (role_id, permission_id) DO NOTHING;
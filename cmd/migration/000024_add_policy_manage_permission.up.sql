@@ -0,0 +1,19 @@
+INSERT INTO
+  permissions (name, description)
+VALUES
+  (
+    'policy:manage',
+    'Evaluate and reload ABAC policies and manage user attributes'
+  ) ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO
+  roles_permissions (role_id, permission_id)
+SELECT
+  r.id,
+  p.id
+FROM
+  roles r,
+  permissions p
+WHERE
+  r.name = 'super'
+  AND p.name = 'policy:manage' ON CONFLICT (role_id, permission_id) DO NOTHING;
@@ -0,0 +1,4 @@
+ALTER TABLE
+  IF EXISTS oauth_states
+DROP
+  COLUMN IF EXISTS user_id;
@@ -0,0 +1,5 @@
+-- Set when a signed in user links another identity to their account.
+ALTER TABLE
+  IF EXISTS oauth_states
+ADD
+  COLUMN IF NOT EXISTS user_id BIGINT REFERENCES users(id) ON DELETE CASCADE;
@@ -0,0 +1,20 @@
+INSERT INTO
+  permissions (name, description)
+VALUES
+  (
+    'post:create',
+    'Write new posts'
+  ) ON CONFLICT (name) DO NOTHING;
+
+-- Higher levels inherit it from user.
+INSERT INTO
+  roles_permissions (role_id, permission_id)
+SELECT
+  r.id,
+  p.id
+FROM
+  roles r,
+  permissions p
+WHERE
+  r.name = 'user'
+  AND p.name = 'post:create' ON CONFLICT (role_id, permission_id) DO NOTHING;
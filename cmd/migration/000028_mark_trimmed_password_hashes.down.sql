@@ -0,0 +1,6 @@
+UPDATE
+  users
+SET
+  password = substr(password, length('trimmed:') + 1)
+WHERE
+  password LIKE 'trimmed:%';
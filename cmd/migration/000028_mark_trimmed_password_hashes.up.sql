@@ -0,0 +1,8 @@
+-- Passwords used to be trimmed before hashing. Marked hashes also accept
+-- the trimmed password and are replaced by an exact hash on the next login.
+UPDATE
+  users
+SET
+  password = 'trimmed:' || password
+WHERE
+  password NOT LIKE 'trimmed:%';
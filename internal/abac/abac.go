@@ -0,0 +1,181 @@
+// Package abac evaluates attribute based access control policies, for rules
+// RBAC cannot express such as "support staff can read users only in their
+// own region during business hours".
+//
+// Policies are declared in a YAML or JSON file, see Load for the format.
+// Each policy applies to a set of actions, which are permission names and
+// accept the same patterns, and holds conditions over four groups of
+// attributes: subject, resource, action and environment. A matching deny
+// always wins over a matching allow, and a request no policy matches is
+// NotApplicable so the caller can fall back to RBAC.
+//
+// An allow is a grant of its own, not a further restriction on RBAC: the
+// RequirePolicy middleware lets a request an allow matches through even
+// when the role lacks the permission, only token scopes still apply. To
+// narrow what a role may do, write a deny; to add to RBAC, keep the allow
+// conditions as tight as the permission they stand in for.
+package abac
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNotFound is returned by resource loaders when the resource does not
+// exist.
+var ErrNotFound = errors.New("resource not found")
+
+type Effect string
+
+const (
+	Allow         Effect = "allow"
+	Deny          Effect = "deny"
+	NotApplicable Effect = "not_applicable"
+)
+
+// Request holds the attributes a decision is made on. Attribute values
+// are strings, numbers, booleans, time.Time, nested maps or lists of
+// those.
+type Request struct {
+	Subject     map[string]any `json:"subject"`
+	Resource    map[string]any `json:"resource"`
+	Action      string         `json:"action"`
+	Environment map[string]any `json:"environment"`
+}
+
+type Decision struct {
+	Effect Effect `json:"effect"`
+	// PolicyID is the policy that decided, empty when none applied.
+	PolicyID string `json:"policy_id,omitempty"`
+	// Trace explains every policy, only filled in by Explain.
+	Trace []Trace `json:"trace,omitempty"`
+}
+
+func (d Decision) Allowed() bool {
+	return d.Effect == Allow
+}
+
+type Trace struct {
+	PolicyID string `json:"policy_id"`
+	Effect   Effect `json:"effect"`
+	Matched  bool   `json:"matched"`
+	// Reason tells why the policy did not match.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Engine evaluates the policies of one file. It is safe for concurrent
+// use, Reload swaps the policies atomically.
+type Engine struct {
+	path string
+	set  atomic.Pointer[PolicySet]
+}
+
+// NewEngine loads the policies in path. An empty path gives an engine
+// without policies, every request is then NotApplicable.
+func NewEngine(path string) (*Engine, error) {
+	e := &Engine{path: path}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Reload reads the policy file again. The current policies are kept when
+// the file is invalid.
+func (e *Engine) Reload() error {
+	set := &PolicySet{Timezone: time.UTC.String(), Location: time.UTC, LoadedAt: time.Now()}
+	if e.path != "" {
+		var err error
+		set, err = LoadFile(e.path)
+		if err != nil {
+			return err
+		}
+	}
+
+	e.set.Store(set)
+	return nil
+}
+
+// Policies returns the loaded policies.
+func (e *Engine) Policies() *PolicySet {
+	return e.set.Load()
+}
+
+// Environment describes when and from where a request is made, in the
+// timezone of the policies: time, date ("2006-01-02"), time_of_day
+// ("15:04", comparable with gte/lt), hour, weekday ("monday") and ip.
+func (e *Engine) Environment(now time.Time, ip string) map[string]any {
+	now = now.In(e.set.Load().Location)
+	return map[string]any{
+		"time":        now,
+		"date":        now.Format(time.DateOnly),
+		"time_of_day": now.Format("15:04"),
+		"hour":        now.Hour(),
+		"weekday":     strings.ToLower(now.Weekday().String()),
+		"ip":          ip,
+	}
+}
+
+func (e *Engine) Evaluate(req Request) Decision {
+	return e.set.Load().evaluate(req, false)
+}
+
+// Explain is Evaluate with a trace of every policy, for debugging.
+func (e *Engine) Explain(req Request) Decision {
+	return e.set.Load().evaluate(req, true)
+}
+
+func (s *PolicySet) evaluate(req Request, explain bool) Decision {
+	attributes := map[string]any{
+		"subject":     req.Subject,
+		"resource":    req.Resource,
+		"action":      req.Action,
+		"environment": req.Environment,
+	}
+
+	decision := Decision{Effect: NotApplicable}
+	for _, p := range s.Policies {
+		reason := p.match(req.Action, attributes)
+		matched := reason == ""
+
+		if explain {
+			decision.Trace = append(decision.Trace, Trace{
+				PolicyID: p.ID,
+				Effect:   p.Effect,
+				Matched:  matched,
+				Reason:   reason,
+			})
+		}
+		if !matched || decision.Effect == Deny {
+			continue
+		}
+
+		if p.Effect == Deny || decision.Effect == NotApplicable {
+			decision.Effect = p.Effect
+			decision.PolicyID = p.ID
+		}
+		// Nothing can overturn a deny, the rest only matters for the
+		// trace.
+		if decision.Effect == Deny && !explain {
+			break
+		}
+	}
+
+	return decision
+}
+
+// match returns why p does not apply, or "" when it does.
+func (p *Policy) match(action string, attributes map[string]any) string {
+	if !p.actions.Allows(action) {
+		return "action does not match"
+	}
+	for i, c := range p.Conditions {
+		if !c.holds(attributes) {
+			return fmt.Sprintf("condition %d failed: %s", i+1, c)
+		}
+	}
+	return ""
+}
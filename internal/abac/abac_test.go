@@ -0,0 +1,197 @@
+package abac
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testPolicies = `
+timezone: Asia/Jakarta
+policies:
+  - id: support-read-in-region
+    effect: allow
+    actions: ["user:read"]
+    when:
+      - {attr: subject.role, op: eq, value: support}
+      - {attr: subject.region, op: eq, ref: resource.region}
+  - id: no-reads-from-outside
+    effect: deny
+    actions: ["user:*"]
+    when:
+      - {attr: environment.ip, op: cidr, value: 203.0.113.0/24}
+  - id: admins-everywhere
+    effect: allow
+    actions: ["*"]
+    when:
+      - {attr: subject.role, op: eq, value: admin}
+`
+
+func mustLoad(t *testing.T, data string) *PolicySet {
+	t.Helper()
+
+	set, err := Load([]byte(data), false)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return set
+}
+
+func TestEvaluate(t *testing.T) {
+	set := mustLoad(t, testPolicies)
+
+	request := func(role, ip string) Request {
+		return Request{
+			Subject:     map[string]any{"role": role, "region": "eu"},
+			Resource:    map[string]any{"region": "eu"},
+			Action:      "user:read",
+			Environment: map[string]any{"ip": ip},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		req    Request
+		effect Effect
+		policy string
+	}{
+		{"allow", request("support", "10.0.0.1"), Allow, "support-read-in-region"},
+		{"first allow decides", request("admin", "10.0.0.1"), Allow, "admins-everywhere"},
+		{"deny overrides an earlier allow", request("support", "203.0.113.9"), Deny, "no-reads-from-outside"},
+		{"deny overrides a later allow", request("admin", "203.0.113.9"), Deny, "no-reads-from-outside"},
+		{"no policy matches", request("user", "10.0.0.1"), NotApplicable, ""},
+		{
+			name:   "action outside every allow",
+			req:    Request{Subject: map[string]any{"role": "support"}, Action: "post:read", Environment: map[string]any{"ip": "203.0.113.9"}},
+			effect: NotApplicable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := set.evaluate(tt.req, false)
+			if decision.Effect != tt.effect || decision.PolicyID != tt.policy {
+				t.Fatalf("got %s by %q, want %s by %q", decision.Effect, decision.PolicyID, tt.effect, tt.policy)
+			}
+			if decision.Trace != nil {
+				t.Fatal("Evaluate filled in a trace")
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	set := mustLoad(t, testPolicies)
+
+	decision := set.evaluate(Request{
+		Subject:     map[string]any{"role": "support", "region": "us"},
+		Resource:    map[string]any{"region": "eu"},
+		Action:      "user:read",
+		Environment: map[string]any{"ip": "203.0.113.9"},
+	}, true)
+
+	if decision.Effect != Deny {
+		t.Fatalf("expected a deny, got %s", decision.Effect)
+	}
+	// The trace goes on past the deny so every policy is explained.
+	if len(decision.Trace) != len(set.Policies) {
+		t.Fatalf("expected %d traces, got %d", len(set.Policies), len(decision.Trace))
+	}
+
+	want := []struct {
+		matched bool
+		reason  string
+	}{
+		{false, "condition 2 failed: subject.region eq resource.region"},
+		{true, ""},
+		{false, "condition 1 failed: subject.role eq admin"},
+	}
+	for i, w := range want {
+		trace := decision.Trace[i]
+		if trace.Matched != w.matched || trace.Reason != w.reason {
+			t.Errorf("trace %d: got %v %q, want %v %q", i, trace.Matched, trace.Reason, w.matched, w.reason)
+		}
+	}
+}
+
+func TestEngineEnvironment(t *testing.T) {
+	e := &Engine{}
+	e.set.Store(mustLoad(t, testPolicies))
+
+	// 02:30 UTC on a Monday is 09:30 in Jakarta.
+	env := e.Environment(time.Date(2026, 3, 9, 2, 30, 0, 0, time.UTC), "10.0.0.1")
+	if env["time_of_day"] != "09:30" || env["hour"] != 9 || env["weekday"] != "monday" || env["date"] != "2026-03-09" {
+		t.Fatalf("unexpected environment %v", env)
+	}
+}
+
+func TestEngineReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	write := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	write(testPolicies)
+
+	e, err := NewEngine(path)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	loaded := e.Policies()
+
+	// A broken file keeps the policies in place.
+	write("policies:\n  - id: broken\n    effect: maybe\n    actions: [\"*\"]\n")
+	if err := e.Reload(); err == nil {
+		t.Fatal("expected the invalid file to be refused")
+	}
+	if e.Policies() != loaded {
+		t.Fatal("the policies changed after a failed reload")
+	}
+
+	// Readers see either the old or the new set, never a mix.
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if n := len(e.Policies().Policies); n != 3 && n != 0 {
+					t.Errorf("saw %d policies", n)
+					return
+				}
+				e.Evaluate(Request{Action: "user:read"})
+			}
+		}()
+	}
+
+	write("policies: []\n")
+	if err := e.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	close(stop)
+	wg.Wait()
+
+	if len(e.Policies().Policies) != 0 {
+		t.Fatal("the new policies were not loaded")
+	}
+}
+
+func TestEngineWithoutFile(t *testing.T) {
+	e, err := NewEngine("")
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	if d := e.Evaluate(Request{Action: "user:read"}); d.Effect != NotApplicable {
+		t.Fatalf("expected NotApplicable, got %s", d.Effect)
+	}
+}
@@ -0,0 +1,105 @@
+package abac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/hierarchy"
+)
+
+// Directory builds the attributes of users and service accounts from
+// Postgres, for use as subject or resource.
+type Directory struct {
+	q     *sqlc.Queries
+	roles *hierarchy.Guard
+}
+
+func NewDirectory(q *sqlc.Queries, roles *hierarchy.Guard) *Directory {
+	return &Directory{q: q, roles: roles}
+}
+
+// User describes a user: id, type ("user"), role, role_id, level,
+// verified and the free form user_attributes under attributes.
+func (d *Directory) User(ctx context.Context, user *sqlc.User) (map[string]any, error) {
+	attributes, err := d.UserAttributes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	described := map[string]any{
+		"id":         user.ID,
+		"type":       "user",
+		"verified":   user.Verified,
+		"attributes": attributes,
+	}
+	if user.RoleID.Valid {
+		if err := d.describeRole(ctx, described, int64(user.RoleID.Int32)); err != nil {
+			return nil, err
+		}
+	}
+	return described, nil
+}
+
+// UserByID is User for a user that has not been loaded yet, ErrNotFound
+// when there is no such user.
+func (d *Directory) UserByID(ctx context.Context, id int64) (map[string]any, error) {
+	user, err := d.q.GetUserByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return d.User(ctx, &user)
+}
+
+// ServiceAccount describes a service account: id, type
+// ("service_account"), name, role, role_id and level.
+func (d *Directory) ServiceAccount(ctx context.Context, account *sqlc.ServiceAccount) (map[string]any, error) {
+	described := map[string]any{
+		"id":         account.ID,
+		"type":       "service_account",
+		"name":       account.Name,
+		"attributes": map[string]any{},
+	}
+	if err := d.describeRole(ctx, described, int64(account.RoleID)); err != nil {
+		return nil, err
+	}
+	return described, nil
+}
+
+// UserAttributes returns the free form attributes of a user, empty when
+// none were set.
+func (d *Directory) UserAttributes(ctx context.Context, userID int64) (map[string]any, error) {
+	data, err := d.q.GetUserAttributes(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return map[string]any{}, nil
+		}
+		return nil, err
+	}
+
+	attributes := map[string]any{}
+	if err := json.Unmarshal(data, &attributes); err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
+
+func (d *Directory) describeRole(ctx context.Context, described map[string]any, roleID int64) error {
+	role, err := d.roles.Role(ctx, roleID)
+	if err != nil {
+		if errors.Is(err, hierarchy.ErrRoleNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	described["role"] = role.Name
+	described["role_id"] = role.ID
+	described["level"] = role.Level
+	return nil
+}
@@ -0,0 +1,300 @@
+package abac
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Op string
+
+const (
+	OpEq       Op = "eq"
+	OpNe       Op = "ne"
+	OpIn       Op = "in"
+	OpNotIn    Op = "not_in"
+	OpGt       Op = "gt"
+	OpGte      Op = "gte"
+	OpLt       Op = "lt"
+	OpLte      Op = "lte"
+	OpContains Op = "contains"
+	OpExists   Op = "exists"
+	OpCIDR     Op = "cidr"
+)
+
+// roots are the attribute groups a path may start with.
+var roots = []string{"subject", "resource", "action", "environment"}
+
+// Condition compares the attribute at Attr with Value or with the
+// attribute at Ref. A missing attribute fails every operator but exists.
+type Condition struct {
+	Attr  string `json:"attr"`
+	Op    Op     `json:"op"`
+	Value any    `json:"value,omitempty"`
+	Ref   string `json:"ref,omitempty"`
+
+	attr     []string
+	ref      []string
+	networks []*net.IPNet
+}
+
+func compileCondition(fc fileCondition) (Condition, error) {
+	c := Condition{Attr: fc.Attr, Op: Op(fc.Op), Value: normalize(fc.Value), Ref: fc.Ref}
+
+	var err error
+	if c.attr, err = parsePath(fc.Attr); err != nil {
+		return Condition{}, err
+	}
+	if fc.Ref != "" {
+		if fc.Value != nil {
+			return Condition{}, errors.New("value and ref are mutually exclusive")
+		}
+		if c.ref, err = parsePath(fc.Ref); err != nil {
+			return Condition{}, err
+		}
+	}
+
+	switch c.Op {
+	case OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpContains:
+		if c.ref == nil && c.Value == nil {
+			return Condition{}, fmt.Errorf("%s needs a value or a ref", c.Op)
+		}
+	case OpIn, OpNotIn:
+		if c.ref == nil {
+			if _, ok := c.Value.([]any); !ok {
+				return Condition{}, fmt.Errorf("%s needs a list value or a ref", c.Op)
+			}
+		}
+	case OpExists:
+		if c.ref != nil {
+			return Condition{}, errors.New("exists does not take a ref")
+		}
+		if c.Value == nil {
+			c.Value = true
+		}
+		if _, ok := c.Value.(bool); !ok {
+			return Condition{}, errors.New("exists takes true or false")
+		}
+	case OpCIDR:
+		if c.networks, err = parseNetworks(c.Value); err != nil {
+			return Condition{}, err
+		}
+	default:
+		return Condition{}, fmt.Errorf("unknown op %q", fc.Op)
+	}
+
+	return c, nil
+}
+
+func parsePath(path string) ([]string, error) {
+	segments := strings.Split(path, ".")
+	if !slices.Contains(roots, segments[0]) {
+		return nil, fmt.Errorf("attribute %q must start with one of %s", path, strings.Join(roots, ", "))
+	}
+	if slices.Contains(segments, "") {
+		return nil, fmt.Errorf("invalid attribute %q", path)
+	}
+	return segments, nil
+}
+
+// parseNetworks reads the CIDR value, a single network or a list.
+func parseNetworks(value any) ([]*net.IPNet, error) {
+	values, ok := value.([]any)
+	if !ok {
+		values = []any{value}
+	}
+
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, v := range values {
+		s, _ := v.(string)
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("cidr needs networks such as 10.0.0.0/8, got %v", v)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func (c Condition) String() string {
+	if c.ref != nil {
+		return fmt.Sprintf("%s %s %s", c.Attr, c.Op, c.Ref)
+	}
+	return fmt.Sprintf("%s %s %v", c.Attr, c.Op, c.Value)
+}
+
+func (c Condition) holds(attributes map[string]any) bool {
+	left, ok := lookup(attributes, c.attr)
+	if c.Op == OpExists {
+		return ok == c.Value.(bool)
+	}
+	if !ok {
+		return false
+	}
+
+	right := c.Value
+	if c.ref != nil {
+		if right, ok = lookup(attributes, c.ref); !ok {
+			return false
+		}
+	}
+
+	switch c.Op {
+	case OpEq:
+		return equal(left, right)
+	case OpNe:
+		return !equal(left, right)
+	case OpIn:
+		return contains(right, left)
+	case OpNotIn:
+		_, isList := right.([]any)
+		return isList && !contains(right, left)
+	case OpContains:
+		return contains(left, right)
+	case OpGt, OpGte, OpLt, OpLte:
+		order, ok := compare(left, right)
+		if !ok {
+			return false
+		}
+		switch c.Op {
+		case OpGt:
+			return order > 0
+		case OpGte:
+			return order >= 0
+		case OpLt:
+			return order < 0
+		default:
+			return order <= 0
+		}
+	case OpCIDR:
+		s, _ := left.(string)
+		ip := net.ParseIP(s)
+		return ip != nil && slices.ContainsFunc(c.networks, func(n *net.IPNet) bool {
+			return n.Contains(ip)
+		})
+	}
+	return false
+}
+
+func lookup(attributes map[string]any, path []string) (any, bool) {
+	var value any = attributes
+	for _, segment := range path {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[segment]; !ok {
+			return nil, false
+		}
+	}
+	return value, value != nil
+}
+
+// contains reports whether list holds an element equal to v.
+func contains(list, v any) bool {
+	values, ok := normalize(list).([]any)
+	return ok && slices.ContainsFunc(values, func(e any) bool {
+		return equal(e, v)
+	})
+}
+
+func equal(a, b any) bool {
+	order, ok := compare(a, b)
+	if ok {
+		return order == 0
+	}
+
+	x, ok := a.(bool)
+	y, isBool := b.(bool)
+	return ok && isBool && x == y
+}
+
+// compare orders two numbers, two strings or two times. A string compared
+// with a time is parsed as RFC 3339 or as a date.
+func compare(a, b any) (int, bool) {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	if x, ok := a.(time.Time); ok {
+		y, ok := toTime(b, x.Location())
+		return x.Compare(y), ok
+	}
+	if y, ok := b.(time.Time); ok {
+		x, ok := toTime(a, y.Location())
+		return x.Compare(y), ok
+	}
+
+	x, ok := a.(string)
+	y, isString := b.(string)
+	if !ok || !isString {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
+
+func toTime(v any, loc *time.Location) (time.Time, bool) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, true
+		}
+		if t, err := time.ParseInLocation(time.DateOnly, v, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func number(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// normalize turns typed lists, as built in Go, into []any like the ones
+// decoded from YAML and JSON.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case []string:
+		values := make([]any, len(v))
+		for i, s := range v {
+			values[i] = s
+		}
+		return values
+	case []int64:
+		values := make([]any, len(v))
+		for i, n := range v {
+			values[i] = n
+		}
+		return values
+	}
+	return v
+}
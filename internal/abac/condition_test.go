@@ -0,0 +1,130 @@
+package abac
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileCondition(t *testing.T) {
+	tests := []struct {
+		name string
+		cond fileCondition
+		err  string
+	}{
+		{"eq with a value", fileCondition{Attr: "subject.role", Op: "eq", Value: "support"}, ""},
+		{"eq with a ref", fileCondition{Attr: "subject.region", Op: "eq", Ref: "resource.region"}, ""},
+		{"in with a list", fileCondition{Attr: "subject.role", Op: "in", Value: []any{"a", "b"}}, ""},
+		{"not_in with a ref", fileCondition{Attr: "subject.id", Op: "not_in", Ref: "resource.blocked"}, ""},
+		{"exists without a value", fileCondition{Attr: "resource.owner", Op: "exists"}, ""},
+		{"cidr list", fileCondition{Attr: "environment.ip", Op: "cidr", Value: []any{"10.0.0.0/8", "192.168.0.0/16"}}, ""},
+		{"unknown root", fileCondition{Attr: "user.role", Op: "eq", Value: "x"}, "must start with"},
+		{"empty segment", fileCondition{Attr: "subject..role", Op: "eq", Value: "x"}, "invalid attribute"},
+		{"bad ref", fileCondition{Attr: "subject.role", Op: "eq", Ref: "user.role"}, "must start with"},
+		{"value and ref", fileCondition{Attr: "subject.role", Op: "eq", Value: "x", Ref: "resource.role"}, "mutually exclusive"},
+		{"eq without a value", fileCondition{Attr: "subject.role", Op: "eq"}, "needs a value or a ref"},
+		{"in on a non-list", fileCondition{Attr: "subject.role", Op: "in", Value: "support"}, "needs a list"},
+		{"not_in on a non-list", fileCondition{Attr: "subject.role", Op: "not_in", Value: "support"}, "needs a list"},
+		{"exists with a ref", fileCondition{Attr: "subject.role", Op: "exists", Ref: "resource.role"}, "does not take a ref"},
+		{"exists with a string", fileCondition{Attr: "subject.role", Op: "exists", Value: "yes"}, "true or false"},
+		{"cidr with an address", fileCondition{Attr: "environment.ip", Op: "cidr", Value: "10.0.0.1"}, "cidr needs networks"},
+		{"unknown op", fileCondition{Attr: "subject.role", Op: "like", Value: "x"}, "unknown op"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileCondition(tt.cond)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestConditionHolds(t *testing.T) {
+	now := time.Date(2026, 3, 10, 14, 30, 0, 0, time.UTC)
+	attributes := map[string]any{
+		"subject": map[string]any{
+			"id":     int64(7),
+			"role":   "support",
+			"level":  2,
+			"tags":   []string{"emea", "tier2"},
+			"region": "eu",
+			"nil":    nil,
+		},
+		"resource": map[string]any{
+			"region":  "eu",
+			"owner":   int64(7),
+			"blocked": "7",
+			"ids":     []any{int64(1), int64(7)},
+			"created": "2026-03-01",
+		},
+		"action": "user:read",
+		"environment": map[string]any{
+			"time":        now,
+			"time_of_day": "14:30",
+			"ip":          "10.1.2.3",
+		},
+	}
+
+	tests := []struct {
+		name string
+		cond fileCondition
+		want bool
+	}{
+		{"eq string", fileCondition{Attr: "subject.role", Op: "eq", Value: "support"}, true},
+		{"eq numbers of different types", fileCondition{Attr: "subject.id", Op: "eq", Value: 7}, true},
+		{"eq ref", fileCondition{Attr: "subject.region", Op: "eq", Ref: "resource.region"}, true},
+		{"eq missing ref", fileCondition{Attr: "subject.region", Op: "eq", Ref: "resource.missing"}, false},
+		{"eq number and string", fileCondition{Attr: "subject.id", Op: "eq", Value: "7"}, false},
+		{"ne", fileCondition{Attr: "subject.role", Op: "ne", Value: "admin"}, true},
+		{"ne on a missing attribute", fileCondition{Attr: "subject.missing", Op: "ne", Value: "admin"}, false},
+		{"in", fileCondition{Attr: "subject.role", Op: "in", Value: []any{"support", "admin"}}, true},
+		{"in ref", fileCondition{Attr: "subject.id", Op: "in", Ref: "resource.ids"}, true},
+		{"not_in", fileCondition{Attr: "subject.role", Op: "not_in", Value: []any{"admin"}}, true},
+		{"not_in listed", fileCondition{Attr: "subject.role", Op: "not_in", Value: []any{"support"}}, false},
+		// A ref to a scalar is no list at all, so nothing is excluded and
+		// the condition must not hold.
+		{"not_in ref to a non-list", fileCondition{Attr: "subject.id", Op: "not_in", Ref: "resource.blocked"}, false},
+		{"not_in ref to a missing list", fileCondition{Attr: "subject.id", Op: "not_in", Ref: "resource.missing"}, false},
+		{"contains typed list", fileCondition{Attr: "subject.tags", Op: "contains", Value: "emea"}, true},
+		{"contains on a string", fileCondition{Attr: "subject.role", Op: "contains", Value: "sup"}, false},
+		{"gte strings", fileCondition{Attr: "environment.time_of_day", Op: "gte", Value: "09:00"}, true},
+		{"lt strings", fileCondition{Attr: "environment.time_of_day", Op: "lt", Value: "14:00"}, false},
+		{"gt numbers", fileCondition{Attr: "subject.level", Op: "gt", Value: 1}, true},
+		{"lte number and string", fileCondition{Attr: "subject.level", Op: "lte", Value: "3"}, false},
+		{"time after an RFC 3339 string", fileCondition{Attr: "environment.time", Op: "gt", Value: "2026-03-10T14:00:00Z"}, true},
+		{"time before a date string", fileCondition{Attr: "environment.time", Op: "lt", Value: "2026-03-11"}, true},
+		{"date string before a time", fileCondition{Attr: "resource.created", Op: "lt", Ref: "environment.time"}, true},
+		{"time and an unparsable string", fileCondition{Attr: "environment.time", Op: "gt", Value: "yesterday"}, false},
+		{"time and an unparsable string reversed", fileCondition{Attr: "environment.time", Op: "lte", Value: "tomorrow"}, false},
+		{"time and a number", fileCondition{Attr: "environment.time", Op: "gt", Value: 1}, false},
+		{"exists", fileCondition{Attr: "resource.owner", Op: "exists"}, true},
+		{"exists missing", fileCondition{Attr: "resource.missing", Op: "exists"}, false},
+		{"exists nil", fileCondition{Attr: "subject.nil", Op: "exists"}, false},
+		{"exists false on a missing attribute", fileCondition{Attr: "resource.missing", Op: "exists", Value: false}, true},
+		{"exists false on a present attribute", fileCondition{Attr: "resource.owner", Op: "exists", Value: false}, false},
+		{"exists below a scalar", fileCondition{Attr: "subject.role.name", Op: "exists"}, false},
+		{"cidr", fileCondition{Attr: "environment.ip", Op: "cidr", Value: "10.0.0.0/8"}, true},
+		{"cidr outside", fileCondition{Attr: "environment.ip", Op: "cidr", Value: []any{"192.168.0.0/16"}}, false},
+		{"cidr on a non-address", fileCondition{Attr: "subject.role", Op: "cidr", Value: "10.0.0.0/8"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := compileCondition(tt.cond)
+			if err != nil {
+				t.Fatalf("compileCondition: %v", err)
+			}
+			if got := c.holds(attributes); got != tt.want {
+				t.Fatalf("%s = %v, want %v", c, got, tt.want)
+			}
+		})
+	}
+}
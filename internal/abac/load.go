@@ -0,0 +1,173 @@
+package abac
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mifaabiyyu/backend-go/internal/permission"
+	"gopkg.in/yaml.v3"
+)
+
+// PolicySet is the content of one policy file.
+type PolicySet struct {
+	Timezone string         `json:"timezone"`
+	Location *time.Location `json:"-"`
+	LoadedAt time.Time      `json:"loaded_at"`
+	Policies []*Policy      `json:"policies"`
+}
+
+type Policy struct {
+	ID          string      `json:"id"`
+	Description string      `json:"description,omitempty"`
+	Effect      Effect      `json:"effect"`
+	Actions     []string    `json:"actions"`
+	Conditions  []Condition `json:"when,omitempty"`
+
+	actions *permission.Set
+}
+
+// file mirrors the policy file, see Load.
+type file struct {
+	Timezone string       `json:"timezone" yaml:"timezone"`
+	Policies []filePolicy `json:"policies" yaml:"policies"`
+}
+
+type filePolicy struct {
+	ID          string          `json:"id" yaml:"id"`
+	Description string          `json:"description" yaml:"description"`
+	Effect      string          `json:"effect" yaml:"effect"`
+	Actions     []string        `json:"actions" yaml:"actions"`
+	When        []fileCondition `json:"when" yaml:"when"`
+}
+
+type fileCondition struct {
+	Attr  string `json:"attr" yaml:"attr"`
+	Op    string `json:"op" yaml:"op"`
+	Value any    `json:"value" yaml:"value"`
+	Ref   string `json:"ref" yaml:"ref"`
+}
+
+// LoadFile reads a policy file, YAML unless its extension is .json.
+func LoadFile(path string) (*PolicySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	set, err := Load(data, strings.EqualFold(filepath.Ext(path), ".json"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return set, nil
+}
+
+// Load parses and checks policies. Unknown fields are refused so that a
+// typo cannot silently drop a condition. A YAML example:
+//
+//	timezone: Asia/Jakarta
+//	policies:
+//	  - id: support-read-users-in-region
+//	    effect: allow
+//	    actions: ["user:read"]
+//	    when:
+//	      - {attr: subject.role, op: eq, value: support}
+//	      - {attr: subject.attributes.region, op: eq, ref: resource.attributes.region}
+//	      - {attr: environment.weekday, op: in, value: [monday, tuesday, wednesday, thursday, friday]}
+//	      - {attr: environment.time_of_day, op: gte, value: "09:00"}
+//	      - {attr: environment.time_of_day, op: lt, value: "17:00"}
+//
+// Every condition of a policy has to hold. A condition compares the
+// attribute at attr with either a literal value or the attribute at ref.
+func Load(data []byte, isJSON bool) (*PolicySet, error) {
+	var f file
+	if isJSON {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&f); err != nil {
+			return nil, err
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+	}
+
+	if f.Timezone == "" {
+		f.Timezone = time.UTC.String()
+	}
+	location, err := time.LoadLocation(f.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", f.Timezone, err)
+	}
+
+	set := &PolicySet{
+		Timezone: f.Timezone,
+		Location: location,
+		LoadedAt: time.Now(),
+		Policies: make([]*Policy, 0, len(f.Policies)),
+	}
+
+	seen := make(map[string]bool, len(f.Policies))
+	for i, fp := range f.Policies {
+		p, err := compilePolicy(fp)
+		if err != nil {
+			return nil, fmt.Errorf("policy %d (%s): %w", i+1, fp.ID, err)
+		}
+		if seen[p.ID] {
+			return nil, fmt.Errorf("policy %d: duplicate id %q", i+1, p.ID)
+		}
+		seen[p.ID] = true
+		set.Policies = append(set.Policies, p)
+	}
+
+	return set, nil
+}
+
+func compilePolicy(fp filePolicy) (*Policy, error) {
+	if fp.ID == "" {
+		return nil, errors.New("id is required")
+	}
+
+	effect := Effect(fp.Effect)
+	if effect != Allow && effect != Deny {
+		return nil, fmt.Errorf("effect must be %q or %q", Allow, Deny)
+	}
+
+	if len(fp.Actions) == 0 {
+		return nil, errors.New("at least one action is required")
+	}
+	for _, action := range fp.Actions {
+		// Denying is the job of the policy effect, not of the pattern.
+		if !permission.Valid(action) || strings.HasPrefix(action, "!") {
+			return nil, fmt.Errorf("invalid action %q", action)
+		}
+	}
+
+	p := &Policy{
+		ID:          fp.ID,
+		Description: fp.Description,
+		Effect:      effect,
+		Actions:     fp.Actions,
+		Conditions:  make([]Condition, 0, len(fp.When)),
+		actions:     permission.Compile(fp.Actions),
+	}
+
+	for i, fc := range fp.When {
+		c, err := compileCondition(fc)
+		if err != nil {
+			return nil, fmt.Errorf("condition %d: %w", i+1, err)
+		}
+		p.Conditions = append(p.Conditions, c)
+	}
+
+	return p, nil
+}
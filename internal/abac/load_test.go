@@ -0,0 +1,88 @@
+package abac
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		isJSON bool
+		err    string
+	}{
+		{name: "empty YAML"},
+		{name: "empty JSON", data: `{}`, isJSON: true},
+		{
+			name:   "JSON policy",
+			data:   `{"policies": [{"id": "p", "effect": "allow", "actions": ["user:read"], "when": [{"attr": "subject.role", "op": "eq", "value": "support"}]}]}`,
+			isJSON: true,
+		},
+		{name: "unknown YAML field", data: "policies:\n  - id: p\n    effect: allow\n    actions: [\"*\"]\n    condition: []\n", err: "field condition not found"},
+		{name: "unknown JSON field", data: `{"policies": [{"id": "p", "effect": "allow", "actions": ["*"], "where": []}]}`, isJSON: true, err: "unknown field"},
+		{name: "unknown condition field", data: "policies:\n  - id: p\n    effect: allow\n    actions: [\"*\"]\n    when: [{attr: subject.role, op: eq, values: [a]}]\n", err: "field values not found"},
+		{name: "malformed YAML", data: "policies: [", err: "yaml"},
+		{name: "malformed JSON", data: `{"policies": [`, isJSON: true, err: "unexpected EOF"},
+		{name: "JSON given as YAML flag", data: "timezone: UTC\n", isJSON: true, err: "invalid character"},
+		{name: "unknown timezone", data: "timezone: Mars/Olympus\n", err: "invalid timezone"},
+		{name: "missing id", data: "policies:\n  - effect: allow\n    actions: [\"*\"]\n", err: "id is required"},
+		{name: "bad effect", data: "policies:\n  - id: p\n    effect: maybe\n    actions: [\"*\"]\n", err: "effect must be"},
+		{name: "no actions", data: "policies:\n  - id: p\n    effect: allow\n", err: "at least one action"},
+		{name: "deny pattern as action", data: "policies:\n  - id: p\n    effect: allow\n    actions: [\"!user:read\"]\n", err: "invalid action"},
+		{name: "invalid action", data: "policies:\n  - id: p\n    effect: allow\n    actions: [\"user::read\"]\n", err: "invalid action"},
+		{name: "bad condition", data: "policies:\n  - id: p\n    effect: allow\n    actions: [\"*\"]\n    when: [{attr: subject.role, op: in, value: support}]\n", err: "policy 1 (p): condition 1: in needs a list"},
+		{
+			name: "duplicate id",
+			data: "policies:\n  - {id: p, effect: allow, actions: [\"*\"]}\n  - {id: p, effect: deny, actions: [\"*\"]}\n",
+			err:  `policy 2: duplicate id "p"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load([]byte(tt.data), tt.isJSON)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	// The extension picks the format, case insensitively.
+	jsonPath := filepath.Join(dir, "policies.JSON")
+	if err := os.WriteFile(jsonPath, []byte(`{"timezone": "Asia/Jakarta", "policies": [{"id": "p", "effect": "deny", "actions": ["user:*"]}]}`), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	set, err := LoadFile(jsonPath)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if set.Location.String() != "Asia/Jakarta" || len(set.Policies) != 1 || set.Policies[0].Effect != Deny {
+		t.Fatalf("unexpected policies %+v", set)
+	}
+
+	// The error names the file.
+	yamlPath := filepath.Join(dir, "policies.yml")
+	if err := os.WriteFile(yamlPath, []byte("policies:\n  - id: p\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := LoadFile(yamlPath); err == nil || !strings.HasPrefix(err.Error(), yamlPath+": ") {
+		t.Fatalf("expected an error naming %s, got %v", yamlPath, err)
+	}
+
+	if _, err := LoadFile(filepath.Join(dir, "missing.yaml")); !os.IsNotExist(err) {
+		t.Fatalf("expected a not exist error, got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+)
+
+type contextKey string
+
+const (
+	claimsCtx contextKey = "claims"
+	userCtx   contextKey = "user"
+)
+
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsCtx, claims)
+}
+
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsCtx).(*Claims)
+	return claims, ok
+}
+
+// WithUser stores the authenticated user, as loaded by the auth middleware
+// for every kind of bearer token.
+func WithUser(ctx context.Context, user *sqlc.User) context.Context {
+	return context.WithValue(ctx, userCtx, user)
+}
+
+func UserFromContext(ctx context.Context) (*sqlc.User, bool) {
+	user, ok := ctx.Value(userCtx).(*sqlc.User)
+	return user, ok && user != nil
+}
+
+const scopesCtx contextKey = "scopes"
+
+// WithScopes marks the request as made with a personal access token limited
+// to scopes.
+func WithScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, scopesCtx, scopes)
+}
+
+// ScopesFromContext returns the scopes of the personal access token used
+// for the request. ok is false for requests authenticated otherwise, which
+// are limited by their role only.
+func ScopesFromContext(ctx context.Context) (scopes []string, ok bool) {
+	scopes, ok = ctx.Value(scopesCtx).([]string)
+	return scopes, ok
+}
@@ -1,7 +1,10 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -10,21 +13,57 @@ type JWTAuthenticator struct {
 	secret string
 	aud    string
 	iss    string
+	leeway time.Duration
 }
 
+// PurposeMFA marks the short-lived token handed out after a correct password
+// when the account still has to pass its second factor. Tokens with a
+// purpose are never accepted as access tokens.
+const PurposeMFA = "mfa"
+
 type Claims struct {
 	UserID int64 `json:"user_id"`
 	RoleID int32 `json:"role_id"`
+	// TokenVersion must match users.token_version, bumping the column
+	// invalidates every token issued before.
+	TokenVersion int32  `json:"ver"`
+	Purpose      string `json:"pur,omitempty"`
+	// SessionID is the sessions row the token was issued for, revoking the
+	// session rejects the token before it expires.
+	SessionID string `json:"sid,omitempty"`
+	// Actor is set on impersonation tokens: the token acts as UserID but
+	// was obtained by Actor.
+	Actor *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// Implementasi interface `jwt.Claims`
-func (c Claims) Valid() error {
+// Actor is the act claim of RFC 8693, the party really making requests
+// with a token issued for someone else.
+type Actor struct {
+	Subject string `json:"sub"`
+	UserID  int64  `json:"user_id"`
+}
+
+// Validate implements jwt.ClaimsValidator. It runs after the registered
+// claims (exp, nbf, iat, iss, aud) have been checked by the parser.
+func (c Claims) Validate() error {
+	if c.UserID <= 0 {
+		return errors.New("token has no user")
+	}
+
+	if c.Subject != "" && c.Subject != strconv.FormatInt(c.UserID, 10) {
+		return errors.New("token subject does not match user")
+	}
+
+	if c.Actor != nil && (c.Actor.UserID <= 0 || c.Actor.UserID == c.UserID) {
+		return errors.New("token has an invalid actor")
+	}
+
 	return nil
 }
 
-func NewJWTAuthenticator(secret, aud, iss string) *JWTAuthenticator {
-	return &JWTAuthenticator{secret, iss, aud}
+func NewJWTAuthenticator(secret, aud, iss string, leeway time.Duration) *JWTAuthenticator {
+	return &JWTAuthenticator{secret: secret, aud: aud, iss: iss, leeway: leeway}
 }
 
 func (j *JWTAuthenticator) GenerateToken(c jwt.Claims) (string, error) {
@@ -40,5 +79,18 @@ func (j *JWTAuthenticator) GenerateToken(c jwt.Claims) (string, error) {
 func (a *JWTAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
 	return jwt.ParseWithClaims(token, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(a.secret), nil
-	})
+	}, parserOptions(a.aud, a.iss, a.leeway, jwt.SigningMethodHS256.Alg())...)
+}
+
+// parserOptions makes the parser enforce the signing algorithm, issuer,
+// audience and expiry. Tokens without an exp claim are rejected.
+func parserOptions(aud, iss string, leeway time.Duration, methods ...string) []jwt.ParserOption {
+	return []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithIssuer(iss),
+		jwt.WithAudience(aud),
+		jwt.WithLeeway(leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
 }
@@ -0,0 +1,354 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testAud    = "backend-test"
+	testIss    = "backend-test-issuer"
+	testSecret = "0123456789abcdef0123456789abcdef"
+	testLeeway = time.Second * 30
+)
+
+func validClaims() *Claims {
+	now := time.Now()
+	return &Claims{
+		UserID: 42,
+		RoleID: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "42",
+			Issuer:    testIss,
+			Audience:  jwt.ClaimStrings{testAud},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute * 15)),
+		},
+	}
+}
+
+// withClaims returns valid claims changed by fn.
+func withClaims(fn func(c *Claims)) *Claims {
+	c := validClaims()
+	fn(c)
+	return c
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, claims jwt.Claims, header map[string]any) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	for k, v := range header {
+		token.Header[k] = v
+	}
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return signed
+}
+
+// tamper changes the payload of a token while keeping its signature.
+func tamper(t *testing.T, token string) string {
+	t.Helper()
+
+	forged := withClaims(func(c *Claims) {
+		c.UserID = 1
+		c.Subject = "1"
+	})
+	other := sign(t, jwt.SigningMethodHS256, []byte("another secret"), forged, nil)
+
+	parts := strings.Split(token, ".")
+	parts[1] = strings.Split(other, ".")[1]
+	return strings.Join(parts, ".")
+}
+
+func TestJWTAuthenticatorValidateToken(t *testing.T) {
+	a := NewJWTAuthenticator(testSecret, testAud, testIss, testLeeway)
+
+	valid, err := a.GenerateToken(validClaims())
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{
+			name:  "valid",
+			token: valid,
+		},
+		{
+			name:    "tampered payload",
+			token:   tamper(t, valid),
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name:    "wrong secret",
+			token:   sign(t, jwt.SigningMethodHS256, []byte("wrong secret"), validClaims(), nil),
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name: "expired",
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), withClaims(func(c *Claims) {
+				c.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+				c.NotBefore = c.IssuedAt
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			}), nil),
+			wantErr: jwt.ErrTokenExpired,
+		},
+		{
+			name: "expired within leeway",
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), withClaims(func(c *Claims) {
+				c.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+				c.NotBefore = c.IssuedAt
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Second * 10))
+			}), nil),
+		},
+		{
+			name: "missing exp",
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), withClaims(func(c *Claims) {
+				c.ExpiresAt = nil
+			}), nil),
+			wantErr: jwt.ErrTokenRequiredClaimMissing,
+		},
+		{
+			name: "not yet valid",
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), withClaims(func(c *Claims) {
+				c.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Minute * 5))
+			}), nil),
+			wantErr: jwt.ErrTokenNotValidYet,
+		},
+		{
+			name: "issued in the future",
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), withClaims(func(c *Claims) {
+				c.IssuedAt = jwt.NewNumericDate(time.Now().Add(time.Minute * 5))
+			}), nil),
+			wantErr: jwt.ErrTokenUsedBeforeIssued,
+		},
+		{
+			name: "wrong issuer",
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), withClaims(func(c *Claims) {
+				c.Issuer = "someone-else"
+			}), nil),
+			wantErr: jwt.ErrTokenInvalidIssuer,
+		},
+		{
+			name: "wrong audience",
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), withClaims(func(c *Claims) {
+				c.Audience = jwt.ClaimStrings{"another-service"}
+			}), nil),
+			wantErr: jwt.ErrTokenInvalidAudience,
+		},
+		{
+			name:    "alg mismatch",
+			token:   sign(t, jwt.SigningMethodHS512, []byte(testSecret), validClaims(), nil),
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name:    "alg none",
+			token:   sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims(), nil),
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name: "subject does not match user",
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), withClaims(func(c *Claims) {
+				c.Subject = "7"
+			}), nil),
+			wantErr: jwt.ErrTokenInvalidClaims,
+		},
+		{
+			name:    "malformed",
+			token:   "not.a.token",
+			wantErr: jwt.ErrTokenMalformed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := a.ValidateToken(tt.token)
+			checkValidation(t, token, err, tt.wantErr)
+		})
+	}
+}
+
+func TestKeySetAuthenticatorValidateToken(t *testing.T) {
+	dir := t.TempDir()
+
+	_, signingKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, retiredKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, unknownKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	signingPath := writeKey(t, dir, "signing.pem", signingKey)
+	retiredPath := writeKey(t, dir, "retired.pem", retiredKey)
+
+	a, err := NewKeySetAuthenticator(testAud, testIss, testLeeway, signingPath, retiredPath)
+	if err != nil {
+		t.Fatalf("NewKeySetAuthenticator: %v", err)
+	}
+
+	valid, err := a.GenerateToken(validClaims())
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	kid := func(key ed25519.PrivateKey) string {
+		jwk, err := NewJWK(key.Public())
+		if err != nil {
+			t.Fatalf("NewJWK: %v", err)
+		}
+		return jwk.Kid
+	}
+	signingKid := kid(signingKey)
+	signed := func(key ed25519.PrivateKey, claims jwt.Claims) string {
+		return sign(t, jwt.SigningMethodEdDSA, key, claims, map[string]any{"kid": kid(key)})
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{
+			name:  "valid",
+			token: valid,
+		},
+		{
+			name:  "signed by a verification-only key",
+			token: signed(retiredKey, validClaims()),
+		},
+		{
+			name:    "tampered payload",
+			token:   tamper(t, valid),
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name:    "unknown kid",
+			token:   signed(unknownKey, validClaims()),
+			wantErr: jwt.ErrTokenUnverifiable,
+		},
+		{
+			name:    "kid of a known key, signed by another",
+			token:   sign(t, jwt.SigningMethodEdDSA, unknownKey, validClaims(), map[string]any{"kid": signingKid}),
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name:    "missing kid",
+			token:   sign(t, jwt.SigningMethodEdDSA, signingKey, validClaims(), nil),
+			wantErr: jwt.ErrTokenUnverifiable,
+		},
+		{
+			// The classic confusion attack, HMAC keyed with the public
+			// key.
+			name:    "alg mismatch",
+			token:   sign(t, jwt.SigningMethodHS256, []byte(signingKey.Public().(ed25519.PublicKey)), validClaims(), map[string]any{"kid": signingKid}),
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name:    "alg none",
+			token:   sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims(), map[string]any{"kid": signingKid}),
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name: "expired",
+			token: signed(signingKey, withClaims(func(c *Claims) {
+				c.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+				c.NotBefore = c.IssuedAt
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			})),
+			wantErr: jwt.ErrTokenExpired,
+		},
+		{
+			name: "not yet valid",
+			token: signed(signingKey, withClaims(func(c *Claims) {
+				c.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Minute * 5))
+			})),
+			wantErr: jwt.ErrTokenNotValidYet,
+		},
+		{
+			name: "wrong issuer",
+			token: signed(signingKey, withClaims(func(c *Claims) {
+				c.Issuer = "someone-else"
+			})),
+			wantErr: jwt.ErrTokenInvalidIssuer,
+		},
+		{
+			name: "wrong audience",
+			token: signed(signingKey, withClaims(func(c *Claims) {
+				c.Audience = jwt.ClaimStrings{"another-service"}
+			})),
+			wantErr: jwt.ErrTokenInvalidAudience,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := a.ValidateToken(tt.token)
+			checkValidation(t, token, err, tt.wantErr)
+		})
+	}
+}
+
+func TestKeySetAuthenticatorJWKS(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	a, err := NewKeySetAuthenticator(testAud, testIss, testLeeway, writeKey(t, t.TempDir(), "signing.pem", key))
+	if err != nil {
+		t.Fatalf("NewKeySetAuthenticator: %v", err)
+	}
+
+	jwks := a.JWKS()
+	if len(jwks.Keys) != 1 || jwks.Keys[0].Kty != "OKP" || jwks.Keys[0].Kid == "" {
+		t.Fatalf("unexpected JWKS: %+v", jwks)
+	}
+}
+
+func checkValidation(t *testing.T, token *jwt.Token, err, wantErr error) {
+	t.Helper()
+
+	if wantErr == nil {
+		if err != nil {
+			t.Fatalf("expected a valid token, got %v", err)
+		}
+		if !token.Valid {
+			t.Fatal("token is not marked valid")
+		}
+		return
+	}
+
+	if err == nil {
+		t.Fatalf("expected %v, token was accepted", wantErr)
+	}
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("expected %v, got %v", wantErr, err)
+	}
+}
+
+func writeKey(t *testing.T, dir, name string, key crypto.Signer) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return path
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const minRSAKeyBits = 2048
+
+// JWK is the public part of a signing key as published in a JWKS document
+// (RFC 7517). Only RSA and Ed25519 (OKP) keys are supported.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeyPublisher is implemented by authenticators whose verification keys can
+// be shared with other services.
+type KeyPublisher interface {
+	JWKS() JWKSet
+}
+
+// LoadPrivateKey reads an RSA or Ed25519 private key from a PEM file, either
+// PKCS#8 ("PRIVATE KEY") or PKCS#1 ("RSA PRIVATE KEY").
+func LoadPrivateKey(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePrivateKey(block)
+}
+
+// LoadPublicKey reads an RSA or Ed25519 public key from a PEM file. Private
+// key files are accepted too, their public half is returned.
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return key, checkPublicKey(key)
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return key, checkPublicKey(key)
+	default:
+		signer, err := parsePrivateKey(block)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return signer.Public(), nil
+	}
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+
+	return block, nil
+}
+
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	var key any
+	var err error
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("key cannot be used for signing")
+	}
+
+	return signer, checkPublicKey(signer.Public())
+}
+
+func checkPublicKey(key crypto.PublicKey) error {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return fmt.Errorf("rsa key must be at least %d bits", minRSAKeyBits)
+		}
+		return nil
+	case ed25519.PublicKey:
+		return nil
+	default:
+		return fmt.Errorf("unsupported key type %T, only RSA and Ed25519 are supported", key)
+	}
+}
+
+func signingMethodFor(key crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// NewJWK describes key as a JWK whose kid is the RFC 7638 thumbprint of the
+// key, so the same key always gets the same kid.
+func NewJWK(key crypto.PublicKey) (JWK, error) {
+	method, err := signingMethodFor(key)
+	if err != nil {
+		return JWK{}, err
+	}
+
+	var jwk JWK
+	var thumbprintInput any
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		jwk = JWK{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}
+		// Members must be in lexicographic order for the thumbprint.
+		thumbprintInput = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case ed25519.PublicKey:
+		jwk = JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(k),
+		}
+		thumbprintInput = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+
+	raw, err := json.Marshal(thumbprintInput)
+	if err != nil {
+		return JWK{}, err
+	}
+	sum := sha256.Sum256(raw)
+
+	jwk.Kid = base64.RawURLEncoding.EncodeToString(sum[:])
+	jwk.Use = "sig"
+	jwk.Alg = method.Alg()
+
+	return jwk, nil
+}
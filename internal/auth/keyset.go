@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"crypto"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type verificationKey struct {
+	jwk    JWK
+	method jwt.SigningMethod
+	key    crypto.PublicKey
+}
+
+// KeySetAuthenticator signs tokens with an RSA (RS256) or Ed25519 (EdDSA)
+// private key and stamps its kid in the token header. Tokens are verified
+// against every configured key, so a key that is being rotated out keeps
+// validating the tokens it signed until they expire.
+type KeySetAuthenticator struct {
+	kid       string
+	method    jwt.SigningMethod
+	signer    crypto.Signer
+	verifiers map[string]verificationKey
+	jwks      JWKSet
+	aud       string
+	iss       string
+	leeway    time.Duration
+}
+
+// NewKeySetAuthenticator loads the active signing key from signingKeyPath
+// and any additional verification-only keys from verifyKeyPaths.
+func NewKeySetAuthenticator(aud, iss string, leeway time.Duration, signingKeyPath string, verifyKeyPaths ...string) (*KeySetAuthenticator, error) {
+	signer, err := LoadPrivateKey(signingKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("signing key: %w", err)
+	}
+
+	a := &KeySetAuthenticator{
+		signer:    signer,
+		verifiers: make(map[string]verificationKey),
+		jwks:      JWKSet{Keys: []JWK{}},
+		aud:       aud,
+		iss:       iss,
+		leeway:    leeway,
+	}
+
+	if err := a.addVerificationKey(signer.Public()); err != nil {
+		return nil, err
+	}
+
+	// The signing key is always the first one added.
+	a.kid = a.jwks.Keys[0].Kid
+	a.method = a.verifiers[a.kid].method
+
+	for _, path := range verifyKeyPaths {
+		key, err := LoadPublicKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("verification key: %w", err)
+		}
+
+		if err := a.addVerificationKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+func (a *KeySetAuthenticator) addVerificationKey(key crypto.PublicKey) error {
+	jwk, err := NewJWK(key)
+	if err != nil {
+		return err
+	}
+
+	if _, exists := a.verifiers[jwk.Kid]; exists {
+		return nil
+	}
+
+	method, err := signingMethodFor(key)
+	if err != nil {
+		return err
+	}
+
+	a.verifiers[jwk.Kid] = verificationKey{jwk: jwk, method: method, key: key}
+	a.jwks.Keys = append(a.jwks.Keys, jwk)
+
+	return nil
+}
+
+func (a *KeySetAuthenticator) GenerateToken(c jwt.Claims) (string, error) {
+	claims, ok := c.(*Claims)
+	if !ok {
+		return "", fmt.Errorf("invalid claims type")
+	}
+
+	token := jwt.NewWithClaims(a.method, claims)
+	token.Header["kid"] = a.kid
+
+	return token.SignedString(a.signer)
+}
+
+func (a *KeySetAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(token, &Claims{}, func(t *jwt.Token) (interface{}, error) {
+		kid, ok := t.Header["kid"].(string)
+		if !ok {
+			return nil, fmt.Errorf("token has no kid header")
+		}
+
+		verifier, ok := a.verifiers[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+
+		// The algorithm has to match the key, never trust the header alone.
+		if t.Method.Alg() != verifier.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %q", t.Method.Alg())
+		}
+
+		return verifier.key, nil
+	}, parserOptions(a.aud, a.iss, a.leeway, jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg())...)
+}
+
+// JWKS returns the public keys tokens may be signed with.
+func (a *KeySetAuthenticator) JWKS() JWKSet {
+	return a.jwks
+}
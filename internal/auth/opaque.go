@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+const opaqueTokenBytes = 32
+
+// GenerateOpaqueToken returns a random URL-safe token and the hash that
+// should be persisted instead of the token itself.
+func GenerateOpaqueToken() (plain string, hash string, err error) {
+	b := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	plain = base64.RawURLEncoding.EncodeToString(b)
+	return plain, HashOpaqueToken(plain), nil
+}
+
+func HashOpaqueToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
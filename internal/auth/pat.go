@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// PersonalAccessTokenPrefix starts every personal access token, it tells
+// them apart from JWTs and makes leaked tokens easy to grep for.
+const PersonalAccessTokenPrefix = "pat_"
+
+const patLookupBytes = 6
+
+// GeneratePersonalAccessToken returns a new token of the form
+// pat_<lookup>_<secret>. lookup is stored in clear to find the row, only
+// hash is stored for the full token.
+func GeneratePersonalAccessToken() (plain, lookup, hash string, err error) {
+	id := make([]byte, patLookupBytes)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", "", err
+	}
+
+	secret := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	lookup = PersonalAccessTokenPrefix + hex.EncodeToString(id)
+	plain = lookup + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	return plain, lookup, HashOpaqueToken(plain), nil
+}
+
+// IsPersonalAccessToken reports whether token looks like a personal access
+// token rather than a JWT.
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+// ParsePersonalAccessToken returns the lookup part of token.
+func ParsePersonalAccessToken(token string) (lookup string, ok bool) {
+	rest, found := strings.CutPrefix(token, PersonalAccessTokenPrefix)
+	if !found {
+		return "", false
+	}
+
+	// The lookup is hex, so the first underscore ends it even though the
+	// base64url secret may contain more.
+	id, secret, found := strings.Cut(rest, "_")
+	if !found || len(id) != patLookupBytes*2 || secret == "" {
+		return "", false
+	}
+
+	return PersonalAccessTokenPrefix + id, true
+}
+
+// VerifyPersonalAccessToken compares token with the stored hash in
+// constant time.
+func VerifyPersonalAccessToken(token, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashOpaqueToken(token)), []byte(hash)) == 1
+}
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// RevocationStore keeps the IDs (jti) of tokens that were revoked before
+// they expired. Entries only need to live until expiresAt.
+type RevocationStore interface {
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// WithLeeway extends every revocation stored in store by leeway. Tokens
+// keep validating for the authenticator's leeway past exp, so their
+// revocation has to outlive exp by as much.
+func WithLeeway(store RevocationStore, leeway time.Duration) RevocationStore {
+	if leeway <= 0 {
+		return store
+	}
+	return &leewayRevocations{RevocationStore: store, leeway: leeway}
+}
+
+type leewayRevocations struct {
+	RevocationStore
+	leeway time.Duration
+}
+
+func (r *leewayRevocations) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	return r.RevocationStore.Revoke(ctx, jti, expiresAt.Add(r.leeway))
+}
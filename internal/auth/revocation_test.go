@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// expiringRevocations drops entries once their expiresAt has passed, like
+// the Redis TTL and the Postgres cleanup do.
+type expiringRevocations struct {
+	now     time.Time
+	entries map[string]time.Time
+}
+
+func (r *expiringRevocations) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	r.entries[jti] = expiresAt
+	return nil
+}
+
+func (r *expiringRevocations) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	expiresAt, ok := r.entries[jti]
+	return ok && r.now.Before(expiresAt), nil
+}
+
+func TestWithLeeway(t *testing.T) {
+	a := NewJWTAuthenticator(testSecret, testAud, testIss, testLeeway)
+
+	claims := validClaims()
+	claims.ID = "revoked-jti"
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-testLeeway / 2))
+
+	token, err := a.GenerateToken(claims)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if _, err := a.ValidateToken(token); err != nil {
+		t.Fatalf("a token within the leeway should still validate: %v", err)
+	}
+
+	inner := &expiringRevocations{now: time.Now(), entries: make(map[string]time.Time)}
+	store := WithLeeway(inner, testLeeway)
+
+	if err := store.Revoke(context.Background(), claims.ID, claims.ExpiresAt.Time); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	revoked, err := store.IsRevoked(context.Background(), claims.ID)
+	if err != nil {
+		t.Fatalf("IsRevoked: %v", err)
+	}
+	if !revoked {
+		t.Fatal("the revocation expired while the token still validates")
+	}
+
+	if got, want := inner.entries[claims.ID], claims.ExpiresAt.Time.Add(testLeeway); !got.Equal(want) {
+		t.Fatalf("stored expiry %v, want %v", got, want)
+	}
+
+	if WithLeeway(inner, 0) != RevocationStore(inner) {
+		t.Fatal("a zero leeway should not wrap the store")
+	}
+}
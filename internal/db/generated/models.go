@@ -8,6 +8,107 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type AccountUnlock struct {
+	ID        int64              `json:"id"`
+	UserID    int64              `json:"user_id"`
+	TokenHash string             `json:"token_hash"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type DataExport struct {
+	ID          int64              `json:"id"`
+	UserID      int64              `json:"user_id"`
+	Status      string             `json:"status"`
+	Archive     []byte             `json:"archive"`
+	Error       string             `json:"error"`
+	StartedAt   pgtype.Timestamptz `json:"started_at"`
+	CompletedAt pgtype.Timestamptz `json:"completed_at"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type EmailChange struct {
+	ID        int64              `json:"id"`
+	UserID    int64              `json:"user_id"`
+	NewEmail  string             `json:"new_email"`
+	TokenHash string             `json:"token_hash"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type ErasureRequest struct {
+	ID           int64              `json:"id"`
+	UserID       int64              `json:"user_id"`
+	ScheduledFor pgtype.Timestamptz `json:"scheduled_for"`
+	CancelledAt  pgtype.Timestamptz `json:"cancelled_at"`
+	CompletedAt  pgtype.Timestamptz `json:"completed_at"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
+type Impersonation struct {
+	ID        int64              `json:"id"`
+	ActorID   int64              `json:"actor_id"`
+	UserID    int64              `json:"user_id"`
+	TokenID   string             `json:"token_id"`
+	Reason    string             `json:"reason"`
+	Ip        string             `json:"ip"`
+	UserAgent string             `json:"user_agent"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type ImpersonationRequest struct {
+	ID        int64              `json:"id"`
+	TokenID   string             `json:"token_id"`
+	ActorID   int64              `json:"actor_id"`
+	UserID    int64              `json:"user_id"`
+	Method    string             `json:"method"`
+	Path      string             `json:"path"`
+	Status    pgtype.Int4        `json:"status"`
+	Ip        string             `json:"ip"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type MagicLink struct {
+	ID        int64              `json:"id"`
+	UserID    int64              `json:"user_id"`
+	Email     string             `json:"email"`
+	TokenHash string             `json:"token_hash"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type MfaRecoveryCode struct {
+	ID        int64              `json:"id"`
+	UserID    int64              `json:"user_id"`
+	CodeHash  string             `json:"code_hash"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type OauthState struct {
+	StateHash    string             `json:"state_hash"`
+	Provider     string             `json:"provider"`
+	Nonce        string             `json:"nonce"`
+	CodeVerifier string             `json:"code_verifier"`
+	ExpiresAt    pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UserID       pgtype.Int8        `json:"user_id"`
+}
+
+type PasswordReset struct {
+	ID        int64              `json:"id"`
+	UserID    int64              `json:"user_id"`
+	TokenHash string             `json:"token_hash"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
 type Permission struct {
 	ID          int64              `json:"id"`
 	Name        string             `json:"name"`
@@ -16,6 +117,19 @@ type Permission struct {
 	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
 }
 
+type PersonalAccessToken struct {
+	ID         int64              `json:"id"`
+	UserID     int64              `json:"user_id"`
+	Name       string             `json:"name"`
+	Prefix     string             `json:"prefix"`
+	TokenHash  string             `json:"token_hash"`
+	Scopes     []string           `json:"scopes"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
 type Post struct {
 	ID        int32              `json:"id"`
 	Title     string             `json:"title"`
@@ -26,6 +140,23 @@ type Post struct {
 	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
 }
 
+type RefreshToken struct {
+	ID        int64              `json:"id"`
+	UserID    int64              `json:"user_id"`
+	TokenHash string             `json:"token_hash"`
+	FamilyID  string             `json:"family_id"`
+	ParentID  pgtype.Int8        `json:"parent_id"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	RevokedAt pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type RevokedToken struct {
+	Jti       string             `json:"jti"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
 type Role struct {
 	ID          int64              `json:"id"`
 	Name        string             `json:"name"`
@@ -42,15 +173,94 @@ type RolesPermission struct {
 	CreatedAt    pgtype.Timestamptz `json:"created_at"`
 }
 
-type User struct {
-	ID         int64              `json:"id"`
-	Email      string             `json:"email"`
-	Username   string             `json:"username"`
-	FullName   string             `json:"full_name"`
-	Password   string             `json:"password"`
-	Verified   bool               `json:"verified"`
-	VerifiedAt pgtype.Timestamptz `json:"verified_at"`
+type ServiceAccount struct {
+	ID          int64              `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	ClientID    string             `json:"client_id"`
+	SecretHash  string             `json:"secret_hash"`
+	RoleID      int32              `json:"role_id"`
+	CreatedBy   pgtype.Int8        `json:"created_by"`
+	LastUsedAt  pgtype.Timestamptz `json:"last_used_at"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Session struct {
+	ID         string             `json:"id"`
+	UserID     int64              `json:"user_id"`
+	UserAgent  string             `json:"user_agent"`
+	Ip         string             `json:"ip"`
 	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	LastSeenAt pgtype.Timestamptz `json:"last_seen_at"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+}
+
+type User struct {
+	ID           int64              `json:"id"`
+	Email        string             `json:"email"`
+	Username     string             `json:"username"`
+	FullName     string             `json:"full_name"`
+	Password     string             `json:"password"`
+	Verified     bool               `json:"verified"`
+	VerifiedAt   pgtype.Timestamptz `json:"verified_at"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	RoleID       pgtype.Int4        `json:"role_id"`
+	TokenVersion int32              `json:"token_version"`
+}
+
+type UserAttribute struct {
+	UserID     int64              `json:"user_id"`
+	Attributes []byte             `json:"attributes"`
 	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
-	RoleID     pgtype.Int4        `json:"role_id"`
+}
+
+type UserIdentity struct {
+	ID          int64              `json:"id"`
+	UserID      int64              `json:"user_id"`
+	Provider    string             `json:"provider"`
+	Subject     string             `json:"subject"`
+	Email       string             `json:"email"`
+	LastLoginAt pgtype.Timestamptz `json:"last_login_at"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type UserInvitation struct {
+	TokenHash string             `json:"token_hash"`
+	UserID    int64              `json:"user_id"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type UserMfa struct {
+	UserID       int64              `json:"user_id"`
+	Secret       string             `json:"secret"`
+	Enabled      bool               `json:"enabled"`
+	LastUsedStep int64              `json:"last_used_step"`
+	EnabledAt    pgtype.Timestamptz `json:"enabled_at"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+}
+
+type WebauthnChallenge struct {
+	ChallengeHash string             `json:"challenge_hash"`
+	Ceremony      string             `json:"ceremony"`
+	UserID        pgtype.Int8        `json:"user_id"`
+	ExpiresAt     pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+type WebauthnCredential struct {
+	ID           int64              `json:"id"`
+	UserID       int64              `json:"user_id"`
+	CredentialID []byte             `json:"credential_id"`
+	PublicKey    []byte             `json:"public_key"`
+	SignCount    int64              `json:"sign_count"`
+	Aaguid       []byte             `json:"aaguid"`
+	Transports   []string           `json:"transports"`
+	Name         string             `json:"name"`
+	LastUsedAt   pgtype.Timestamptz `json:"last_used_at"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
 }
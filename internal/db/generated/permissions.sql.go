@@ -7,8 +7,135 @@ package sqlc
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const attachPermissionToRole = `-- name: AttachPermissionToRole :exec
+INSERT INTO roles_permissions (role_id, permission_id)
+VALUES ($1, $2)
+ON CONFLICT (role_id, permission_id) DO NOTHING
+`
+
+type AttachPermissionToRoleParams struct {
+	RoleID       int32 `json:"role_id"`
+	PermissionID int32 `json:"permission_id"`
+}
+
+func (q *Queries) AttachPermissionToRole(ctx context.Context, arg AttachPermissionToRoleParams) error {
+	_, err := q.db.Exec(ctx, attachPermissionToRole, arg.RoleID, arg.PermissionID)
+	return err
+}
+
+const createPermission = `-- name: CreatePermission :one
+INSERT INTO permissions (name, description)
+VALUES ($1, $2)
+RETURNING id, name, description, created_at, updated_at
+`
+
+type CreatePermissionParams struct {
+	Name        string      `json:"name"`
+	Description pgtype.Text `json:"description"`
+}
+
+func (q *Queries) CreatePermission(ctx context.Context, arg CreatePermissionParams) (Permission, error) {
+	row := q.db.QueryRow(ctx, createPermission, arg.Name, arg.Description)
+	var i Permission
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deletePermission = `-- name: DeletePermission :execrows
+DELETE FROM permissions
+WHERE id = $1
+`
+
+func (q *Queries) DeletePermission(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deletePermission, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const detachPermissionFromRole = `-- name: DetachPermissionFromRole :execrows
+DELETE FROM roles_permissions
+WHERE role_id = $1 AND permission_id = $2
+`
+
+type DetachPermissionFromRoleParams struct {
+	RoleID       int32 `json:"role_id"`
+	PermissionID int32 `json:"permission_id"`
+}
+
+func (q *Queries) DetachPermissionFromRole(ctx context.Context, arg DetachPermissionFromRoleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, detachPermissionFromRole, arg.RoleID, arg.PermissionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getEffectivePermissionsByRoleID = `-- name: GetEffectivePermissionsByRoleID :many
+SELECT DISTINCT p.id, p.name, p.description, p.created_at, p.updated_at FROM permissions p
+JOIN roles_permissions rp ON rp.permission_id = p.id
+JOIN roles r ON r.id = rp.role_id
+JOIN roles own ON own.id = $1
+WHERE r.id = own.id OR (r.level < own.level AND p.name NOT LIKE '!%')
+ORDER BY p.name
+`
+
+// A role has its own permissions plus the allows of every lower level role.
+// Denies are not inherited, a deny on a lower role only restricts that role.
+func (q *Queries) GetEffectivePermissionsByRoleID(ctx context.Context, roleID int64) ([]Permission, error) {
+	rows, err := q.db.Query(ctx, getEffectivePermissionsByRoleID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Permission
+	for rows.Next() {
+		var i Permission
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPermissionByID = `-- name: GetPermissionByID :one
+SELECT id, name, description, created_at, updated_at FROM permissions WHERE id = $1
+`
+
+func (q *Queries) GetPermissionByID(ctx context.Context, id int64) (Permission, error) {
+	row := q.db.QueryRow(ctx, getPermissionByID, id)
+	var i Permission
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const getPermissionsByRoleID = `-- name: GetPermissionsByRoleID :many
 SELECT p.id, p.name, p.description, p.created_at, p.updated_at FROM permissions p
 JOIN roles_permissions rp ON rp.permission_id = p.id
@@ -40,3 +167,62 @@ func (q *Queries) GetPermissionsByRoleID(ctx context.Context, roleID int32) ([]P
 	}
 	return items, nil
 }
+
+const listPermissions = `-- name: ListPermissions :many
+SELECT id, name, description, created_at, updated_at FROM permissions
+ORDER BY name
+`
+
+func (q *Queries) ListPermissions(ctx context.Context) ([]Permission, error) {
+	rows, err := q.db.Query(ctx, listPermissions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Permission
+	for rows.Next() {
+		var i Permission
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updatePermission = `-- name: UpdatePermission :one
+UPDATE permissions
+  set name = $2,
+  description = $3,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, description, created_at, updated_at
+`
+
+type UpdatePermissionParams struct {
+	ID          int64       `json:"id"`
+	Name        string      `json:"name"`
+	Description pgtype.Text `json:"description"`
+}
+
+func (q *Queries) UpdatePermission(ctx context.Context, arg UpdatePermissionParams) (Permission, error) {
+	row := q.db.QueryRow(ctx, updatePermission, arg.ID, arg.Name, arg.Description)
+	var i Permission
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
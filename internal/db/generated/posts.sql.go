@@ -41,3 +41,117 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 	)
 	return i, err
 }
+
+const deletePost = `-- name: DeletePost :execrows
+DELETE FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) DeletePost(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deletePost, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deletePostsByUser = `-- name: DeletePostsByUser :exec
+DELETE FROM posts
+WHERE user_id = $1
+`
+
+func (q *Queries) DeletePostsByUser(ctx context.Context, userID int32) error {
+	_, err := q.db.Exec(ctx, deletePostsByUser, userID)
+	return err
+}
+
+const getPostByID = `-- name: GetPostByID :one
+SELECT id, title, content, user_id, tags, created_at, updated_at FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) GetPostByID(ctx context.Context, id int32) (Post, error) {
+	row := q.db.QueryRow(ctx, getPostByID, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Content,
+		&i.UserID,
+		&i.Tags,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listPostsByUser = `-- name: ListPostsByUser :many
+SELECT id, title, content, user_id, tags, created_at, updated_at FROM posts
+WHERE user_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) ListPostsByUser(ctx context.Context, userID int32) ([]Post, error) {
+	rows, err := q.db.Query(ctx, listPostsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Content,
+			&i.UserID,
+			&i.Tags,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updatePost = `-- name: UpdatePost :one
+UPDATE posts
+  set title = $2,
+  content = $3,
+  tags = $4,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING id, title, content, user_id, tags, created_at, updated_at
+`
+
+type UpdatePostParams struct {
+	ID      int32    `json:"id"`
+	Title   string   `json:"title"`
+	Content string   `json:"content"`
+	Tags    []string `json:"tags"`
+}
+
+func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, error) {
+	row := q.db.QueryRow(ctx, updatePost,
+		arg.ID,
+		arg.Title,
+		arg.Content,
+		arg.Tags,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Content,
+		&i.UserID,
+		&i.Tags,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -7,8 +7,86 @@ package sqlc
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const createRole = `-- name: CreateRole :one
+INSERT INTO roles (name, level, description)
+VALUES ($1, $2, $3)
+RETURNING id, name, level, description, created_at, updated_at
+`
+
+type CreateRoleParams struct {
+	Name        string      `json:"name"`
+	Level       int32       `json:"level"`
+	Description pgtype.Text `json:"description"`
+}
+
+func (q *Queries) CreateRole(ctx context.Context, arg CreateRoleParams) (Role, error) {
+	row := q.db.QueryRow(ctx, createRole, arg.Name, arg.Level, arg.Description)
+	var i Role
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Level,
+		&i.Description,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteRole = `-- name: DeleteRole :execrows
+DELETE FROM roles
+WHERE id = $1
+`
+
+func (q *Queries) DeleteRole(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRole, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getRoleByID = `-- name: GetRoleByID :one
+SELECT id, name, level, description, created_at, updated_at FROM roles WHERE id = $1
+`
+
+func (q *Queries) GetRoleByID(ctx context.Context, id int64) (Role, error) {
+	row := q.db.QueryRow(ctx, getRoleByID, id)
+	var i Role
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Level,
+		&i.Description,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getRoleByIDForUpdate = `-- name: GetRoleByIDForUpdate :one
+SELECT id, name, level, description, created_at, updated_at FROM roles WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetRoleByIDForUpdate(ctx context.Context, id int64) (Role, error) {
+	row := q.db.QueryRow(ctx, getRoleByIDForUpdate, id)
+	var i Role
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Level,
+		&i.Description,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const getRoleByName = `-- name: GetRoleByName :one
 SELECT id, name, level, description, created_at, updated_at FROM roles WHERE name = $1
 `
@@ -26,3 +104,71 @@ func (q *Queries) GetRoleByName(ctx context.Context, name string) (Role, error)
 	)
 	return i, err
 }
+
+const listRoles = `-- name: ListRoles :many
+SELECT id, name, level, description, created_at, updated_at FROM roles
+ORDER BY level, name
+`
+
+func (q *Queries) ListRoles(ctx context.Context) ([]Role, error) {
+	rows, err := q.db.Query(ctx, listRoles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Role
+	for rows.Next() {
+		var i Role
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Level,
+			&i.Description,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateRole = `-- name: UpdateRole :one
+UPDATE roles
+  set name = $2,
+  level = $3,
+  description = $4,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, level, description, created_at, updated_at
+`
+
+type UpdateRoleParams struct {
+	ID          int64       `json:"id"`
+	Name        string      `json:"name"`
+	Level       int32       `json:"level"`
+	Description pgtype.Text `json:"description"`
+}
+
+func (q *Queries) UpdateRole(ctx context.Context, arg UpdateRoleParams) (Role, error) {
+	row := q.db.QueryRow(ctx, updateRole,
+		arg.ID,
+		arg.Name,
+		arg.Level,
+		arg.Description,
+	)
+	var i Role
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Level,
+		&i.Description,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
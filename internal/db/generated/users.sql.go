@@ -11,10 +11,22 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countUsersByRole = `-- name: CountUsersByRole :one
+SELECT COUNT(*) FROM users
+WHERE role_id = $1
+`
+
+func (q *Queries) CountUsersByRole(ctx context.Context, roleID pgtype.Int4) (int64, error) {
+	row := q.db.QueryRow(ctx, countUsersByRole, roleID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (email, username, full_name, password, role_id)
 VALUES ($1, $2, $3, $4, $5)
-RETURNING id, email, username, full_name, password, verified, verified_at, created_at, updated_at, role_id
+RETURNING id, email, username, full_name, password, verified, verified_at, created_at, updated_at, role_id, token_version
 `
 
 type CreateUserParams struct {
@@ -45,6 +57,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.RoleID,
+		&i.TokenVersion,
 	)
 	return i, err
 }
@@ -60,7 +73,7 @@ func (q *Queries) DeleteUser(ctx context.Context, id int64) error {
 }
 
 const getByEmail = `-- name: GetByEmail :one
-SELECT id, email, username, full_name, password, verified, verified_at, created_at, updated_at, role_id FROM users
+SELECT id, email, username, full_name, password, verified, verified_at, created_at, updated_at, role_id, token_version FROM users
 WHERE email = $1 LIMIT 1
 `
 
@@ -78,12 +91,13 @@ func (q *Queries) GetByEmail(ctx context.Context, email string) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.RoleID,
+		&i.TokenVersion,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, email, username, full_name, password, verified, verified_at, created_at, updated_at, role_id FROM users
+SELECT id, email, username, full_name, password, verified, verified_at, created_at, updated_at, role_id, token_version FROM users
 WHERE id = $1 LIMIT 1
 `
 
@@ -101,6 +115,7 @@ func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.RoleID,
+		&i.TokenVersion,
 	)
 	return i, err
 }
@@ -166,8 +181,20 @@ func (q *Queries) GetUserWithRole(ctx context.Context, id int64) (GetUserWithRol
 	return i, err
 }
 
+const incrementTokenVersion = `-- name: IncrementTokenVersion :exec
+UPDATE users
+  set token_version = token_version + 1,
+  updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) IncrementTokenVersion(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, incrementTokenVersion, id)
+	return err
+}
+
 const listUsers = `-- name: ListUsers :many
-SELECT users.id, email, username, full_name, password, verified, verified_at, users.created_at, users.updated_at, role_id, roles.id, name, level, description, roles.created_at, roles.updated_at FROM users
+SELECT users.id, email, username, full_name, password, verified, verified_at, users.created_at, users.updated_at, role_id, token_version, roles.id, name, level, description, roles.created_at, roles.updated_at FROM users
 JOIN roles ON users.role_id = roles.id
 LIMIT $1
 OFFSET $2
@@ -179,22 +206,23 @@ type ListUsersParams struct {
 }
 
 type ListUsersRow struct {
-	ID          int64              `json:"id"`
-	Email       string             `json:"email"`
-	Username    string             `json:"username"`
-	FullName    string             `json:"full_name"`
-	Password    string             `json:"password"`
-	Verified    bool               `json:"verified"`
-	VerifiedAt  pgtype.Timestamptz `json:"verified_at"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
-	RoleID      pgtype.Int4        `json:"role_id"`
-	ID_2        int64              `json:"id_2"`
-	Name        string             `json:"name"`
-	Level       int32              `json:"level"`
-	Description pgtype.Text        `json:"description"`
-	CreatedAt_2 pgtype.Timestamptz `json:"created_at_2"`
-	UpdatedAt_2 pgtype.Timestamptz `json:"updated_at_2"`
+	ID           int64              `json:"id"`
+	Email        string             `json:"email"`
+	Username     string             `json:"username"`
+	FullName     string             `json:"full_name"`
+	Password     string             `json:"password"`
+	Verified     bool               `json:"verified"`
+	VerifiedAt   pgtype.Timestamptz `json:"verified_at"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	RoleID       pgtype.Int4        `json:"role_id"`
+	TokenVersion int32              `json:"token_version"`
+	ID_2         int64              `json:"id_2"`
+	Name         string             `json:"name"`
+	Level        int32              `json:"level"`
+	Description  pgtype.Text        `json:"description"`
+	CreatedAt_2  pgtype.Timestamptz `json:"created_at_2"`
+	UpdatedAt_2  pgtype.Timestamptz `json:"updated_at_2"`
 }
 
 func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error) {
@@ -217,6 +245,7 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUse
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.RoleID,
+			&i.TokenVersion,
 			&i.ID_2,
 			&i.Name,
 			&i.Level,
@@ -234,13 +263,34 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUse
 	return items, nil
 }
 
+const rehashUserPassword = `-- name: RehashUserPassword :execrows
+UPDATE users
+  set password = $1,
+  updated_at = NOW()
+WHERE id = $2 AND password = $3
+`
+
+type RehashUserPasswordParams struct {
+	NewPassword string `json:"new_password"`
+	ID          int64  `json:"id"`
+	OldPassword string `json:"old_password"`
+}
+
+func (q *Queries) RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error) {
+	result, err := q.db.Exec(ctx, rehashUserPassword, arg.NewPassword, arg.ID, arg.OldPassword)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateUser = `-- name: UpdateUser :exec
 UPDATE users
   set email = $2, 
   username = $3, 
   full_name = $4,
   updated_at = NOW()
-WHERE id = $1 RETURNING id, email, username, full_name, password, verified, verified_at, created_at, updated_at, role_id
+WHERE id = $1 RETURNING id, email, username, full_name, password, verified, verified_at, created_at, updated_at, role_id, token_version
 `
 
 type UpdateUserParams struct {
@@ -259,3 +309,50 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
 	)
 	return err
 }
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+  set password = $2,
+  updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID       int64  `json:"id"`
+	Password string `json:"password"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.Exec(ctx, updateUserPassword, arg.ID, arg.Password)
+	return err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :exec
+UPDATE users
+  set role_id = $2,
+  updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserRoleParams struct {
+	ID     int64       `json:"id"`
+	RoleID pgtype.Int4 `json:"role_id"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) error {
+	_, err := q.db.Exec(ctx, updateUserRole, arg.ID, arg.RoleID)
+	return err
+}
+
+const verifyUser = `-- name: VerifyUser :exec
+UPDATE users
+  set verified = TRUE,
+  verified_at = NOW(),
+  updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) VerifyUser(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, verifyUser, id)
+	return err
+}
@@ -0,0 +1,9 @@
+-- name: CreateAccountUnlock :exec
+INSERT INTO account_unlocks (user_id, token_hash, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: ConsumeAccountUnlock :one
+UPDATE account_unlocks
+  set used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING user_id;
@@ -0,0 +1,57 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports (user_id)
+VALUES ($1)
+RETURNING id, user_id, status, error, started_at, completed_at, expires_at, created_at;
+
+-- name: CountActiveDataExports :one
+SELECT COUNT(*) FROM data_exports
+WHERE user_id = $1 AND status IN ('pending', 'running');
+
+-- name: ListDataExports :many
+SELECT id, user_id, status, error, started_at, completed_at, expires_at, created_at FROM data_exports
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetDataExportArchive :one
+SELECT archive FROM data_exports
+WHERE id = $1 AND user_id = $2 AND status = 'ready' AND expires_at > NOW();
+
+-- name: ClaimDataExport :one
+UPDATE data_exports
+  set status = 'running',
+  started_at = NOW()
+WHERE id = (
+  SELECT id FROM data_exports
+  WHERE status = 'pending'
+  ORDER BY created_at
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, user_id;
+
+-- name: CompleteDataExport :exec
+UPDATE data_exports
+  set status = 'ready',
+  archive = $2,
+  expires_at = $3,
+  completed_at = NOW()
+WHERE id = $1;
+
+-- name: FailDataExport :exec
+UPDATE data_exports
+  set status = 'failed',
+  error = $2,
+  completed_at = NOW()
+WHERE id = $1;
+
+-- name: RequeueStaleDataExports :exec
+UPDATE data_exports
+  set status = 'pending',
+  started_at = NULL
+WHERE status = 'running' AND started_at < $1;
+
+-- name: ExpireDataExports :exec
+UPDATE data_exports
+  set status = 'expired',
+  archive = NULL
+WHERE status = 'ready' AND expires_at <= NOW();
@@ -0,0 +1,14 @@
+-- name: CreateEmailChange :exec
+INSERT INTO email_changes (user_id, new_email, token_hash, expires_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: ConsumeEmailChange :one
+UPDATE email_changes
+  set used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING user_id, new_email;
+
+-- name: InvalidateEmailChanges :exec
+UPDATE email_changes
+  set used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
@@ -0,0 +1,25 @@
+-- name: CreateErasureRequest :one
+INSERT INTO erasure_requests (user_id, scheduled_for)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetPendingErasureRequest :one
+SELECT * FROM erasure_requests
+WHERE user_id = $1 AND cancelled_at IS NULL AND completed_at IS NULL;
+
+-- name: CancelErasureRequest :execrows
+UPDATE erasure_requests
+  set cancelled_at = NOW()
+WHERE user_id = $1 AND cancelled_at IS NULL AND completed_at IS NULL;
+
+-- name: ClaimDueErasureRequest :one
+UPDATE erasure_requests
+  set completed_at = NOW()
+WHERE id = (
+  SELECT id FROM erasure_requests
+  WHERE cancelled_at IS NULL AND completed_at IS NULL AND scheduled_for <= NOW()
+  ORDER BY scheduled_for
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
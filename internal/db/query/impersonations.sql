@@ -0,0 +1,23 @@
+-- name: CreateImpersonation :exec
+INSERT INTO impersonations (actor_id, user_id, token_id, reason, ip, user_agent, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: CreateImpersonationRequest :one
+INSERT INTO impersonation_requests (token_id, actor_id, user_id, method, path, ip)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id;
+
+-- name: SetImpersonationRequestStatus :exec
+UPDATE impersonation_requests
+  set status = $2
+WHERE id = $1;
+
+-- name: ListImpersonationsByUser :many
+SELECT * FROM impersonations
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: ListImpersonationRequestsByUser :many
+SELECT * FROM impersonation_requests
+WHERE user_id = $1
+ORDER BY created_at;
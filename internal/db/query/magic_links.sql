@@ -0,0 +1,18 @@
+-- name: CreateMagicLink :exec
+INSERT INTO magic_links (user_id, email, token_hash, expires_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: CountRecentMagicLinks :one
+SELECT COUNT(*) FROM magic_links
+WHERE user_id = $1 AND created_at > $2;
+
+-- name: ConsumeMagicLink :one
+UPDATE magic_links
+  set used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING user_id, email;
+
+-- name: InvalidateMagicLinks :exec
+UPDATE magic_links
+  set used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
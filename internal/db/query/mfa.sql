@@ -0,0 +1,44 @@
+-- name: UpsertUserMFA :exec
+INSERT INTO user_mfa (user_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+  set secret = EXCLUDED.secret,
+  enabled = FALSE,
+  last_used_step = 0,
+  enabled_at = NULL,
+  updated_at = NOW();
+
+-- name: GetUserMFA :one
+SELECT * FROM user_mfa
+WHERE user_id = $1 LIMIT 1;
+
+-- name: EnableUserMFA :exec
+UPDATE user_mfa
+  set enabled = TRUE,
+  enabled_at = NOW(),
+  last_used_step = $2,
+  updated_at = NOW()
+WHERE user_id = $1;
+
+-- name: UpdateMFALastUsedStep :execrows
+UPDATE user_mfa
+  set last_used_step = $2,
+  updated_at = NOW()
+WHERE user_id = $1 AND last_used_step < $2;
+
+-- name: DeleteUserMFA :exec
+DELETE FROM user_mfa
+WHERE user_id = $1;
+
+-- name: CreateMFARecoveryCode :exec
+INSERT INTO mfa_recovery_codes (user_id, code_hash)
+VALUES ($1, $2);
+
+-- name: UseMFARecoveryCode :execrows
+UPDATE mfa_recovery_codes
+  set used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: DeleteMFARecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE user_id = $1;
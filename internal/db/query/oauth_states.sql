@@ -0,0 +1,12 @@
+-- name: CreateOAuthState :exec
+INSERT INTO oauth_states (state_hash, provider, nonce, code_verifier, expires_at, user_id)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ConsumeOAuthState :one
+DELETE FROM oauth_states
+WHERE state_hash = $1 AND provider = $2 AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteExpiredOAuthStates :exec
+DELETE FROM oauth_states
+WHERE expires_at <= NOW();
@@ -0,0 +1,14 @@
+-- name: CreatePasswordReset :exec
+INSERT INTO password_resets (user_id, token_hash, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: ConsumePasswordReset :one
+UPDATE password_resets
+  set used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING user_id;
+
+-- name: InvalidatePasswordResets :exec
+UPDATE password_resets
+  set used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
@@ -9,3 +9,46 @@ WHERE u.id = $1;
 SELECT p.* FROM permissions p
 JOIN roles_permissions rp ON rp.permission_id = p.id
 WHERE rp.role_id = $1;
+
+-- name: GetPermissionByID :one
+SELECT * FROM permissions WHERE id = $1;
+
+-- name: ListPermissions :many
+SELECT * FROM permissions
+ORDER BY name;
+
+-- name: CreatePermission :one
+INSERT INTO permissions (name, description)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: UpdatePermission :one
+UPDATE permissions
+  set name = $2,
+  description = $3,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeletePermission :execrows
+DELETE FROM permissions
+WHERE id = $1;
+
+-- name: AttachPermissionToRole :exec
+INSERT INTO roles_permissions (role_id, permission_id)
+VALUES ($1, $2)
+ON CONFLICT (role_id, permission_id) DO NOTHING;
+
+-- name: DetachPermissionFromRole :execrows
+DELETE FROM roles_permissions
+WHERE role_id = $1 AND permission_id = $2;
+
+-- name: GetEffectivePermissionsByRoleID :many
+-- A role has its own permissions plus the allows of every lower level role.
+-- Denies are not inherited, a deny on a lower role only restricts that role.
+SELECT DISTINCT p.* FROM permissions p
+JOIN roles_permissions rp ON rp.permission_id = p.id
+JOIN roles r ON r.id = rp.role_id
+JOIN roles own ON own.id = sqlc.arg(role_id)
+WHERE r.id = own.id OR (r.level < own.level AND p.name NOT LIKE '!%')
+ORDER BY p.name;
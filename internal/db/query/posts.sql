@@ -1,4 +1,30 @@
 -- name: CreatePost :one
 INSERT INTO posts (title, content, user_id, tags)
 VALUES ($1, $2, $3, $4)
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: ListPostsByUser :many
+SELECT * FROM posts
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: DeletePostsByUser :exec
+DELETE FROM posts
+WHERE user_id = $1;
+
+-- name: GetPostByID :one
+SELECT * FROM posts
+WHERE id = $1;
+
+-- name: UpdatePost :one
+UPDATE posts
+  set title = $2,
+  content = $3,
+  tags = $4,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeletePost :execrows
+DELETE FROM posts
+WHERE id = $1;
@@ -0,0 +1,23 @@
+-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (user_id, token_hash, family_id, parent_id, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetRefreshTokenByHash :one
+SELECT * FROM refresh_tokens
+WHERE token_hash = $1 LIMIT 1;
+
+-- name: RevokeRefreshToken :execrows
+UPDATE refresh_tokens
+  set revoked_at = NOW()
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+  set revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+  set revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
@@ -1,2 +1,31 @@
 -- name: GetRoleByName :one
-SELECT * FROM roles WHERE name = $1;
\ No newline at end of file
+SELECT * FROM roles WHERE name = $1;
+
+-- name: GetRoleByID :one
+SELECT * FROM roles WHERE id = $1;
+
+-- name: GetRoleByIDForUpdate :one
+SELECT * FROM roles WHERE id = $1
+FOR UPDATE;
+
+-- name: ListRoles :many
+SELECT * FROM roles
+ORDER BY level, name;
+
+-- name: CreateRole :one
+INSERT INTO roles (name, level, description)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: UpdateRole :one
+UPDATE roles
+  set name = $2,
+  level = $3,
+  description = $4,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteRole :execrows
+DELETE FROM roles
+WHERE id = $1;
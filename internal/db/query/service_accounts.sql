@@ -0,0 +1,30 @@
+-- name: CreateServiceAccount :one
+INSERT INTO service_accounts (name, description, client_id, secret_hash, role_id, created_by)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetServiceAccountByID :one
+SELECT * FROM service_accounts
+WHERE id = $1;
+
+-- name: GetServiceAccountByClientID :one
+SELECT * FROM service_accounts
+WHERE client_id = $1;
+
+-- name: ListServiceAccounts :many
+SELECT * FROM service_accounts
+ORDER BY name;
+
+-- name: UpdateServiceAccountSecret :execrows
+UPDATE service_accounts
+  set secret_hash = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteServiceAccount :execrows
+DELETE FROM service_accounts
+WHERE id = $1;
+
+-- name: TouchServiceAccount :exec
+UPDATE service_accounts
+  set last_used_at = NOW()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
@@ -0,0 +1,39 @@
+-- name: UpsertSession :one
+INSERT INTO sessions (id, user_id, user_agent, ip, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (id) DO UPDATE
+  set user_agent = EXCLUDED.user_agent,
+      ip = EXCLUDED.ip,
+      expires_at = EXCLUDED.expires_at,
+      last_seen_at = NOW()
+WHERE sessions.user_id = EXCLUDED.user_id
+RETURNING *;
+
+-- name: GetSession :one
+SELECT * FROM sessions
+WHERE id = $1;
+
+-- name: ListUserSessions :many
+SELECT * FROM sessions
+WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+ORDER BY last_seen_at DESC;
+
+-- name: TouchSession :exec
+UPDATE sessions
+  set last_seen_at = NOW(), ip = $2
+WHERE id = $1;
+
+-- name: RevokeSession :execrows
+UPDATE sessions
+  set revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeUserSessions :exec
+UPDATE sessions
+  set revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: ListAllUserSessions :many
+SELECT * FROM sessions
+WHERE user_id = $1
+ORDER BY created_at;
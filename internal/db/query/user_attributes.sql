@@ -0,0 +1,11 @@
+-- name: GetUserAttributes :one
+SELECT attributes FROM user_attributes
+WHERE user_id = $1;
+
+-- name: UpsertUserAttributes :one
+INSERT INTO user_attributes (user_id, attributes)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+  SET attributes = EXCLUDED.attributes,
+  updated_at = NOW()
+RETURNING *;
@@ -0,0 +1,19 @@
+-- name: CreateUserIdentity :exec
+INSERT INTO user_identities (user_id, provider, subject, email)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetUserByIdentity :one
+SELECT u.* FROM users u
+JOIN user_identities i ON i.user_id = u.id
+WHERE i.provider = $1 AND i.subject = $2 LIMIT 1;
+
+-- name: ListUserIdentities :many
+SELECT * FROM user_identities
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: TouchUserIdentity :exec
+UPDATE user_identities
+  set email = $3,
+  last_login_at = NOW()
+WHERE provider = $1 AND subject = $2;
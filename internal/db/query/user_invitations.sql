@@ -0,0 +1,13 @@
+-- name: CreateUserInvitation :exec
+INSERT INTO user_invitations (token_hash, user_id, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: GetUserByInvitationToken :one
+SELECT u.* FROM users u
+JOIN user_invitations ui ON ui.user_id = u.id
+WHERE ui.token_hash = $1 AND ui.expires_at > now()
+LIMIT 1;
+
+-- name: DeleteUserInvitations :exec
+DELETE FROM user_invitations
+WHERE user_id = $1;
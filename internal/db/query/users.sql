@@ -44,4 +44,39 @@ WHERE email = $1 LIMIT 1;
 
 -- name: DeleteUser :exec
 DELETE FROM users
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: VerifyUser :exec
+UPDATE users
+  set verified = TRUE,
+  verified_at = NOW(),
+  updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+  set password = $2,
+  updated_at = NOW()
+WHERE id = $1;
+
+-- name: IncrementTokenVersion :exec
+UPDATE users
+  set token_version = token_version + 1,
+  updated_at = NOW()
+WHERE id = $1;
+
+-- name: RehashUserPassword :execrows
+UPDATE users
+  set password = sqlc.arg(new_password),
+  updated_at = NOW()
+WHERE id = sqlc.arg(id) AND password = sqlc.arg(old_password);
+
+-- name: UpdateUserRole :exec
+UPDATE users
+  set role_id = $2,
+  updated_at = NOW()
+WHERE id = $1;
+
+-- name: CountUsersByRole :one
+SELECT COUNT(*) FROM users
+WHERE role_id = $1;
@@ -0,0 +1,36 @@
+-- name: CreateWebAuthnCredential :one
+INSERT INTO webauthn_credentials (user_id, credential_id, public_key, sign_count, aaguid, transports, name)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetWebAuthnCredentialByCredentialID :one
+SELECT * FROM webauthn_credentials
+WHERE credential_id = $1;
+
+-- name: ListWebAuthnCredentials :many
+SELECT * FROM webauthn_credentials
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: UpdateWebAuthnSignCount :execrows
+UPDATE webauthn_credentials
+  set sign_count = $2,
+  last_used_at = NOW()
+WHERE id = $1 AND (sign_count < $2 OR $2 = 0);
+
+-- name: DeleteWebAuthnCredential :execrows
+DELETE FROM webauthn_credentials
+WHERE id = $1 AND user_id = $2;
+
+-- name: CreateWebAuthnChallenge :exec
+INSERT INTO webauthn_challenges (challenge_hash, ceremony, user_id, expires_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: ConsumeWebAuthnChallenge :one
+DELETE FROM webauthn_challenges
+WHERE challenge_hash = $1 AND ceremony = $2 AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteExpiredWebAuthnChallenges :exec
+DELETE FROM webauthn_challenges
+WHERE expires_at <= NOW();
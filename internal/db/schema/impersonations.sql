@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS impersonations (
+  id BIGSERIAL PRIMARY KEY,
+  -- No foreign keys, the audit trail outlives deleted users.
+  actor_id BIGINT NOT NULL,
+  user_id BIGINT NOT NULL,
+  token_id TEXT NOT NULL UNIQUE,
+  reason TEXT NOT NULL,
+  ip TEXT NOT NULL,
+  user_agent TEXT NOT NULL,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE IF NOT EXISTS impersonation_requests (
+  id BIGSERIAL PRIMARY KEY,
+  token_id TEXT NOT NULL REFERENCES impersonations(token_id) ON DELETE RESTRICT,
+  actor_id BIGINT NOT NULL,
+  user_id BIGINT NOT NULL,
+  method TEXT NOT NULL,
+  path TEXT NOT NULL,
+  status INT,
+  ip TEXT NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT (now())
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS oauth_states (
+  state_hash TEXT PRIMARY KEY,
+  provider TEXT NOT NULL,
+  nonce TEXT NOT NULL,
+  code_verifier TEXT NOT NULL,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT (now()),
+  -- Set when a signed in user links another identity to their account.
+  user_id BIGINT REFERENCES users(id) ON DELETE CASCADE
+);
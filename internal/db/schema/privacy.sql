@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS data_exports (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  status TEXT NOT NULL DEFAULT 'pending',
+  archive BYTEA,
+  error TEXT NOT NULL DEFAULT '',
+  started_at timestamptz,
+  completed_at timestamptz,
+  expires_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX IF NOT EXISTS data_exports_user_id_idx ON data_exports (user_id);
+
+CREATE INDEX IF NOT EXISTS data_exports_pending_idx ON data_exports (created_at)
+WHERE
+  status = 'pending';
+
+-- Erasure requests outlive the user on purpose, they are the record that
+-- the erasure happened. user_id is therefore not a foreign key.
+CREATE TABLE IF NOT EXISTS erasure_requests (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  scheduled_for timestamptz NOT NULL,
+  cancelled_at timestamptz,
+  completed_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS erasure_requests_pending_user_id_idx ON erasure_requests (user_id)
+WHERE
+  cancelled_at IS NULL
+  AND completed_at IS NULL;
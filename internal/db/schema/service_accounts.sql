@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS service_accounts (
+  id BIGSERIAL PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE,
+  description TEXT NOT NULL DEFAULT '',
+  client_id TEXT NOT NULL UNIQUE,
+  secret_hash TEXT NOT NULL,
+  role_id INT NOT NULL REFERENCES roles(id),
+  created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  last_used_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT (now()),
+  updated_at timestamptz NOT NULL DEFAULT (now())
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS sessions (
+  -- id is the refresh token family_id and the sid claim of access tokens.
+  id TEXT PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  user_agent TEXT NOT NULL DEFAULT '',
+  ip TEXT NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT (now()),
+  last_seen_at timestamptz NOT NULL DEFAULT (now()),
+  expires_at timestamptz NOT NULL,
+  revoked_at timestamptz
+);
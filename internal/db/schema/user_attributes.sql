@@ -0,0 +1,6 @@
+-- Free form attributes used by ABAC policies, e.g. {"region": "id-jkt"}.
+CREATE TABLE IF NOT EXISTS user_attributes (
+  user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  attributes JSONB NOT NULL DEFAULT '{}',
+  updated_at timestamptz NOT NULL DEFAULT (now())
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  provider TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  email TEXT NOT NULL DEFAULT '',
+  last_login_at timestamptz NOT NULL DEFAULT (now()),
+  created_at timestamptz NOT NULL DEFAULT (now()),
+  UNIQUE (provider, subject)
+);
@@ -8,5 +8,6 @@ CREATE TABLE users (
     verified_at timestamptz NOT NULL DEFAULT (now()),
     created_at timestamptz NOT NULL DEFAULT (now()),
     updated_at timestamptz NOT NULL DEFAULT (now()),
-    role_id INT REFERENCES roles(id)
+    role_id INT REFERENCES roles(id),
+    token_version INT NOT NULL DEFAULT 0
 );
\ No newline at end of file
@@ -0,0 +1,125 @@
+// Package hierarchy applies roles.level. A role inherits the permissions of
+// every role with a lower level (see GetEffectivePermissionsByRoleID), and
+// the rules below keep anyone from acting on users at or above their own
+// level.
+package hierarchy
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrRoleNotFound = errors.New("role not found")
+	ErrLevelTooLow  = errors.New("your role level is too low for this action")
+	ErrOutranked    = errors.New("you cannot manage a user at or above your own role level")
+	ErrLevelTooHigh = errors.New("you cannot create or manage a role at or above your own level")
+)
+
+type Role struct {
+	ID    int64
+	Name  string
+	Level int32
+}
+
+// RoleStore looks up roles, ErrRoleNotFound when there is no such role.
+type RoleStore interface {
+	RoleByID(ctx context.Context, id int64) (Role, error)
+}
+
+type Guard struct {
+	roles RoleStore
+}
+
+func New(roles RoleStore) *Guard {
+	return &Guard{roles: roles}
+}
+
+// Role looks up a role.
+func (g *Guard) Role(ctx context.Context, roleID int64) (Role, error) {
+	return g.roles.RoleByID(ctx, roleID)
+}
+
+// Level returns the level of a role.
+func (g *Guard) Level(ctx context.Context, roleID int64) (int32, error) {
+	role, err := g.roles.RoleByID(ctx, roleID)
+	if err != nil {
+		return 0, err
+	}
+	return role.Level, nil
+}
+
+// RequireLevel checks that the role is at least min.
+func (g *Guard) RequireLevel(ctx context.Context, roleID int64, min int32) error {
+	level, err := g.Level(ctx, roleID)
+	if err != nil {
+		return err
+	}
+	if level < min {
+		return ErrLevelTooLow
+	}
+	return nil
+}
+
+// CanManageUser checks that the actor outranks the target user. Equal
+// levels are refused, two super admins cannot demote each other.
+func (g *Guard) CanManageUser(ctx context.Context, actorRoleID, targetRoleID int64) error {
+	actor, err := g.Level(ctx, actorRoleID)
+	if err != nil {
+		return err
+	}
+	target, err := g.Level(ctx, targetRoleID)
+	if err != nil {
+		return err
+	}
+	if target >= actor {
+		return ErrOutranked
+	}
+	return nil
+}
+
+// CanAssignRole checks that the actor may move the target user, currently
+// in targetRoleID, to newRoleID. The new role is either the actor's own,
+// which hands out nothing the actor lacks, or one below the actor's level.
+func (g *Guard) CanAssignRole(ctx context.Context, actorRoleID, targetRoleID, newRoleID int64) error {
+	if err := g.CanManageUser(ctx, actorRoleID, targetRoleID); err != nil {
+		return err
+	}
+	if newRoleID == actorRoleID {
+		return nil
+	}
+
+	actor, err := g.Level(ctx, actorRoleID)
+	if err != nil {
+		return err
+	}
+	level, err := g.Level(ctx, newRoleID)
+	if err != nil {
+		return err
+	}
+	return CanGrantLevel(actor, level)
+}
+
+// CanManageRole checks that the actor may change or delete roleID. The
+// actor's own role and the roles at its level are out of reach, otherwise
+// an admin could attach new permissions to themselves.
+func (g *Guard) CanManageRole(ctx context.Context, actorRoleID, roleID int64) error {
+	actor, err := g.Level(ctx, actorRoleID)
+	if err != nil {
+		return err
+	}
+	level, err := g.Level(ctx, roleID)
+	if err != nil {
+		return err
+	}
+	return CanGrantLevel(actor, level)
+}
+
+// CanGrantLevel reports whether an actor at actorLevel may create or
+// manage a role at level, which has to be below the actor's.
+func CanGrantLevel(actorLevel, level int32) error {
+	if level >= actorLevel {
+		return ErrLevelTooHigh
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package hierarchy
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+const (
+	userRole int64 = iota + 1
+	moderatorRole
+	adminRole
+	otherAdminRole
+	superRole
+	missingRole int64 = 99
+)
+
+func newGuard() *Guard {
+	return New(NewMemoryRoleStore(
+		Role{ID: userRole, Name: "user", Level: 1},
+		Role{ID: moderatorRole, Name: "moderator", Level: 2},
+		Role{ID: adminRole, Name: "admin", Level: 3},
+		Role{ID: otherAdminRole, Name: "support-admin", Level: 3},
+		Role{ID: superRole, Name: "super", Level: 4},
+	))
+}
+
+func checkErr(t *testing.T, err, want error) {
+	t.Helper()
+
+	if want == nil {
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return
+	}
+	if !errors.Is(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+}
+
+func TestRequireLevel(t *testing.T) {
+	g := newGuard()
+
+	tests := []struct {
+		name   string
+		roleID int64
+		min    int32
+		want   error
+	}{
+		{"above", adminRole, 2, nil},
+		{"equal", moderatorRole, 2, nil},
+		{"below", userRole, 2, ErrLevelTooLow},
+		{"unknown role", missingRole, 1, ErrRoleNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkErr(t, g.RequireLevel(context.Background(), tt.roleID, tt.min), tt.want)
+		})
+	}
+}
+
+func TestCanManageUser(t *testing.T) {
+	g := newGuard()
+
+	tests := []struct {
+		name   string
+		actor  int64
+		target int64
+		want   error
+	}{
+		{"lower target", adminRole, moderatorRole, nil},
+		{"much lower target", superRole, userRole, nil},
+		{"same role", adminRole, adminRole, ErrOutranked},
+		{"other role at the same level", adminRole, otherAdminRole, ErrOutranked},
+		{"higher target", moderatorRole, adminRole, ErrOutranked},
+		{"two super admins", superRole, superRole, ErrOutranked},
+		{"unknown actor role", missingRole, userRole, ErrRoleNotFound},
+		{"unknown target role", adminRole, missingRole, ErrRoleNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkErr(t, g.CanManageUser(context.Background(), tt.actor, tt.target), tt.want)
+		})
+	}
+}
+
+func TestCanAssignRole(t *testing.T) {
+	g := newGuard()
+
+	tests := []struct {
+		name    string
+		actor   int64
+		target  int64
+		newRole int64
+		want    error
+	}{
+		{"demote", adminRole, moderatorRole, userRole, nil},
+		{"promote below the actor", adminRole, userRole, moderatorRole, nil},
+		{"promote to the actor's role", adminRole, moderatorRole, adminRole, nil},
+		{"promote to another role at the actor's level", adminRole, moderatorRole, otherAdminRole, ErrLevelTooHigh},
+		{"promote above the actor", adminRole, moderatorRole, superRole, ErrLevelTooHigh},
+		{"target at the actor's level", adminRole, otherAdminRole, userRole, ErrOutranked},
+		{"target above the actor", moderatorRole, superRole, userRole, ErrOutranked},
+		{"own role", adminRole, adminRole, moderatorRole, ErrOutranked},
+		{"unknown new role", adminRole, userRole, missingRole, ErrRoleNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkErr(t, g.CanAssignRole(context.Background(), tt.actor, tt.target, tt.newRole), tt.want)
+		})
+	}
+}
+
+func TestCanManageRole(t *testing.T) {
+	g := newGuard()
+
+	tests := []struct {
+		name  string
+		actor int64
+		role  int64
+		want  error
+	}{
+		{"lower role", adminRole, userRole, nil},
+		{"role at the actor's level", adminRole, otherAdminRole, ErrLevelTooHigh},
+		{"own role", adminRole, adminRole, ErrLevelTooHigh},
+		{"own super role", superRole, superRole, ErrLevelTooHigh},
+		{"higher role", adminRole, superRole, ErrLevelTooHigh},
+		{"unknown role", adminRole, missingRole, ErrRoleNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkErr(t, g.CanManageRole(context.Background(), tt.actor, tt.role), tt.want)
+		})
+	}
+}
+
+func TestCanGrantLevel(t *testing.T) {
+	tests := []struct {
+		actor int32
+		level int32
+		want  error
+	}{
+		{3, 1, nil},
+		{3, 2, nil},
+		{3, 3, ErrLevelTooHigh},
+		{3, 4, ErrLevelTooHigh},
+		{0, 0, ErrLevelTooHigh},
+	}
+
+	for _, tt := range tests {
+		checkErr(t, CanGrantLevel(tt.actor, tt.level), tt.want)
+	}
+}
+
+func TestMemoryRoleStorePut(t *testing.T) {
+	store := NewMemoryRoleStore()
+	g := New(store)
+
+	checkErr(t, g.RequireLevel(context.Background(), moderatorRole, 2), ErrRoleNotFound)
+
+	store.Put(Role{ID: moderatorRole, Name: "moderator", Level: 2})
+	checkErr(t, g.RequireLevel(context.Background(), moderatorRole, 2), nil)
+
+	role, err := store.RoleByID(context.Background(), moderatorRole)
+	checkErr(t, err, nil)
+	if role.Name != "moderator" || role.Level != 2 {
+		t.Fatalf("unexpected role %+v", role)
+	}
+}
@@ -0,0 +1,62 @@
+package hierarchy
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+)
+
+type queriesRoleStore struct {
+	q *sqlc.Queries
+}
+
+// NewRoleStore reads roles from Postgres.
+func NewRoleStore(q *sqlc.Queries) RoleStore {
+	return &queriesRoleStore{q: q}
+}
+
+func (s *queriesRoleStore) RoleByID(ctx context.Context, id int64) (Role, error) {
+	role, err := s.q.GetRoleByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Role{}, ErrRoleNotFound
+		}
+		return Role{}, err
+	}
+	return Role{ID: role.ID, Name: role.Name, Level: role.Level}, nil
+}
+
+// MemoryRoleStore is a fixed set of roles, for tests and tools that run
+// without a database.
+type MemoryRoleStore struct {
+	mu    sync.RWMutex
+	roles map[int64]Role
+}
+
+func NewMemoryRoleStore(roles ...Role) *MemoryRoleStore {
+	s := &MemoryRoleStore{roles: make(map[int64]Role, len(roles))}
+	for _, role := range roles {
+		s.roles[role.ID] = role
+	}
+	return s
+}
+
+func (s *MemoryRoleStore) Put(role Role) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roles[role.ID] = role
+}
+
+func (s *MemoryRoleStore) RoleByID(ctx context.Context, id int64) (Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	role, ok := s.roles[id]
+	if !ok {
+		return Role{}, ErrRoleNotFound
+	}
+	return role, nil
+}
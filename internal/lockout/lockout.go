@@ -0,0 +1,196 @@
+// Package lockout protects credential checks against brute force. Failed
+// attempts are counted per account and per client IP; after a few free
+// attempts every failure adds a growing delay and once the threshold is hit
+// the account (or IP) is locked for a while.
+package lockout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrLocked = errors.New("too many failed attempts, try again later")
+
+// LockedError is returned while a key is locked, RetryAfter tells the
+// client how long to wait.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s (retry after %s)", ErrLocked, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrLocked
+}
+
+// Store keeps failure counters and locks. Counters expire window after the
+// first failure, locks after their ttl.
+type Store interface {
+	Increment(ctx context.Context, key string, window time.Duration) (int64, error)
+	Lock(ctx context.Context, key string, ttl time.Duration) error
+	LockedFor(ctx context.Context, key string) (time.Duration, error)
+	Reset(ctx context.Context, key string) error
+}
+
+type Config struct {
+	MaxAccountFailures int
+	MaxIPFailures      int
+	// FreeAttempts is how many failures are tolerated before delays kick in.
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	Window          time.Duration
+	LockoutDuration time.Duration
+	Enabled         bool
+}
+
+// Result describes what a failed attempt caused.
+type Result struct {
+	Failures int64
+	// AccountLocked is only true for the failure that locked the account,
+	// so callers can notify the owner exactly once.
+	AccountLocked bool
+	RetryAfter    time.Duration
+}
+
+type Guard struct {
+	store Store
+	cfg   Config
+}
+
+func New(store Store, cfg Config) *Guard {
+	return &Guard{store: store, cfg: cfg}
+}
+
+// Check returns a *LockedError when either the account or the IP has to
+// wait before trying again.
+func (g *Guard) Check(ctx context.Context, account, ip string) error {
+	if !g.cfg.Enabled {
+		return nil
+	}
+
+	var wait time.Duration
+	for _, key := range g.keys(account, ip) {
+		ttl, err := g.store.LockedFor(ctx, key)
+		if err != nil {
+			return err
+		}
+		wait = max(wait, ttl)
+	}
+
+	if wait > 0 {
+		return &LockedError{RetryAfter: wait}
+	}
+
+	return nil
+}
+
+// Fail records a failed attempt for the account and the IP.
+func (g *Guard) Fail(ctx context.Context, account, ip string) (Result, error) {
+	var res Result
+	if !g.cfg.Enabled {
+		return res, nil
+	}
+
+	if account != "" {
+		n, err := g.store.Increment(ctx, accountKey(account), g.cfg.Window)
+		if err != nil {
+			return res, err
+		}
+		res.Failures = n
+
+		wait := g.delay(n)
+		if n >= int64(g.cfg.MaxAccountFailures) {
+			wait = g.cfg.LockoutDuration
+			res.AccountLocked = n == int64(g.cfg.MaxAccountFailures)
+		}
+
+		if wait > 0 {
+			if err := g.store.Lock(ctx, accountKey(account), wait); err != nil {
+				return res, err
+			}
+			res.RetryAfter = wait
+		}
+	}
+
+	// Many users can share an IP behind a NAT, so IPs only get the hard
+	// lock with a higher threshold and no progressive delay.
+	if ip != "" {
+		n, err := g.store.Increment(ctx, ipKey(ip), g.cfg.Window)
+		if err != nil {
+			return res, err
+		}
+
+		if n >= int64(g.cfg.MaxIPFailures) {
+			if err := g.store.Lock(ctx, ipKey(ip), g.cfg.LockoutDuration); err != nil {
+				return res, err
+			}
+			res.RetryAfter = max(res.RetryAfter, g.cfg.LockoutDuration)
+		}
+	}
+
+	return res, nil
+}
+
+// Succeed clears the account counter after a successful login. The IP
+// counter is left alone, one valid account must not whitewash an IP that
+// is guessing passwords for others.
+func (g *Guard) Succeed(ctx context.Context, account string) error {
+	if !g.cfg.Enabled {
+		return nil
+	}
+	return g.store.Reset(ctx, accountKey(account))
+}
+
+// UnlockAccount removes the lock and counter of an account.
+func (g *Guard) UnlockAccount(ctx context.Context, account string) error {
+	return g.store.Reset(ctx, accountKey(account))
+}
+
+// UnlockIP removes the lock and counter of an IP.
+func (g *Guard) UnlockIP(ctx context.Context, ip string) error {
+	return g.store.Reset(ctx, ipKey(ip))
+}
+
+// LockoutDuration is how long a locked account stays locked.
+func (g *Guard) LockoutDuration() time.Duration {
+	return g.cfg.LockoutDuration
+}
+
+// delay doubles with every failure past the free attempts.
+func (g *Guard) delay(failures int64) time.Duration {
+	over := failures - int64(g.cfg.FreeAttempts)
+	if over <= 0 || g.cfg.BaseDelay <= 0 {
+		return 0
+	}
+
+	d := g.cfg.BaseDelay
+	for i := int64(1); i < over && d < g.cfg.MaxDelay; i++ {
+		d *= 2
+	}
+
+	return min(d, g.cfg.MaxDelay)
+}
+
+func (g *Guard) keys(account, ip string) []string {
+	keys := make([]string, 0, 2)
+	if account != "" {
+		keys = append(keys, accountKey(account))
+	}
+	if ip != "" {
+		keys = append(keys, ipKey(ip))
+	}
+	return keys
+}
+
+func accountKey(account string) string {
+	return "account:" + account
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
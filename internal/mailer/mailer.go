@@ -3,9 +3,13 @@ package mailer
 import "embed"
 
 const (
-	FromName            = "GopherSocial"
-	maxRetires          = 3
-	UserWelcomeTemplate = "user_invitation.tmpl"
+	FromName              = "GopherSocial"
+	maxRetires            = 3
+	UserWelcomeTemplate   = "user_invitation.tmpl"
+	PasswordResetTemplate = "password_reset.tmpl"
+	AccountLockedTemplate = "account_locked.tmpl"
+	MagicLinkTemplate     = "magic_link.tmpl"
+	EmailChangeTemplate   = "email_change.tmpl"
 )
 
 //go:embed "templates"
@@ -0,0 +1,220 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var supportedAlgs = []string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", "EdDSA"}
+
+// minRefreshInterval stops tokens with made up kids from hammering the
+// provider's JWKS endpoint.
+const minRefreshInterval = time.Minute
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+type publicKey struct {
+	alg string
+	key crypto.PublicKey
+}
+
+// remoteKeySet caches the provider's signing keys and refetches them when a
+// token is signed with a kid it has not seen, which is how providers roll
+// their keys.
+type remoteKeySet struct {
+	uri    string
+	client *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]publicKey
+	lastRefresh time.Time
+}
+
+func newRemoteKeySet(uri string, client *http.Client) *remoteKeySet {
+	return &remoteKeySet{uri: uri, client: client}
+}
+
+func (s *remoteKeySet) key(ctx context.Context, kid, alg string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if k, ok := s.lookup(kid, alg); ok {
+		return k, nil
+	}
+
+	if s.keys != nil && time.Since(s.lastRefresh) < minRefreshInterval {
+		return nil, fmt.Errorf("oidc: unknown signing key %q", kid)
+	}
+
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	if k, ok := s.lookup(kid, alg); ok {
+		return k, nil
+	}
+
+	return nil, fmt.Errorf("oidc: unknown signing key %q", kid)
+}
+
+// lookup finds the key by kid, or the only key when the token has no kid.
+func (s *remoteKeySet) lookup(kid, alg string) (crypto.PublicKey, bool) {
+	var found *publicKey
+
+	if kid != "" {
+		if k, ok := s.keys[kid]; ok {
+			found = &k
+		}
+	} else if len(s.keys) == 1 {
+		for _, k := range s.keys {
+			found = &k
+		}
+	}
+
+	if found == nil || !algMatchesKey(alg, found) {
+		return nil, false
+	}
+
+	return found.key, true
+}
+
+func (s *remoteKeySet) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.uri, nil)
+	if err != nil {
+		return err
+	}
+
+	var set jwkSet
+	status, err := fetchJSON(s.client, req, &set)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("oidc: jwks endpoint returned %d", status)
+	}
+
+	keys := make(map[string]publicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := parseJWK(k)
+		if err != nil {
+			// Skip keys we do not understand instead of failing the set.
+			continue
+		}
+		keys[k.Kid] = publicKey{alg: k.Alg, key: key}
+	}
+
+	s.keys = keys
+	s.lastRefresh = time.Now()
+
+	return nil
+}
+
+func parseJWK(k jwk) (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() {
+			return nil, errors.New("rsa exponent too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("ec point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// algMatchesKey makes sure the token's alg header fits the key type, and the
+// key's own alg when the JWKS pins one.
+func algMatchesKey(alg string, k *publicKey) bool {
+	if k.alg != "" && k.alg != alg {
+		return false
+	}
+
+	switch k.key.(type) {
+	case *rsa.PublicKey:
+		return alg == "RS256" || alg == "RS384" || alg == "RS512" || alg == "PS256"
+	case *ecdsa.PublicKey:
+		return alg == "ES256" || alg == "ES384"
+	case ed25519.PublicKey:
+		return alg == "EdDSA"
+	default:
+		return false
+	}
+}
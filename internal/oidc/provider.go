@@ -0,0 +1,311 @@
+// Package oidc is a small OpenID Connect relying party: discovery, the
+// authorization code flow with PKCE and ID token verification against the
+// provider's JWKS.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidIDToken = errors.New("oidc: invalid id token")
+	ErrNonceMismatch  = errors.New("oidc: nonce mismatch")
+)
+
+const maxResponseSize = 1 << 20
+
+// tenantPlaceholder stands for the tenant in the issuer of multi-tenant
+// providers, Microsoft's common endpoint announces
+// https://login.microsoftonline.com/{tenantid}/v2.0 while every ID token
+// carries the issuer of the user's own tenant.
+const tenantPlaceholder = "{tenantid}"
+
+type Config struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes default to openid, email and profile.
+	Scopes []string
+	// Leeway is the clock skew tolerated when checking exp and iat.
+	Leeway time.Duration
+	// Tenants are the tenant IDs accepted from a multi-tenant issuer.
+	// Anyone can create a tenant, so tokens of other tenants are refused,
+	// and every token is when the list is empty.
+	Tenants []string
+}
+
+// Metadata is the subset of the discovery document we use.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider talks to one OpenID provider. Discovery happens lazily on first
+// use so the API can start while a provider is unreachable.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *Metadata
+	keys     *remoteKeySet
+}
+
+// NewProvider returns a Provider for cfg. client may be nil to use
+// http.DefaultClient.
+func NewProvider(cfg Config, client *http.Client) *Provider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+
+	return &Provider{cfg: cfg, client: client}
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// AuthCodeURL returns the URL the user is sent to. codeChallenge is the
+// S256 PKCE challenge of the verifier later passed to Exchange.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.cfg.ClientID)
+	params.Set("redirect_uri", p.cfg.RedirectURL)
+	params.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(md.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return md.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange trades the authorization code for tokens and returns the
+// verified ID token claims.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Claims, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("client_secret", p.cfg.ClientSecret)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token tokenResponse
+	status, err := fetchJSON(p.client, req, &token)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("oidc: token endpoint returned %d: %s %s", status, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("oidc: token response has no id_token")
+	}
+
+	return p.VerifyIDToken(ctx, token.IDToken, nonce)
+}
+
+// Claims are the ID token claims we rely on.
+type Claims struct {
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	Nonce             string `json:"nonce"`
+	AuthorizedParty   string `json:"azp"`
+	// TenantID is set by multi-tenant providers such as Microsoft.
+	TenantID string `json:"tid"`
+	// MultiTenant is set when the token came from a multi-tenant issuer.
+	// Tenant admins assert whatever email they like, so the email must not
+	// be trusted to identify an existing account.
+	MultiTenant bool `json:"-"`
+	jwt.RegisteredClaims
+}
+
+// VerifyIDToken checks the signature against the provider's JWKS as well as
+// iss, aud, azp, exp, iat and the nonce. When the discovered issuer is a
+// tenant template, iss has to be the template filled with the token's tid
+// and tid one of the configured tenants.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*Claims, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(supportedAlgs),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(p.cfg.Leeway),
+	}
+	multiTenant := strings.Contains(md.Issuer, tenantPlaceholder)
+	if !multiTenant {
+		options = append(options, jwt.WithIssuer(md.Issuer))
+	}
+
+	claims := &Claims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.keys.key(ctx, kid, t.Method.Alg())
+	}, options...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if multiTenant {
+		if claims.TenantID == "" || strings.ContainsAny(claims.TenantID, "/{}") {
+			return nil, fmt.Errorf("%w: missing or invalid tid", ErrInvalidIDToken)
+		}
+		if expected := strings.Replace(md.Issuer, tenantPlaceholder, claims.TenantID, 1); claims.Issuer != expected {
+			return nil, fmt.Errorf("%w: issuer %q does not match tenant %q", ErrInvalidIDToken, claims.Issuer, claims.TenantID)
+		}
+		if !slices.Contains(p.cfg.Tenants, claims.TenantID) {
+			return nil, fmt.Errorf("%w: tenant %q is not allowed", ErrInvalidIDToken, claims.TenantID)
+		}
+		claims.MultiTenant = true
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+
+	// With several audiences the token must have been issued to us.
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.cfg.ClientID {
+		return nil, fmt.Errorf("%w: unexpected azp %q", ErrInvalidIDToken, claims.AuthorizedParty)
+	}
+
+	if claims.Nonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+
+	return claims, nil
+}
+
+// discover fetches and caches the discovery document. Failures are not
+// cached, the next call retries.
+func (p *Provider) discover(ctx context.Context) (*Metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var md Metadata
+	status, err := fetchJSON(p.client, req, &md)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("oidc: discovery for %s returned %d", p.cfg.Issuer, status)
+	}
+
+	// OpenID Connect Discovery 1.0 section 4.3.
+	if !issuerMatches(strings.TrimSuffix(md.Issuer, "/"), p.cfg.Issuer) {
+		return nil, fmt.Errorf("oidc: issuer mismatch, expected %q got %q", p.cfg.Issuer, md.Issuer)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc: incomplete discovery document for %s", p.cfg.Issuer)
+	}
+
+	p.metadata = &md
+	p.keys = newRemoteKeySet(md.JWKSURI, p.client)
+
+	return p.metadata, nil
+}
+
+// issuerMatches compares the discovered issuer with the configured one. A
+// tenant template matches any single path segment in its place, such as
+// common or organizations.
+func issuerMatches(discovered, configured string) bool {
+	prefix, suffix, ok := strings.Cut(discovered, tenantPlaceholder)
+	if !ok {
+		return discovered == configured
+	}
+
+	if len(configured) <= len(prefix)+len(suffix) || !strings.HasPrefix(configured, prefix) || !strings.HasSuffix(configured, suffix) {
+		return false
+	}
+	tenant := configured[len(prefix) : len(configured)-len(suffix)]
+	return !strings.Contains(tenant, "/")
+}
+
+// fetchJSON sends req and decodes a JSON body into v whatever the status.
+func fetchJSON(client *http.Client, req *http.Request, v any) (int, error) {
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return 0, err
+	}
+
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, v); err != nil && res.StatusCode == http.StatusOK {
+			return 0, fmt.Errorf("oidc: decoding %s: %w", req.URL, err)
+		}
+	}
+
+	return res.StatusCode, nil
+}
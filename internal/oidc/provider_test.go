@@ -0,0 +1,526 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID     = "client-123"
+	testClientSecret = "client-secret"
+	testRedirectURL  = "http://localhost:3000/v1/auth/oauth/fake/callback"
+)
+
+type authRequest struct {
+	challenge string
+	nonce     string
+}
+
+// fakeProvider is an OpenID provider served by httptest: discovery, JWKS,
+// an authorize step driven by the test and a token endpoint that enforces
+// PKCE and single use codes.
+type fakeProvider struct {
+	server *httptest.Server
+	key    *ecdsa.PrivateKey
+	kid    string
+	// issuer is announced in discovery, it may hold the tenant template.
+	issuer string
+	// claims may change the ID token before it is signed.
+	claims func(c jwt.MapClaims)
+
+	mu    sync.Mutex
+	codes map[string]authRequest
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	f := &fakeProvider{key: key, kid: "key-1", codes: make(map[string]authRequest)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", f.discovery)
+	mux.HandleFunc("/jwks", f.jwks)
+	mux.HandleFunc("/token", f.token)
+	f.server = httptest.NewServer(mux)
+	f.issuer = f.server.URL
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+func (f *fakeProvider) provider(issuer string, tenants ...string) *Provider {
+	return NewProvider(Config{
+		Name:         "fake",
+		Issuer:       issuer,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Leeway:       time.Second * 30,
+		Tenants:      tenants,
+	}, f.server.Client())
+}
+
+// discovery answers every path ending in the well known suffix, so that a
+// configured issuer such as <server>/common/v2.0 is served too.
+func (f *fakeProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasSuffix(r.URL.Path, "/.well-known/openid-configuration") {
+		http.NotFound(w, r)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, Metadata{
+		Issuer:                f.issuer,
+		AuthorizationEndpoint: f.server.URL + "/authorize",
+		TokenEndpoint:         f.server.URL + "/token",
+		JWKSURI:               f.server.URL + "/jwks",
+	})
+}
+
+func (f *fakeProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, jwkSet{Keys: []jwk{ecJWK(f.kid, &f.key.PublicKey)}})
+}
+
+// authorize plays the user agent: it checks the authorization request and
+// returns the code and state the provider would redirect back with.
+func (f *fakeProvider) authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse auth url: %v", err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != f.server.URL+"/authorize" {
+		t.Fatalf("auth url points to %s", got)
+	}
+
+	q := u.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          testRedirectURL,
+		"scope":                 "openid email profile",
+		"code_challenge_method": "S256",
+	}
+	for name, value := range want {
+		if q.Get(name) != value {
+			t.Fatalf("%s = %q, want %q", name, q.Get(name), value)
+		}
+	}
+	for _, name := range []string{"state", "nonce", "code_challenge"} {
+		if q.Get(name) == "" {
+			t.Fatalf("auth url has no %s", name)
+		}
+	}
+
+	code, err = RandomString(16)
+	if err != nil {
+		t.Fatalf("RandomString: %v", err)
+	}
+
+	f.mu.Lock()
+	f.codes[code] = authRequest{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	f.mu.Unlock()
+
+	return code, q.Get("state")
+}
+
+func (f *fakeProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("client_id") != testClientID ||
+		r.PostForm.Get("client_secret") != testClientSecret ||
+		r.PostForm.Get("redirect_uri") != testRedirectURL {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	f.mu.Lock()
+	req, ok := f.codes[r.PostForm.Get("code")]
+	delete(f.codes, r.PostForm.Get("code"))
+	f.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != req.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken, err := f.sign(f.idClaims(req.nonce), f.key, f.kid)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokenResponse{AccessToken: "access", TokenType: "Bearer", IDToken: idToken})
+}
+
+func (f *fakeProvider) idClaims(nonce string) jwt.MapClaims {
+	now := time.Now()
+	c := jwt.MapClaims{
+		"iss":            f.issuer,
+		"sub":            "subject-1",
+		"aud":            testClientID,
+		"exp":            now.Add(time.Hour).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          nonce,
+		"email":          "jane@example.com",
+		"email_verified": true,
+		"name":           "Jane",
+	}
+	if f.claims != nil {
+		f.claims(c)
+	}
+	return c
+}
+
+func (f *fakeProvider) sign(claims jwt.MapClaims, key *ecdsa.PrivateKey, kid string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = kid
+	return token.SignedString(key)
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) jwk {
+	return jwk{
+		Kty: "EC",
+		Kid: kid,
+		Use: "sig",
+		Alg: "ES256",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// start runs the first half of a sign-in the way the auth service does.
+func start(t *testing.T, p *Provider) (authURL, state, nonce, verifier string) {
+	t.Helper()
+
+	var err error
+	if state, err = RandomString(32); err != nil {
+		t.Fatalf("RandomString: %v", err)
+	}
+	if nonce, err = RandomString(32); err != nil {
+		t.Fatalf("RandomString: %v", err)
+	}
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		t.Fatalf("NewPKCE: %v", err)
+	}
+
+	authURL, err = p.AuthCodeURL(context.Background(), state, nonce, challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	return authURL, state, nonce, verifier
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	f := newFakeProvider(t)
+	p := f.provider(f.server.URL)
+	ctx := context.Background()
+
+	authURL, state, nonce, verifier := start(t, p)
+	code, returnedState := f.authorize(t, authURL)
+	if returnedState != state {
+		t.Fatalf("state = %q, want %q", returnedState, state)
+	}
+
+	claims, err := p.Exchange(ctx, code, verifier, nonce)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Subject != "subject-1" || claims.Email != "jane@example.com" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+
+	if _, err := p.Exchange(ctx, code, verifier, nonce); err == nil {
+		t.Fatal("a code was exchanged twice")
+	}
+}
+
+func TestExchangeRequiresPKCEVerifier(t *testing.T) {
+	f := newFakeProvider(t)
+	p := f.provider(f.server.URL)
+
+	authURL, _, nonce, _ := start(t, p)
+	code, _ := f.authorize(t, authURL)
+
+	other, _, err := NewPKCE()
+	if err != nil {
+		t.Fatalf("NewPKCE: %v", err)
+	}
+	if _, err := p.Exchange(context.Background(), code, other, nonce); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Fatalf("expected invalid_grant, got %v", err)
+	}
+}
+
+func TestExchangeChecksNonce(t *testing.T) {
+	f := newFakeProvider(t)
+	p := f.provider(f.server.URL)
+
+	authURL, _, _, verifier := start(t, p)
+	code, _ := f.authorize(t, authURL)
+
+	if _, err := p.Exchange(context.Background(), code, verifier, "another-nonce"); !errors.Is(err, ErrNonceMismatch) {
+		t.Fatalf("expected ErrNonceMismatch, got %v", err)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	f := newFakeProvider(t)
+	p := f.provider(f.server.URL)
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	const nonce = "nonce-1"
+	signed := func(change func(c jwt.MapClaims)) string {
+		c := f.idClaims(nonce)
+		if change != nil {
+			change(c)
+		}
+		token, err := f.sign(c, f.key, f.kid)
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{
+			name:  "valid",
+			token: signed(nil),
+		},
+		{
+			name: "wrong issuer",
+			token: signed(func(c jwt.MapClaims) {
+				c["iss"] = "https://evil.example.com"
+			}),
+			wantErr: ErrInvalidIDToken,
+		},
+		{
+			name: "wrong audience",
+			token: signed(func(c jwt.MapClaims) {
+				c["aud"] = "another-client"
+			}),
+			wantErr: ErrInvalidIDToken,
+		},
+		{
+			name: "several audiences without azp",
+			token: signed(func(c jwt.MapClaims) {
+				c["aud"] = []string{testClientID, "another-client"}
+			}),
+			wantErr: ErrInvalidIDToken,
+		},
+		{
+			name: "several audiences issued to us",
+			token: signed(func(c jwt.MapClaims) {
+				c["aud"] = []string{testClientID, "another-client"}
+				c["azp"] = testClientID
+			}),
+		},
+		{
+			name: "expired",
+			token: signed(func(c jwt.MapClaims) {
+				c["exp"] = time.Now().Add(-time.Hour).Unix()
+			}),
+			wantErr: ErrInvalidIDToken,
+		},
+		{
+			name: "missing sub",
+			token: signed(func(c jwt.MapClaims) {
+				delete(c, "sub")
+			}),
+			wantErr: ErrInvalidIDToken,
+		},
+		{
+			name: "nonce mismatch",
+			token: signed(func(c jwt.MapClaims) {
+				c["nonce"] = "replayed"
+			}),
+			wantErr: ErrNonceMismatch,
+		},
+		{
+			name: "signed by an unknown key",
+			token: func() string {
+				token, err := f.sign(f.idClaims(nonce), otherKey, f.kid)
+				if err != nil {
+					t.Fatalf("sign: %v", err)
+				}
+				return token
+			}(),
+			wantErr: ErrInvalidIDToken,
+		},
+		{
+			name: "hmac keyed with the client secret",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, f.idClaims(nonce))
+				token.Header["kid"] = f.kid
+				signed, err := token.SignedString([]byte(testClientSecret))
+				if err != nil {
+					t.Fatalf("sign: %v", err)
+				}
+				return signed
+			}(),
+			wantErr: ErrInvalidIDToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.VerifyIDToken(context.Background(), tt.token, nonce)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("expected a valid token, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	f := newFakeProvider(t)
+	f.issuer = "https://someone-else.example.com"
+
+	if _, err := f.provider(f.server.URL).VerifyIDToken(context.Background(), "x", ""); err == nil || !strings.Contains(err.Error(), "issuer mismatch") {
+		t.Fatalf("expected an issuer mismatch, got %v", err)
+	}
+}
+
+// TestMultiTenantIssuer mirrors Microsoft's common endpoint, where discovery
+// announces a tenant template and tokens carry the user's tenant.
+func TestMultiTenantIssuer(t *testing.T) {
+	f := newFakeProvider(t)
+	f.issuer = f.server.URL + "/{tenantid}/v2.0"
+	const nonce = "nonce-1"
+	const tenant = "9188040d-6c67-4c5b-b112-36a304b66dad"
+	const otherTenant = "72f988bf-86f1-41af-91ab-2d7cd011db47"
+	p := f.provider(f.server.URL+"/common/v2.0", tenant)
+	signed := func(iss string, tid any) string {
+		c := f.idClaims(nonce)
+		c["iss"] = iss
+		if tid != nil {
+			c["tid"] = tid
+		}
+		token, err := f.sign(c, f.key, f.kid)
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return token
+	}
+	tenantIssuer := f.server.URL + "/" + tenant + "/v2.0"
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"issuer of the token's tenant", signed(tenantIssuer, tenant), true},
+		{"issuer of another tenant", signed(f.server.URL+"/other-tenant/v2.0", tenant), false},
+		{"missing tid", signed(tenantIssuer, nil), false},
+		{"template left as issuer", signed(f.issuer, tenant), false},
+		{"tid with a path", signed(f.server.URL+"/a/b/v2.0", "a/b"), false},
+		{"tenant not in the allowlist", signed(f.server.URL+"/"+otherTenant+"/v2.0", otherTenant), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := p.VerifyIDToken(context.Background(), tt.token, nonce)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("expected a valid token, got %v", err)
+				}
+				if claims.TenantID != tenant || !claims.MultiTenant {
+					t.Fatalf("tid = %q, multi-tenant %v; want %q from a multi-tenant issuer", claims.TenantID, claims.MultiTenant, tenant)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMultiTenantIssuerWithoutTenants(t *testing.T) {
+	f := newFakeProvider(t)
+	f.issuer = f.server.URL + "/{tenantid}/v2.0"
+	p := f.provider(f.server.URL + "/common/v2.0")
+
+	const tenant = "9188040d-6c67-4c5b-b112-36a304b66dad"
+	c := f.idClaims("nonce-1")
+	c["iss"] = f.server.URL + "/" + tenant + "/v2.0"
+	c["tid"] = tenant
+	token, err := f.sign(c, f.key, f.kid)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	if _, err := p.VerifyIDToken(context.Background(), token, "nonce-1"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+	}
+}
+
+func TestIssuerMatches(t *testing.T) {
+	const template = "https://login.microsoftonline.com/{tenantid}/v2.0"
+
+	tests := []struct {
+		discovered string
+		configured string
+		want       bool
+	}{
+		{"https://accounts.google.com", "https://accounts.google.com", true},
+		{"https://accounts.google.com", "https://accounts.google.com/x", false},
+		{template, "https://login.microsoftonline.com/common/v2.0", true},
+		{template, "https://login.microsoftonline.com/organizations/v2.0", true},
+		{template, "https://login.microsoftonline.com/v2.0", false},
+		{template, "https://login.microsoftonline.com/a/b/v2.0", false},
+		{template, "https://evil.example.com/common/v2.0", false},
+	}
+
+	for _, tt := range tests {
+		if got := issuerMatches(tt.discovered, tt.configured); got != tt.want {
+			t.Errorf("issuerMatches(%q, %q) = %v, want %v", tt.discovered, tt.configured, got, tt.want)
+		}
+	}
+}
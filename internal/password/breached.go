@@ -0,0 +1,119 @@
+package password
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// BreachList tells whether a password is known from a data breach.
+type BreachList interface {
+	Contains(ctx context.Context, password string) (bool, error)
+}
+
+const rangePrefixLength = 5
+
+//go:embed breached_passwords.txt
+var bundledHashes string
+
+// HashList is an in-memory set of SHA-1 password hashes bucketed by their
+// first five hex characters, the layout of the Pwned Passwords range API.
+// A lookup only ever reads the bucket of the password's prefix, so the same
+// BreachList can be backed by a remote range endpoint later without
+// sending full hashes anywhere.
+type HashList struct {
+	buckets map[string]map[string]struct{}
+	size    int
+}
+
+func NewHashList() *HashList {
+	return &HashList{buckets: make(map[string]map[string]struct{})}
+}
+
+// BundledHashList returns a HashList with the most common breached
+// passwords shipped with the binary.
+func BundledHashList() (*HashList, error) {
+	l := NewHashList()
+	if err := l.Load(strings.NewReader(bundledHashes)); err != nil {
+		return nil, fmt.Errorf("bundled breached passwords: %w", err)
+	}
+	return l, nil
+}
+
+// Load adds the hashes read from r. Every line is a SHA-1 hex digest,
+// optionally followed by ":<count>" as in the Pwned Passwords downloads.
+// Blank lines and lines starting with # are skipped.
+func (l *HashList) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(text, ":")
+		hash = strings.ToUpper(hash)
+
+		if len(hash) != sha1.Size*2 {
+			return fmt.Errorf("line %d: not a SHA-1 hash", line)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		l.add(hash)
+	}
+
+	return scanner.Err()
+}
+
+// LoadFile adds the hashes from the file at path, see Load.
+func (l *HashList) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := l.Load(f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Len returns how many hashes the list holds.
+func (l *HashList) Len() int {
+	return l.size
+}
+
+func (l *HashList) Contains(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	bucket := l.buckets[hash[:rangePrefixLength]]
+	_, found := bucket[hash[rangePrefixLength:]]
+
+	return found, nil
+}
+
+func (l *HashList) add(hash string) {
+	prefix, suffix := hash[:rangePrefixLength], hash[rangePrefixLength:]
+
+	bucket, ok := l.buckets[prefix]
+	if !ok {
+		bucket = make(map[string]struct{})
+		l.buckets[prefix] = bucket
+	}
+
+	if _, exists := bucket[suffix]; !exists {
+		bucket[suffix] = struct{}{}
+		l.size++
+	}
+}
@@ -1,13 +1,166 @@
+// Package password hashes and verifies user passwords. New hashes use the
+// configured algorithm (argon2id by default); hashes produced by any
+// registered scheme keep verifying, and Verify reports when a stored hash
+// should be upgraded.
 package password
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var ErrUnknownHash = errors.New("password: unknown hash format")
+
+// Scheme is one hashing algorithm.
+type Scheme interface {
+	// Name is the identifier used in configuration, e.g. "argon2id".
+	Name() string
+	Hash(password string) (string, error)
+	// Verify reports whether password matches encoded.
+	Verify(encoded, password string) (bool, error)
+	// Recognizes reports whether encoded was produced by this scheme.
+	Recognizes(encoded string) bool
+	// NeedsRehash reports whether encoded was produced with weaker
+	// parameters than the scheme is configured with.
+	NeedsRehash(encoded string) bool
+}
+
+type Config struct {
+	// Algorithm used for new hashes, "argon2id" or "bcrypt".
+	Algorithm  string
+	Argon2     Argon2Params
+	BcryptCost int
+}
+
+// Hasher hashes with its primary scheme and verifies with all of them.
+type Hasher struct {
+	primary Scheme
+	schemes []Scheme
+}
+
+// New builds a Hasher from cfg. Both argon2id and bcrypt are always
+// available for verification.
+func New(cfg Config) (*Hasher, error) {
+	schemes := []Scheme{
+		NewArgon2id(cfg.Argon2),
+		NewBcrypt(cfg.BcryptCost),
+	}
+
+	return NewHasher(cfg.Algorithm, schemes...)
+}
+
+// NewHasher builds a Hasher whose primary scheme is the one called primary.
+func NewHasher(primary string, schemes ...Scheme) (*Hasher, error) {
+	h := &Hasher{schemes: schemes}
+
+	for _, s := range schemes {
+		if s.Name() == primary {
+			h.primary = s
+		}
+	}
+
+	if h.primary == nil {
+		return nil, fmt.Errorf("password: unsupported algorithm %q", primary)
+	}
+
+	return h, nil
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.primary.Hash(password)
+}
+
+// trimmedPrefix marks the hashes stored while passwords were still trimmed
+// before hashing, migration 000028 adds it to every hash of that time.
+const trimmedPrefix = "trimmed:"
+
+// Verify checks password against encoded. needsRehash is only meaningful
+// when ok is true and means encoded should be replaced by a fresh Hash.
+//
+// Hashes marked with trimmedPrefix may be of the trimmed password, so a
+// password with surrounding whitespace also matches them once trimmed.
+// Any match on such a hash needs a rehash, which stores the password as
+// typed and drops the marker. Every other hash needs the exact password.
+func (h *Hasher) Verify(encoded, password string) (ok bool, needsRehash bool, err error) {
+	legacy, found := strings.CutPrefix(encoded, trimmedPrefix)
+	if !found {
+		return h.verify(encoded, password)
+	}
+
+	ok, _, err = h.verify(legacy, password)
+	if err == nil && !ok {
+		if trimmed := strings.TrimSpace(password); trimmed != password {
+			ok, _, err = h.verify(legacy, trimmed)
+		}
+	}
+	if err != nil {
+		return false, false, err
+	}
+
+	return ok, ok, nil
+}
+
+func (h *Hasher) verify(encoded, password string) (ok bool, needsRehash bool, err error) {
+	for _, s := range h.schemes {
+		if !s.Recognizes(encoded) {
+			continue
+		}
+
+		ok, err := s.Verify(encoded, password)
+		if err != nil || !ok {
+			return false, false, err
+		}
+
+		return true, s != h.primary || s.NeedsRehash(encoded), nil
+	}
+
+	return false, false, ErrUnknownHash
+}
+
+var (
+	mu            sync.RWMutex
+	defaultHasher = mustDefault()
+)
+
+func mustDefault() *Hasher {
+	h, err := New(Config{
+		Algorithm:  Argon2idName,
+		Argon2:     DefaultArgon2Params,
+		BcryptCost: DefaultBcryptCost,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// SetDefault replaces the Hasher used by the package level functions. It
+// is meant to be called once at startup.
+func SetDefault(h *Hasher) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultHasher = h
+}
+
+// Default returns the Hasher used by the package level functions.
+func Default() *Hasher {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultHasher
+}
 
 func Hash(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 12)
-	return string(bytes), err
+	return Default().Hash(password)
+}
+
+// Verify checks password with the default Hasher, see Hasher.Verify.
+func Verify(encoded, password string) (ok bool, needsRehash bool, err error) {
+	return Default().Verify(encoded, password)
 }
 
 func CheckHash(hashedPwd, plainPwd string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPwd), []byte(plainPwd))
-	return err == nil
+	ok, _, err := Verify(hashedPwd, plainPwd)
+	return err == nil && ok
 }
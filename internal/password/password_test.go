@@ -0,0 +1,126 @@
+package password
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func testHasher(t *testing.T, primary string) *Hasher {
+	t.Helper()
+
+	h, err := NewHasher(primary,
+		NewArgon2id(Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1}),
+		NewBcrypt(bcrypt.MinCost),
+	)
+	if err != nil {
+		t.Fatalf("NewHasher: %v", err)
+	}
+	return h
+}
+
+func TestVerify(t *testing.T) {
+	h := testHasher(t, Argon2idName)
+
+	exact, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	padded, err := h.Hash("  correct horse ")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	// Hashes from before whitespace was significant are of the trimmed
+	// password and carry the marker added by the migration.
+	legacy := trimmedPrefix + exact
+	legacyPadded := trimmedPrefix + padded
+
+	tests := []struct {
+		name        string
+		encoded     string
+		password    string
+		ok          bool
+		needsRehash bool
+	}{
+		{"exact", exact, "correct horse", true, false},
+		{"whitespace around a new hash", exact, " correct horse\t", false, false},
+		{"padded hash as typed", padded, "  correct horse ", true, false},
+		{"padded hash without its whitespace", padded, "correct horse", false, false},
+		{"padded hash with other whitespace", padded, " correct horse ", false, false},
+		{"legacy hash exact", legacy, "correct horse", true, true},
+		{"whitespace around a legacy hash", legacy, " correct horse\t", true, true},
+		{"legacy hash wrong password", legacy, "battery staple", false, false},
+		{"legacy hash wrong password with whitespace", legacy, " battery staple ", false, false},
+		{"legacy hash inner whitespace still counts", legacy, "correcthorse", false, false},
+		// Hashed exactly between the change and the migration.
+		{"marked padded hash as typed", legacyPadded, "  correct horse ", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := h.Verify(tt.encoded, tt.password)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if ok != tt.ok {
+				t.Fatalf("Verify = %v, want %v", ok, tt.ok)
+			}
+			if needsRehash != tt.needsRehash {
+				t.Fatalf("needsRehash = %v, want %v", needsRehash, tt.needsRehash)
+			}
+		})
+	}
+}
+
+// TestVerifyLegacyRehash checks that the rehash done after a legacy match
+// stores the password as typed, and that the trimmed form stops matching.
+func TestVerifyLegacyRehash(t *testing.T) {
+	h := testHasher(t, Argon2idName)
+
+	old, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	typed := " correct horse "
+	ok, needsRehash, err := h.Verify(trimmedPrefix+old, typed)
+	if err != nil || !ok || !needsRehash {
+		t.Fatalf("Verify = %v, %v, %v; want a match that needs a rehash", ok, needsRehash, err)
+	}
+
+	rehashed, err := h.Hash(typed)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if ok, needsRehash, err := h.Verify(rehashed, typed); err != nil || !ok || needsRehash {
+		t.Fatalf("Verify = %v, %v, %v; want a match without a rehash", ok, needsRehash, err)
+	}
+	if ok, _, err := h.Verify(rehashed, "correct horse"); err != nil || ok {
+		t.Fatalf("Verify = %v, %v; want the trimmed password to be refused", ok, err)
+	}
+}
+
+func TestVerifyNeedsRehash(t *testing.T) {
+	old := testHasher(t, BcryptName)
+	encoded, err := old.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	h := testHasher(t, Argon2idName)
+
+	ok, needsRehash, err := h.Verify(encoded, "correct horse")
+	if err != nil || !ok || !needsRehash {
+		t.Fatalf("Verify = %v, %v, %v; want a match that needs a rehash", ok, needsRehash, err)
+	}
+
+	ok, needsRehash, err = h.Verify(trimmedPrefix+encoded, " correct horse ")
+	if err != nil || !ok || !needsRehash {
+		t.Fatalf("Verify = %v, %v, %v; want a legacy bcrypt match that needs a rehash", ok, needsRehash, err)
+	}
+
+	if _, _, err := h.Verify("plaintext", "plaintext"); !errors.Is(err, ErrUnknownHash) {
+		t.Fatalf("expected ErrUnknownHash, got %v", err)
+	}
+}
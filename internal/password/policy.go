@@ -0,0 +1,138 @@
+package password
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BcryptMaxBytes is the longest input bcrypt looks at, anything after is
+// silently ignored, so longer passwords are rejected instead.
+const BcryptMaxBytes = 72
+
+type PolicyConfig struct {
+	MinLength int
+	// MaxBytes caps the UTF-8 length, BcryptMaxBytes by default.
+	MaxBytes int
+	// MinCharClasses is how many of lower case, upper case, digits and
+	// symbols a password has to contain.
+	MinCharClasses int
+	// DisallowPersonalInfo rejects passwords containing the email or the
+	// username.
+	DisallowPersonalInfo bool
+}
+
+// UserInfo is what a password is checked against besides the rules.
+type UserInfo struct {
+	Email    string
+	Username string
+}
+
+// PolicyError lists every rule a password broke.
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return "password " + strings.Join(e.Violations, ", ")
+}
+
+// Fields returns the violations keyed by request field, ready to be sent
+// back as structured validation errors.
+func (e *PolicyError) Fields() map[string][]string {
+	return map[string][]string{"password": e.Violations}
+}
+
+type Policy struct {
+	cfg      PolicyConfig
+	breached BreachList
+}
+
+// NewPolicy builds a Policy, breached may be nil to skip the breach check.
+func NewPolicy(cfg PolicyConfig, breached BreachList) *Policy {
+	if cfg.MaxBytes <= 0 || cfg.MaxBytes > BcryptMaxBytes {
+		cfg.MaxBytes = BcryptMaxBytes
+	}
+	return &Policy{cfg: cfg, breached: breached}
+}
+
+// Check returns a *PolicyError when password breaks any rule. Other errors
+// come from the breach list.
+func (p *Policy) Check(ctx context.Context, password string, user UserInfo) error {
+	var violations []string
+
+	if utf8.RuneCountInString(password) < p.cfg.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.cfg.MinLength))
+	}
+
+	if len(password) > p.cfg.MaxBytes {
+		violations = append(violations, fmt.Sprintf("must not be longer than %d bytes", p.cfg.MaxBytes))
+	}
+
+	if classes := charClasses(password); classes < p.cfg.MinCharClasses {
+		violations = append(violations, fmt.Sprintf("must contain at least %d of: lower case letters, upper case letters, digits, symbols", p.cfg.MinCharClasses))
+	}
+
+	if p.cfg.DisallowPersonalInfo && containsPersonalInfo(password, user) {
+		violations = append(violations, "must not contain your email or username")
+	}
+
+	if p.breached != nil && len(violations) == 0 {
+		breached, err := p.breached.Contains(ctx, password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, "has appeared in a data breach, please choose another one")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+
+	return nil
+}
+
+func charClasses(password string) int {
+	var lower, upper, digit, symbol int
+
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+
+	return lower + upper + digit + symbol
+}
+
+// minPersonalInfoLength keeps very short usernames from rejecting half of
+// all passwords.
+const minPersonalInfoLength = 3
+
+func containsPersonalInfo(password string, user UserInfo) bool {
+	password = strings.ToLower(password)
+
+	candidates := []string{user.Username, user.Email}
+	if local, _, ok := strings.Cut(user.Email, "@"); ok {
+		candidates = append(candidates, local)
+	}
+
+	for _, c := range candidates {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if len(c) >= minPersonalInfoLength && strings.Contains(password, c) {
+			return true
+		}
+	}
+
+	return false
+}
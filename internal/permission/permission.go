@@ -0,0 +1,208 @@
+// Package permission matches permission names against a role's grants.
+//
+// Names are namespaced with ":", e.g. "user:read". A grant may use "*" in
+// place of any segment: "user:*" matches every user permission, "*:read"
+// every read permission and "*" alone everything. A trailing "*" also
+// covers deeper names, "user:*" matches "user:profile:read". A grant
+// starting with "!" denies whatever it matches, and a deny always wins
+// over any allow.
+package permission
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	separator = ":"
+	wildcard  = "*"
+	denyMark  = "!"
+)
+
+var grantPattern = regexp.MustCompile(`^!?(\*|[a-z][a-z0-9_]*)(:(\*|[a-z][a-z0-9_]*))*$`)
+
+// Valid reports whether grant is a well formed permission or pattern.
+// Single segment names other than "*" are refused, permissions are
+// always namespaced.
+func Valid(grant string) bool {
+	if !grantPattern.MatchString(grant) {
+		return false
+	}
+	name := strings.TrimPrefix(grant, denyMark)
+	return name == wildcard || strings.Contains(name, separator)
+}
+
+// Set is a compiled list of grants. It is immutable and safe for
+// concurrent use.
+type Set struct {
+	allow rules
+	deny  rules
+	// denied keeps the deny patterns for Covers.
+	denied []string
+}
+
+// rules splits grants by shape so the common ones are map lookups.
+type rules struct {
+	// all is set by a bare "*".
+	all bool
+	// exact holds grants without wildcards.
+	exact map[string]struct{}
+	// prefixes holds grants whose only wildcard is the last segment,
+	// keyed by what comes before ":*".
+	prefixes map[string]struct{}
+	// byLast holds patterns ending in a literal segment, e.g. "*:read",
+	// keyed by that segment so only candidates are walked.
+	byLast map[string][][]string
+	// patterns holds everything else, split into segments.
+	patterns [][]string
+}
+
+// Compile builds a Set from grants. Malformed grants are ignored, they
+// could never match a valid name.
+func Compile(grants []string) *Set {
+	s := &Set{}
+	for _, grant := range grants {
+		if !Valid(grant) {
+			continue
+		}
+		if name, ok := strings.CutPrefix(grant, denyMark); ok {
+			s.deny.add(name)
+			s.denied = append(s.denied, name)
+		} else {
+			s.allow.add(grant)
+		}
+	}
+	return s
+}
+
+// Allows reports whether name is granted and not denied.
+func (s *Set) Allows(name string) bool {
+	if s == nil {
+		return false
+	}
+	return !s.deny.match(name) && s.allow.match(name)
+}
+
+// Covers reports whether s allows every name that grant matches. For a
+// plain name that is Allows. A pattern also must not overlap a deny:
+// "user:*" is not covered by "*" together with "!user:delete".
+func (s *Set) Covers(grant string) bool {
+	if !Valid(grant) || strings.HasPrefix(grant, denyMark) || !s.Allows(grant) {
+		return false
+	}
+
+	segments := strings.Split(grant, separator)
+	for _, deny := range s.denied {
+		if overlaps(segments, strings.Split(deny, separator)) {
+			return false
+		}
+	}
+	return true
+}
+
+// overlaps reports whether some name matches both patterns.
+func overlaps(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if (a[i] == wildcard && i == len(a)-1) || (b[i] == wildcard && i == len(b)-1) {
+			return true
+		}
+		if a[i] != b[i] && a[i] != wildcard && b[i] != wildcard {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
+
+func (r *rules) add(grant string) {
+	switch {
+	case grant == wildcard:
+		r.all = true
+	case !strings.Contains(grant, wildcard):
+		if r.exact == nil {
+			r.exact = make(map[string]struct{})
+		}
+		r.exact[grant] = struct{}{}
+	case strings.HasSuffix(grant, separator+wildcard) && !strings.Contains(strings.TrimSuffix(grant, separator+wildcard), wildcard):
+		if r.prefixes == nil {
+			r.prefixes = make(map[string]struct{})
+		}
+		r.prefixes[strings.TrimSuffix(grant, separator+wildcard)] = struct{}{}
+	default:
+		segments := strings.Split(grant, separator)
+		last := segments[len(segments)-1]
+		if last == wildcard {
+			r.patterns = append(r.patterns, segments)
+			return
+		}
+		if r.byLast == nil {
+			r.byLast = make(map[string][][]string)
+		}
+		r.byLast[last] = append(r.byLast[last], segments)
+	}
+}
+
+func (r *rules) match(name string) bool {
+	if r.all {
+		return true
+	}
+	if _, ok := r.exact[name]; ok {
+		return true
+	}
+
+	// Every proper prefix ending before a ":" is a candidate for "prefix:*".
+	if len(r.prefixes) > 0 {
+		for i := 0; i < len(name); i++ {
+			if name[i] != ':' {
+				continue
+			}
+			if _, ok := r.prefixes[name[:i]]; ok {
+				return true
+			}
+		}
+	}
+
+	if len(r.byLast) > 0 {
+		last := name[strings.LastIndex(name, separator)+1:]
+		for _, pattern := range r.byLast[last] {
+			if matchSegments(pattern, name) {
+				return true
+			}
+		}
+	}
+
+	for _, pattern := range r.patterns {
+		if matchSegments(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments walks name segment by segment without allocating. A "*"
+// matches one segment, or all remaining ones when it is the last segment
+// of the pattern.
+func matchSegments(pattern []string, name string) bool {
+	for i, segment := range pattern {
+		if name == "" {
+			return false
+		}
+
+		last := i == len(pattern)-1
+		if segment == wildcard && last {
+			return true
+		}
+
+		current, rest, more := strings.Cut(name, separator)
+		if segment != wildcard && segment != current {
+			return false
+		}
+		if last {
+			return !more
+		}
+		if !more {
+			return false
+		}
+		name = rest
+	}
+	return false
+}
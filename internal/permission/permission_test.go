@@ -0,0 +1,258 @@
+package permission
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		grant string
+		want  bool
+	}{
+		{"user:read", true},
+		{"user:profile:read", true},
+		{"user:*", true},
+		{"*:read", true},
+		{"*", true},
+		{"!user:delete", true},
+		{"!*", true},
+		{"service_account:manage", true},
+		{"user", false},
+		{"!user", false},
+		{"", false},
+		{"User:read", false},
+		{"user:", false},
+		{":read", false},
+		{"user::read", false},
+		{"user:re*d", false},
+		{"1user:read", false},
+		{"!!user:read", false},
+		{"user:read ", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.grant, func(t *testing.T) {
+			if got := Valid(tt.grant); got != tt.want {
+				t.Fatalf("Valid(%q) = %v, want %v", tt.grant, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		name   string
+		grants []string
+		allow  []string
+		refuse []string
+	}{
+		{
+			name:   "exact",
+			grants: []string{"user:read"},
+			allow:  []string{"user:read"},
+			refuse: []string{"user:write", "user:read:all", "post:read", "user"},
+		},
+		{
+			name:   "resource wildcard",
+			grants: []string{"user:*"},
+			allow:  []string{"user:read", "user:delete", "user:profile:read"},
+			refuse: []string{"post:read", "user", "users:read"},
+		},
+		{
+			name:   "action wildcard",
+			grants: []string{"*:read"},
+			allow:  []string{"user:read", "post:read"},
+			refuse: []string{"user:write", "user:profile:read", "user:read:all"},
+		},
+		{
+			name:   "middle wildcard",
+			grants: []string{"user:*:read"},
+			allow:  []string{"user:profile:read", "user:sessions:read"},
+			refuse: []string{"user:read", "user:profile:write", "post:profile:read"},
+		},
+		{
+			name:   "trailing wildcard after a wildcard",
+			grants: []string{"*:profile:*"},
+			allow:  []string{"user:profile:read", "user:profile:avatar:write"},
+			refuse: []string{"user:profile", "user:read"},
+		},
+		{
+			name:   "everything",
+			grants: []string{"*"},
+			allow:  []string{"user:read", "rbac:manage", "user:profile:read"},
+		},
+		{
+			name:   "deny beats exact allow",
+			grants: []string{"user:delete", "!user:delete"},
+			refuse: []string{"user:delete"},
+		},
+		{
+			name:   "deny beats wildcard allow",
+			grants: []string{"*", "!user:delete"},
+			allow:  []string{"user:read", "post:delete"},
+			refuse: []string{"user:delete"},
+		},
+		{
+			name:   "wildcard deny",
+			grants: []string{"user:*", "!*:delete"},
+			allow:  []string{"user:read"},
+			refuse: []string{"user:delete"},
+		},
+		{
+			name:   "deny order does not matter",
+			grants: []string{"!admin:*", "admin:users:read"},
+			refuse: []string{"admin:users:read"},
+		},
+		{
+			name:   "deny alone grants nothing",
+			grants: []string{"!user:delete"},
+			refuse: []string{"user:read", "user:delete"},
+		},
+		{
+			name:   "malformed grants are ignored",
+			grants: []string{"user", "USER:READ", "user:re*d"},
+			refuse: []string{"user", "user:read"},
+		},
+		{
+			name:   "no grants",
+			refuse: []string{"user:read"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := Compile(tt.grants)
+			for _, name := range tt.allow {
+				if !set.Allows(name) {
+					t.Errorf("%v should allow %q", tt.grants, name)
+				}
+			}
+			for _, name := range tt.refuse {
+				if set.Allows(name) {
+					t.Errorf("%v should refuse %q", tt.grants, name)
+				}
+			}
+		})
+	}
+}
+
+func TestNilSetAllowsNothing(t *testing.T) {
+	var set *Set
+	if set.Allows("user:read") {
+		t.Fatal("a nil set allowed a permission")
+	}
+}
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		name   string
+		grants []string
+		cover  []string
+		refuse []string
+	}{
+		{
+			name:   "exact",
+			grants: []string{"user:read"},
+			cover:  []string{"user:read"},
+			refuse: []string{"user:*", "*", "user:write", "!user:read"},
+		},
+		{
+			name:   "resource wildcard",
+			grants: []string{"user:*"},
+			cover:  []string{"user:read", "user:*", "user:profile:*", "user:*:read"},
+			refuse: []string{"*", "*:read", "post:*"},
+		},
+		{
+			name:   "everything",
+			grants: []string{"*"},
+			cover:  []string{"*", "user:*", "*:read", "user:read"},
+		},
+		{
+			name:   "pattern overlapping a deny",
+			grants: []string{"*", "!user:delete"},
+			cover:  []string{"post:*", "user:read", "*:read", "user:*:delete"},
+			refuse: []string{"*", "user:*", "*:delete", "user:delete"},
+		},
+		{
+			name:   "deny pattern",
+			grants: []string{"*", "!*:delete"},
+			cover:  []string{"user:read", "user:profile:*"},
+			refuse: []string{"user:*", "*:*", "user:delete"},
+		},
+		{
+			name:   "malformed",
+			grants: []string{"*"},
+			refuse: []string{"user", "", "user::read"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := Compile(tt.grants)
+			for _, grant := range tt.cover {
+				if !set.Covers(grant) {
+					t.Errorf("%v should cover %q", tt.grants, grant)
+				}
+			}
+			for _, grant := range tt.refuse {
+				if set.Covers(grant) {
+					t.Errorf("%v should not cover %q", tt.grants, grant)
+				}
+			}
+		})
+	}
+}
+
+// benchmarkGrants builds n grants mixing every shape, about the size of a
+// large role with inherited permissions.
+func benchmarkGrants(n int) []string {
+	grants := make([]string, 0, n)
+	for i := 0; len(grants) < n; i++ {
+		resource := fmt.Sprintf("resource%d", i)
+		grants = append(grants,
+			resource+":read",
+			resource+":write",
+			resource+":items:list",
+			resource+":admin:*",
+			"*:action"+fmt.Sprint(i),
+			resource+":*:export",
+			"!"+resource+":delete",
+		)
+	}
+	return grants[:n]
+}
+
+func BenchmarkAllows(b *testing.B) {
+	for _, n := range []int{100, 500, 1000} {
+		set := Compile(benchmarkGrants(n))
+		names := []struct {
+			label string
+			name  string
+		}{
+			{"exact", "resource42:write"},
+			{"prefix", "resource42:admin:users:read"},
+			{"last segment", "billing:action17"},
+			{"middle wildcard", "resource42:reports:export"},
+			{"denied", "resource42:delete"},
+			{"miss", "unknown:thing:read"},
+		}
+
+		for _, nn := range names {
+			b.Run(fmt.Sprintf("grants=%d/%s", n, nn.label), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					set.Allows(nn.name)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkCompile(b *testing.B) {
+	grants := benchmarkGrants(500)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Compile(grants)
+	}
+}
@@ -0,0 +1,148 @@
+// Package policy authorizes actions on a single resource, for rules that
+// depend on the resource itself and not only on the caller's role, e.g.
+// "the author may edit their own post, moderators may edit anyone's".
+//
+// A Policy pairs a Loader, which fetches the resource named by the
+// request, with Rules. The request is allowed as soon as one rule allows
+// it. Modules only write the loader, the rules are shared.
+package policy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/mifaabiyyu/backend-go/internal/hierarchy"
+	"github.com/mifaabiyyu/backend-go/internal/permission"
+)
+
+var (
+	// ErrNotFound is returned by loaders when the resource does not exist.
+	ErrNotFound  = errors.New("resource not found")
+	ErrForbidden = errors.New("you don't have permission to perform this action on this resource")
+)
+
+// Resource is what a Loader returns.
+type Resource interface {
+	// OwnerID is the id of the user owning the resource, 0 when no user
+	// does.
+	OwnerID() int64
+}
+
+// Loader fetches the resource a request refers to, ErrNotFound when there
+// is none.
+type Loader func(r *http.Request) (Resource, error)
+
+// Subject is the caller a policy is evaluated for.
+type Subject struct {
+	// UserID is 0 for service accounts.
+	UserID int64
+	RoleID int64
+	// Permissions are the effective grants of the role.
+	Permissions *permission.Set
+	// Scopes limit a personal access token, nil for any other caller.
+	Scopes *permission.Set
+	Roles  *hierarchy.Guard
+}
+
+// Can reports whether the subject holds permission, within the token
+// scopes if any.
+func (s Subject) Can(name string) bool {
+	if s.Scopes != nil && !s.Scopes.Allows(name) {
+		return false
+	}
+	return s.Permissions.Allows(name)
+}
+
+// Rule decides whether subject may act on res. Errors are reserved for
+// failures, a refusal is false.
+type Rule func(ctx context.Context, subject Subject, res Resource) (bool, error)
+
+// Owner allows the user owning the resource. Scoped personal access
+// tokens are refused, ownership is not a permission a token can be
+// limited to and would otherwise widen it.
+func Owner() Rule {
+	return func(_ context.Context, s Subject, res Resource) (bool, error) {
+		return s.UserID != 0 && s.Scopes == nil && res.OwnerID() == s.UserID, nil
+	}
+}
+
+// Permission allows callers holding name, whoever owns the resource.
+func Permission(name string) Rule {
+	return func(_ context.Context, s Subject, _ Resource) (bool, error) {
+		return s.Can(name), nil
+	}
+}
+
+// MinLevel allows callers whose role is at least level.
+func MinLevel(level int32) Rule {
+	return func(ctx context.Context, s Subject, _ Resource) (bool, error) {
+		err := s.Roles.RequireLevel(ctx, s.RoleID, level)
+		switch {
+		case err == nil:
+			return true, nil
+		case errors.Is(err, hierarchy.ErrLevelTooLow):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+}
+
+// All allows the request only when every rule does, e.g. an owner who
+// also holds a permission.
+func All(rules ...Rule) Rule {
+	return func(ctx context.Context, s Subject, res Resource) (bool, error) {
+		for _, rule := range rules {
+			ok, err := rule(ctx, s, res)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+type Policy struct {
+	Load Loader
+	// Rules are alternatives, the first one allowing the request wins.
+	Rules []Rule
+	// Conceal answers refused callers as if the resource did not exist,
+	// for resources whose existence is private.
+	Conceal bool
+}
+
+// Authorize checks res against the rules. It returns ErrForbidden, or
+// ErrNotFound when the policy conceals refusals.
+func (p Policy) Authorize(ctx context.Context, subject Subject, res Resource) error {
+	for _, rule := range p.Rules {
+		ok, err := rule(ctx, subject, res)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	if p.Conceal {
+		return ErrNotFound
+	}
+	return ErrForbidden
+}
+
+type contextKey string
+
+const resourceCtx contextKey = "resource"
+
+// WithResource stores the authorized resource so the handler does not have
+// to load it again.
+func WithResource(ctx context.Context, res Resource) context.Context {
+	return context.WithValue(ctx, resourceCtx, res)
+}
+
+// ResourceFromContext returns the resource authorized for the request.
+func ResourceFromContext[T Resource](ctx context.Context) (T, bool) {
+	res, ok := ctx.Value(resourceCtx).(T)
+	return res, ok
+}
@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type LoginAttemptStore struct {
+	rdb *redis.Client
+}
+
+func (s *LoginAttemptStore) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	cacheKey := fmt.Sprintf("login-attempts-%s", key)
+
+	n, err := s.rdb.Incr(ctx, cacheKey).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// The window starts with the first failure.
+	if n == 1 {
+		if err := s.rdb.Expire(ctx, cacheKey, window).Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	return n, nil
+}
+
+func (s *LoginAttemptStore) Lock(ctx context.Context, key string, ttl time.Duration) error {
+	cacheKey := fmt.Sprintf("login-lock-%s", key)
+	return s.rdb.SetEX(ctx, cacheKey, 1, ttl).Err()
+}
+
+func (s *LoginAttemptStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	cacheKey := fmt.Sprintf("login-lock-%s", key)
+
+	ttl, err := s.rdb.PTTL(ctx, cacheKey).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// Negative values mean the key does not exist or has no expiry.
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+func (s *LoginAttemptStore) Reset(ctx context.Context, key string) error {
+	return s.rdb.Del(ctx,
+		fmt.Sprintf("login-attempts-%s", key),
+		fmt.Sprintf("login-lock-%s", key),
+	).Err()
+}
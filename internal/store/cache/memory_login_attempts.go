@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepThreshold bounds how many keys may pile up before expired ones are
+// dropped.
+const sweepThreshold = 1024
+
+type counter struct {
+	count     int64
+	expiresAt time.Time
+}
+
+// MemoryLoginAttemptStore keeps failed login counters in process for when
+// Redis is disabled. Counters are lost on restart and are not shared
+// between instances.
+type MemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	counters map[string]counter
+	locks    map[string]time.Time
+	now      func() time.Time
+}
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{
+		counters: make(map[string]counter),
+		locks:    make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+func (s *MemoryLoginAttemptStore) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	c, exists := s.counters[key]
+	if !exists || !now.Before(c.expiresAt) {
+		c = counter{expiresAt: now.Add(window)}
+	}
+	c.count++
+	s.counters[key] = c
+
+	return c.count, nil
+}
+
+func (s *MemoryLoginAttemptStore) Lock(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.locks[key] = s.now().Add(ttl)
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	until, exists := s.locks[key]
+	if !exists {
+		return 0, nil
+	}
+
+	ttl := until.Sub(s.now())
+	if ttl <= 0 {
+		delete(s.locks, key)
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+func (s *MemoryLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.counters, key)
+	delete(s.locks, key)
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) sweep(now time.Time) {
+	if len(s.counters)+len(s.locks) < sweepThreshold {
+		return
+	}
+
+	for key, c := range s.counters {
+		if !now.Before(c.expiresAt) {
+			delete(s.counters, key)
+		}
+	}
+	for key, until := range s.locks {
+		if !now.Before(until) {
+			delete(s.locks, key)
+		}
+	}
+}
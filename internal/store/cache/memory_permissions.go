@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mifaabiyyu/backend-go/internal/permission"
+)
+
+// MemoryPermissionStore keeps compiled permission sets in process for when
+// Redis is disabled. Other instances learn about changes through the
+// Postgres notifications, see api.Application.RunPermissionListener.
+type MemoryPermissionStore struct {
+	mu      sync.RWMutex
+	version int64
+	sets    map[int32]*permission.Set
+}
+
+func NewMemoryPermissionStore() *MemoryPermissionStore {
+	return &MemoryPermissionStore{
+		sets: make(map[int32]*permission.Set),
+	}
+}
+
+func (s *MemoryPermissionStore) Get(ctx context.Context, roleID int32) (*permission.Set, int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.sets[roleID], s.version, nil
+}
+
+func (s *MemoryPermissionStore) Set(ctx context.Context, version int64, roleID int32, grants []string) error {
+	set := permission.Compile(grants)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if version != s.version {
+		return nil
+	}
+	s.sets[roleID] = set
+	return nil
+}
+
+func (s *MemoryPermissionStore) Invalidate(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.version++
+	clear(s.sets)
+	return nil
+}
@@ -2,14 +2,19 @@ package cache
 
 import (
 	"context"
+	"time"
 
 	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/permission"
 	"github.com/stretchr/testify/mock"
 )
 
 func NewMockStore() Storage {
 	return Storage{
-		Users: &MockUserStore{},
+		Users:         &MockUserStore{},
+		RevokedTokens: &MockRevokedTokenStore{},
+		LoginAttempts: &MockLoginAttemptStore{},
+		Permissions:   &MockPermissionStore{},
 	}
 }
 
@@ -30,3 +35,61 @@ func (m *MockUserStore) Set(ctx context.Context, user *sqlc.User) error {
 func (m *MockUserStore) Delete(ctx context.Context, userID int64) {
 	m.Called(userID)
 }
+
+type MockRevokedTokenStore struct {
+	mock.Mock
+}
+
+func (m *MockRevokedTokenStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	args := m.Called(jti, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockRevokedTokenStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	args := m.Called(jti)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockLoginAttemptStore struct {
+	mock.Mock
+}
+
+func (m *MockLoginAttemptStore) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	args := m.Called(key, window)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockLoginAttemptStore) Lock(ctx context.Context, key string, ttl time.Duration) error {
+	args := m.Called(key, ttl)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	args := m.Called(key)
+	return args.Get(0).(time.Duration), args.Error(1)
+}
+
+func (m *MockLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+type MockPermissionStore struct {
+	mock.Mock
+}
+
+func (m *MockPermissionStore) Get(ctx context.Context, roleID int32) (*permission.Set, int64, error) {
+	args := m.Called(roleID)
+	set, _ := args.Get(0).(*permission.Set)
+	return set, args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockPermissionStore) Set(ctx context.Context, version int64, roleID int32, grants []string) error {
+	args := m.Called(version, roleID, grants)
+	return args.Error(0)
+}
+
+func (m *MockPermissionStore) Invalidate(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
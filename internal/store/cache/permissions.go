@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mifaabiyyu/backend-go/internal/permission"
+)
+
+// PermissionExpTime bounds how long a stale entry can live should an
+// invalidation be lost.
+const PermissionExpTime = time.Minute * 10
+
+const permissionVersionKey = "role-permissions-version"
+
+// PermissionStore caches the effective permissions of each role. Entries
+// are keyed by a shared version, Invalidate bumps it so every role is
+// reloaded; inherited permissions mean one change can affect many roles.
+type PermissionStore struct {
+	rdb *redis.Client
+}
+
+func (s *PermissionStore) Get(ctx context.Context, roleID int32) (*permission.Set, int64, error) {
+	version, err := s.rdb.Get(ctx, permissionVersionKey).Int64()
+	if err != nil && err != redis.Nil {
+		return nil, 0, err
+	}
+
+	data, err := s.rdb.Get(ctx, permissionKey(version, roleID)).Bytes()
+	if err == redis.Nil {
+		return nil, version, nil
+	} else if err != nil {
+		return nil, 0, err
+	}
+
+	var grants []string
+	if err := json.Unmarshal(data, &grants); err != nil {
+		return nil, 0, err
+	}
+
+	return permission.Compile(grants), version, nil
+}
+
+// Set stores grants under the version returned by the Get that missed, a
+// version bumped in the meantime leaves the entry unreachable.
+func (s *PermissionStore) Set(ctx context.Context, version int64, roleID int32, grants []string) error {
+	data, err := json.Marshal(grants)
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.SetEX(ctx, permissionKey(version, roleID), data, PermissionExpTime).Err()
+}
+
+func (s *PermissionStore) Invalidate(ctx context.Context) error {
+	return s.rdb.Incr(ctx, permissionVersionKey).Err()
+}
+
+func permissionKey(version int64, roleID int32) string {
+	return fmt.Sprintf("role-permissions-%d-%d", version, roleID)
+}
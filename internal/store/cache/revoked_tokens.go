@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type RevokedTokenStore struct {
+	rdb *redis.Client
+}
+
+func (s *RevokedTokenStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	cacheKey := fmt.Sprintf("revoked-token-%s", jti)
+	return s.rdb.SetEX(ctx, cacheKey, 1, ttl).Err()
+}
+
+func (s *RevokedTokenStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	cacheKey := fmt.Sprintf("revoked-token-%s", jti)
+
+	n, err := s.rdb.Exists(ctx, cacheKey).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
@@ -2,9 +2,11 @@ package cache
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+	"github.com/mifaabiyyu/backend-go/internal/permission"
 )
 
 type Storage struct {
@@ -13,10 +15,28 @@ type Storage struct {
 		Set(context.Context, *sqlc.User) error
 		Delete(context.Context, int64)
 	}
+	RevokedTokens interface {
+		Revoke(context.Context, string, time.Time) error
+		IsRevoked(context.Context, string) (bool, error)
+	}
+	LoginAttempts interface {
+		Increment(context.Context, string, time.Duration) (int64, error)
+		Lock(context.Context, string, time.Duration) error
+		LockedFor(context.Context, string) (time.Duration, error)
+		Reset(context.Context, string) error
+	}
+	Permissions interface {
+		Get(context.Context, int32) (*permission.Set, int64, error)
+		Set(context.Context, int64, int32, []string) error
+		Invalidate(context.Context) error
+	}
 }
 
 func NewRedisStorage(rbd *redis.Client) Storage {
 	return Storage{
-		Users: &UserStore{rdb: rbd},
+		Users:         &UserStore{rdb: rbd},
+		RevokedTokens: &RevokedTokenStore{rdb: rbd},
+		LoginAttempts: &LoginAttemptStore{rdb: rbd},
+		Permissions:   &PermissionStore{rdb: rbd},
 	}
 }
@@ -0,0 +1,35 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Listen runs LISTEN on a dedicated connection and calls fn for every
+// notification on channel. ready is called once the LISTEN is in place,
+// notifications sent before that are lost and callers should resync
+// there. Listen returns when ctx is done or the connection fails.
+func (s *Store) Listen(ctx context.Context, channel string, ready func(), fn func(payload string)) error {
+	pooled, err := s.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The connection keeps listening, it must not go back to the pool.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return err
+	}
+	ready()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		fn(notification.Payload)
+	}
+}
@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
+)
+
+// RevokedTokenStore is the Postgres backed token revocation list, used when
+// Redis is not enabled.
+type RevokedTokenStore struct {
+	q *sqlc.Queries
+}
+
+func (s *RevokedTokenStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	if err := s.q.RevokeToken(ctx, sqlc.RevokeTokenParams{
+		Jti:       jti,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	}); err != nil {
+		return err
+	}
+
+	// Revocations are rare, so this is a cheap place to drop entries whose
+	// tokens have expired anyway.
+	return s.q.DeleteExpiredRevokedTokens(ctx)
+}
+
+func (s *RevokedTokenStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	return s.q.IsTokenRevoked(ctx, jti)
+}
@@ -1,16 +1,41 @@
 package store
 
 import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	sqlc "github.com/mifaabiyyu/backend-go/internal/db/generated"
 )
 
 type Store struct {
-	Queries *sqlc.Queries
+	Queries       *sqlc.Queries
+	RevokedTokens *RevokedTokenStore
+	db            *pgxpool.Pool
 }
 
 func NewStore(pool *pgxpool.Pool) *Store {
+	queries := sqlc.New(pool)
+
 	return &Store{
-		Queries: sqlc.New(pool),
+		Queries:       queries,
+		RevokedTokens: &RevokedTokenStore{q: queries},
+		db:            pool,
+	}
+}
+
+// WithTx runs fn inside a single transaction. The transaction is committed
+// when fn returns nil and rolled back otherwise.
+func (s *Store) WithTx(ctx context.Context, fn func(*sqlc.Queries) error) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(s.Queries.WithTx(tx)); err != nil {
+		return err
 	}
+
+	return tx.Commit(ctx)
 }
@@ -0,0 +1,130 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var errMalformedCBOR = errors.New("webauthn: malformed cbor")
+
+// maxCBORDepth bounds nesting, attestation objects and COSE keys are only
+// a few levels deep.
+const maxCBORDepth = 16
+
+// decodeCBOR decodes one CBOR data item from b and returns it together
+// with the bytes that follow it. Only the subset WebAuthn uses is
+// supported: integers (as int64), byte strings, text strings, arrays, maps
+// with integer or text keys, booleans and null. Tags, floats and
+// indefinite lengths are rejected.
+func decodeCBOR(b []byte) (any, []byte, error) {
+	return decodeCBORItem(b, 0)
+}
+
+func decodeCBORItem(b []byte, depth int) (any, []byte, error) {
+	if depth > maxCBORDepth || len(b) == 0 {
+		return nil, nil, errMalformedCBOR
+	}
+
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, b, nil
+		case 21:
+			return true, b, nil
+		case 22:
+			return nil, b, nil
+		default:
+			return nil, nil, errMalformedCBOR
+		}
+	}
+
+	n, b, err := readCBORArgument(info, b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case 0:
+		if n > math.MaxInt64 {
+			return nil, nil, errMalformedCBOR
+		}
+		return int64(n), b, nil
+	case 1:
+		if n > math.MaxInt64 {
+			return nil, nil, errMalformedCBOR
+		}
+		return -1 - int64(n), b, nil
+	case 2, 3:
+		if n > uint64(len(b)) {
+			return nil, nil, errMalformedCBOR
+		}
+		if major == 3 {
+			return string(b[:n]), b[n:], nil
+		}
+		return append([]byte(nil), b[:n]...), b[n:], nil
+	case 4:
+		// Every item takes at least one byte, which bounds the allocation.
+		if n > uint64(len(b)) {
+			return nil, nil, errMalformedCBOR
+		}
+		items := make([]any, 0, n)
+		for i := uint64(0); i < n; i++ {
+			var item any
+			item, b, err = decodeCBORItem(b, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, b, nil
+	case 5:
+		if n > uint64(len(b))/2 {
+			return nil, nil, errMalformedCBOR
+		}
+		m := make(map[any]any, n)
+		for i := uint64(0); i < n; i++ {
+			var key, value any
+			key, b, err = decodeCBORItem(b, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errMalformedCBOR
+			}
+			if _, dup := m[key]; dup {
+				return nil, nil, errMalformedCBOR
+			}
+			value, b, err = decodeCBORItem(b, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[key] = value
+		}
+		return m, b, nil
+	default:
+		return nil, nil, errMalformedCBOR
+	}
+}
+
+func readCBORArgument(info byte, b []byte) (uint64, []byte, error) {
+	switch {
+	case info < 24:
+		return uint64(info), b, nil
+	case info == 24 && len(b) >= 1:
+		return uint64(b[0]), b[1:], nil
+	case info == 25 && len(b) >= 2:
+		return uint64(binary.BigEndian.Uint16(b)), b[2:], nil
+	case info == 26 && len(b) >= 4:
+		return uint64(binary.BigEndian.Uint32(b)), b[4:], nil
+	case info == 27 && len(b) >= 8:
+		return binary.BigEndian.Uint64(b), b[8:], nil
+	default:
+		return 0, nil, errMalformedCBOR
+	}
+}
@@ -0,0 +1,121 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers of the keys we accept.
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// SupportedAlgorithms are offered to authenticators in order of preference.
+var SupportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+// COSE key labels, RFC 9052 and RFC 9053.
+const (
+	coseKty = 1
+	coseAlg = 3
+	// -1 is the curve for EC2 and OKP keys and the modulus for RSA keys.
+	coseCrvOrN = -1
+	// -2 is x for EC2 and OKP keys and the exponent for RSA keys.
+	coseXOrE = -2
+	coseY    = -3
+
+	ktyOKP = 1
+	ktyEC2 = 2
+	ktyRSA = 3
+
+	crvP256    = 1
+	crvEd25519 = 6
+)
+
+type publicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+// parsePublicKey decodes a COSE_Key as stored with a credential.
+func parsePublicKey(raw []byte) (*publicKey, error) {
+	item, rest, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errMalformedCBOR
+	}
+
+	m, ok := item.(map[any]any)
+	if !ok {
+		return nil, errors.New("webauthn: public key is not a map")
+	}
+
+	kty, _ := m[int64(coseKty)].(int64)
+	alg, _ := m[int64(coseAlg)].(int64)
+
+	switch {
+	case kty == ktyEC2 && alg == AlgES256:
+		crv, _ := m[int64(coseCrvOrN)].(int64)
+		x, _ := m[int64(coseXOrE)].([]byte)
+		y, _ := m[int64(coseY)].([]byte)
+		if crv != crvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("webauthn: invalid P-256 key")
+		}
+		curve := elliptic.P256()
+		X, Y := new(big.Int).SetBytes(x), new(big.Int).SetBytes(y)
+		if !curve.IsOnCurve(X, Y) {
+			return nil, errors.New("webauthn: P-256 point not on curve")
+		}
+		return &publicKey{alg: alg, key: &ecdsa.PublicKey{Curve: curve, X: X, Y: Y}}, nil
+	case kty == ktyOKP && alg == AlgEdDSA:
+		crv, _ := m[int64(coseCrvOrN)].(int64)
+		x, _ := m[int64(coseXOrE)].([]byte)
+		if crv != crvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("webauthn: invalid Ed25519 key")
+		}
+		return &publicKey{alg: alg, key: ed25519.PublicKey(x)}, nil
+	case kty == ktyRSA && alg == AlgRS256:
+		n, _ := m[int64(coseCrvOrN)].([]byte)
+		e, _ := m[int64(coseXOrE)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("webauthn: invalid RSA key")
+		}
+		E := new(big.Int).SetBytes(e)
+		return &publicKey{alg: alg, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(E.Int64())}}, nil
+	default:
+		return nil, fmt.Errorf("webauthn: unsupported key type %d with algorithm %d", kty, alg)
+	}
+}
+
+func (k *publicKey) verify(data, sig []byte) error {
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return errors.New("webauthn: invalid signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, data, sig) {
+			return errors.New("webauthn: invalid signature")
+		}
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			return errors.New("webauthn: invalid signature")
+		}
+	default:
+		return errors.New("webauthn: unsupported key")
+	}
+
+	return nil
+}
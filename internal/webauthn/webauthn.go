@@ -0,0 +1,511 @@
+// Package webauthn is a small WebAuthn relying party: creation and request
+// options, and verification of registration and assertion responses for
+// passkeys with "none" attestation.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidResponse = errors.New("webauthn: invalid authenticator response")
+	ErrChallenge       = errors.New("webauthn: challenge mismatch")
+	ErrOrigin          = errors.New("webauthn: origin not allowed")
+	ErrUserPresence    = errors.New("webauthn: user presence or verification missing")
+	ErrAttestation     = errors.New("webauthn: unsupported attestation")
+	ErrSignCount       = errors.New("webauthn: sign counter did not increase, the authenticator may be cloned")
+)
+
+// maxCredentialIDLength is the limit of the WebAuthn spec.
+const maxCredentialIDLength = 1023
+
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagAttestedCredData = 0x40
+	flagExtensionData    = 0x80
+)
+
+type Config struct {
+	// RPID is the domain the credentials are scoped to, e.g. example.com.
+	RPID   string
+	RPName string
+	// Origins are the exact origins the ceremonies may run on, e.g.
+	// https://app.example.com.
+	Origins []string
+	Timeout time.Duration
+	// RequireUserVerification rejects responses where the authenticator did
+	// not verify the user with a PIN or biometric.
+	RequireUserVerification bool
+}
+
+// RelyingParty creates ceremony options and verifies the responses.
+type RelyingParty struct {
+	cfg    Config
+	rpHash [sha256.Size]byte
+}
+
+func New(cfg Config) *RelyingParty {
+	if cfg.RPName == "" {
+		cfg.RPName = cfg.RPID
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Minute
+	}
+	origins := make([]string, 0, len(cfg.Origins))
+	for _, origin := range cfg.Origins {
+		origins = append(origins, strings.TrimSuffix(origin, "/"))
+	}
+	cfg.Origins = origins
+
+	return &RelyingParty{cfg: cfg, rpHash: sha256.Sum256([]byte(cfg.RPID))}
+}
+
+// Timeout is how long a ceremony, and so its challenge, is valid.
+func (rp *RelyingParty) Timeout() time.Duration {
+	return rp.cfg.Timeout
+}
+
+// NewChallenge returns a random base64url challenge.
+func NewChallenge() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// User is the account a credential is registered for. ID is the user
+// handle, it must not contain personal information.
+type User struct {
+	ID          []byte
+	Name        string
+	DisplayName string
+}
+
+type RPEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions is PublicKeyCredentialCreationOptionsJSON, ready for
+// PublicKeyCredential.parseCreationOptionsFromJSON in the browser.
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     RPEntity               `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions is PublicKeyCredentialRequestOptionsJSON.
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int64                  `json:"timeout"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// CreationOptions returns the options of a registration ceremony. exclude
+// lists the credentials the user already has so the same authenticator is
+// not registered twice.
+func (rp *RelyingParty) CreationOptions(challenge string, user User, exclude []CredentialDescriptor) *CreationOptions {
+	params := make([]CredentialParameter, 0, len(SupportedAlgorithms))
+	for _, alg := range SupportedAlgorithms {
+		params = append(params, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+
+	return &CreationOptions{
+		Challenge: challenge,
+		RP:        RPEntity{ID: rp.cfg.RPID, Name: rp.cfg.RPName},
+		User: UserEntity{
+			ID:          base64.RawURLEncoding.EncodeToString(user.ID),
+			Name:        user.Name,
+			DisplayName: user.DisplayName,
+		},
+		PubKeyCredParams:   params,
+		Timeout:            rp.cfg.Timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: rp.userVerification(),
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions returns the options of an authentication ceremony. No
+// credentials are listed, the authenticator offers its discoverable
+// credentials for the RP and the user handle tells who signed in.
+func (rp *RelyingParty) RequestOptions(challenge string) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        challenge,
+		RPID:             rp.cfg.RPID,
+		Timeout:          rp.cfg.Timeout.Milliseconds(),
+		AllowCredentials: []CredentialDescriptor{},
+		UserVerification: rp.userVerification(),
+	}
+}
+
+func (rp *RelyingParty) userVerification() string {
+	if rp.cfg.RequireUserVerification {
+		return "required"
+	}
+	return "preferred"
+}
+
+// AttestationResponse is the JSON form of a PublicKeyCredential returned by
+// navigator.credentials.create, as produced by its toJSON method.
+type AttestationResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the JSON form of a PublicKeyCredential returned by
+// navigator.credentials.get.
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// Credential is what has to be stored after a successful registration.
+type Credential struct {
+	ID []byte
+	// PublicKey is the COSE encoded key, passed back to VerifyAssertion.
+	PublicKey    []byte
+	SignCount    uint32
+	AAGUID       []byte
+	Transports   []string
+	UserVerified bool
+}
+
+// Assertion is the outcome of a successful authentication.
+type Assertion struct {
+	CredentialID []byte
+	UserHandle   []byte
+	SignCount    uint32
+	UserVerified bool
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// Challenge returns the challenge a response was created for, so the
+// ceremony it belongs to can be looked up before it is verified.
+func Challenge(clientDataJSON string) (string, error) {
+	raw, err := decodeBase64(clientDataJSON)
+	if err != nil {
+		return "", ErrInvalidResponse
+	}
+
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil || cd.Challenge == "" {
+		return "", ErrInvalidResponse
+	}
+
+	return cd.Challenge, nil
+}
+
+// VerifyRegistration checks a registration response against the challenge
+// of its ceremony and returns the new credential.
+func (rp *RelyingParty) VerifyRegistration(res *AttestationResponse, challenge string) (*Credential, error) {
+	if res.Type != "public-key" {
+		return nil, ErrInvalidResponse
+	}
+
+	rawID, err := decodeBase64(res.RawID)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+
+	clientDataJSON, err := decodeBase64(res.Response.ClientDataJSON)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	attObj, err := decodeBase64(res.Response.AttestationObject)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+
+	item, rest, err := decodeCBOR(attObj)
+	if err != nil || len(rest) != 0 {
+		return nil, ErrInvalidResponse
+	}
+	att, ok := item.(map[any]any)
+	if !ok {
+		return nil, ErrInvalidResponse
+	}
+
+	// Only "none" attestation is requested, we do not vouch for any
+	// authenticator model.
+	format, _ := att["fmt"].(string)
+	stmt, _ := att["attStmt"].(map[any]any)
+	if format != "none" || stmt == nil || len(stmt) != 0 {
+		return nil, ErrAttestation
+	}
+
+	authData, _ := att["authData"].([]byte)
+	data, err := rp.parseAuthenticatorData(authData)
+	if err != nil {
+		return nil, err
+	}
+	if data.flags&flagAttestedCredData == 0 {
+		return nil, ErrInvalidResponse
+	}
+
+	if len(data.credentialID) > maxCredentialIDLength || !bytes.Equal(rawID, data.credentialID) {
+		return nil, ErrInvalidResponse
+	}
+
+	if _, err := parsePublicKey(data.publicKey); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+
+	return &Credential{
+		ID:           data.credentialID,
+		PublicKey:    data.publicKey,
+		SignCount:    data.signCount,
+		AAGUID:       data.aaguid,
+		Transports:   res.Response.Transports,
+		UserVerified: data.flags&flagUserVerified != 0,
+	}, nil
+}
+
+// VerifyAssertion checks an authentication response against the challenge
+// of its ceremony and the stored credential. The returned sign count has to
+// be stored for the next assertion.
+func (rp *RelyingParty) VerifyAssertion(res *AssertionResponse, challenge string, publicKey []byte, storedSignCount uint32) (*Assertion, error) {
+	if res.Type != "public-key" {
+		return nil, ErrInvalidResponse
+	}
+
+	rawID, err := decodeBase64(res.RawID)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+
+	clientDataJSON, err := decodeBase64(res.Response.ClientDataJSON)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return nil, err
+	}
+
+	authData, err := decodeBase64(res.Response.AuthenticatorData)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	data, err := rp.parseAuthenticatorData(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := decodeBase64(res.Response.Signature)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+
+	key, err := parsePublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+	if err := key.verify(signed, sig); err != nil {
+		return nil, ErrInvalidResponse
+	}
+
+	// Authenticators without a counter always report 0. Otherwise it must
+	// move forward, a counter going back means the key was copied.
+	if (data.signCount != 0 || storedSignCount != 0) && data.signCount <= storedSignCount {
+		return nil, ErrSignCount
+	}
+
+	var userHandle []byte
+	if res.Response.UserHandle != "" {
+		userHandle, err = decodeBase64(res.Response.UserHandle)
+		if err != nil {
+			return nil, ErrInvalidResponse
+		}
+	}
+
+	return &Assertion{
+		CredentialID: rawID,
+		UserHandle:   userHandle,
+		SignCount:    data.signCount,
+		UserVerified: data.flags&flagUserVerified != 0,
+	}, nil
+}
+
+func (rp *RelyingParty) verifyClientData(raw []byte, ceremony, challenge string) error {
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return ErrInvalidResponse
+	}
+
+	if cd.Type != ceremony {
+		return ErrInvalidResponse
+	}
+
+	if subtle.ConstantTimeCompare([]byte(cd.Challenge), []byte(challenge)) != 1 {
+		return ErrChallenge
+	}
+
+	if cd.CrossOrigin || !slices.Contains(rp.cfg.Origins, cd.Origin) {
+		return ErrOrigin
+	}
+
+	return nil
+}
+
+type authenticatorData struct {
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+// parseAuthenticatorData parses and checks the rpIdHash, flags and counter
+// and, when present, the attested credential data.
+func (rp *RelyingParty) parseAuthenticatorData(b []byte) (*authenticatorData, error) {
+	// rpIdHash (32), flags (1), signCount (4).
+	if len(b) < 37 {
+		return nil, ErrInvalidResponse
+	}
+
+	if subtle.ConstantTimeCompare(b[:32], rp.rpHash[:]) != 1 {
+		return nil, ErrInvalidResponse
+	}
+
+	data := &authenticatorData{
+		flags:     b[32],
+		signCount: binary.BigEndian.Uint32(b[33:37]),
+	}
+
+	if data.flags&flagUserPresent == 0 {
+		return nil, ErrUserPresence
+	}
+	if rp.cfg.RequireUserVerification && data.flags&flagUserVerified == 0 {
+		return nil, ErrUserPresence
+	}
+
+	rest := b[37:]
+
+	if data.flags&flagAttestedCredData != 0 {
+		// aaguid (16), credentialIdLength (2), credentialId, COSE key.
+		if len(rest) < 18 {
+			return nil, ErrInvalidResponse
+		}
+		data.aaguid = append([]byte(nil), rest[:16]...)
+		n := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if n == 0 || len(rest) < n {
+			return nil, ErrInvalidResponse
+		}
+		data.credentialID = append([]byte(nil), rest[:n]...)
+		rest = rest[n:]
+
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrInvalidResponse
+		}
+		data.publicKey = append([]byte(nil), rest[:len(rest)-len(after)]...)
+		rest = after
+	}
+
+	if data.flags&flagExtensionData != 0 {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrInvalidResponse
+		}
+		rest = after
+	}
+
+	if len(rest) != 0 {
+		return nil, ErrInvalidResponse
+	}
+
+	return data, nil
+}
+
+// decodeBase64 accepts base64url with or without padding, browsers and
+// libraries disagree on it.
+func decodeBase64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// DecodeID decodes a credential ID or user handle sent by the browser.
+func DecodeID(s string) ([]byte, error) {
+	return decodeBase64(s)
+}
+
+// EncodeID encodes a credential ID or user handle the way the browser
+// reports it.
+func EncodeID(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
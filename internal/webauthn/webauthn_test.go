@@ -0,0 +1,607 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://app.example.com"
+)
+
+func testRP() *RelyingParty {
+	return New(Config{RPID: testRPID, Origins: []string{testOrigin + "/"}})
+}
+
+// softAuthenticator is a software passkey: an ECDSA P-256 key with "none"
+// attestation and a counter.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		t.Fatalf("credential id: %v", err)
+	}
+
+	return &softAuthenticator{key: key, credentialID: id}
+}
+
+func (a *softAuthenticator) coseKey() []byte {
+	return encodeCBOR(map[any]any{
+		int64(coseKty):    int64(ktyEC2),
+		int64(coseAlg):    AlgES256,
+		int64(coseCrvOrN): int64(crvP256),
+		int64(coseXOrE):   a.key.X.FillBytes(make([]byte, 32)),
+		int64(coseY):      a.key.Y.FillBytes(make([]byte, 32)),
+	})
+}
+
+// ceremony is what the browser and the authenticator put in a response,
+// tests change single fields to break it.
+type ceremony struct {
+	typ         string
+	challenge   string
+	origin      string
+	crossOrigin bool
+	rpID        string
+	flags       byte
+	format      string
+}
+
+func (c ceremony) clientDataJSON() []byte {
+	b, _ := json.Marshal(map[string]any{
+		"type":        c.typ,
+		"challenge":   c.challenge,
+		"origin":      c.origin,
+		"crossOrigin": c.crossOrigin,
+	})
+	return b
+}
+
+func (c ceremony) authDataHeader(signCount uint32) []byte {
+	rpHash := sha256.Sum256([]byte(c.rpID))
+	b := append(rpHash[:], c.flags)
+	return binary.BigEndian.AppendUint32(b, signCount)
+}
+
+func registration(challenge string) ceremony {
+	return ceremony{
+		typ:       "webauthn.create",
+		challenge: challenge,
+		origin:    testOrigin,
+		rpID:      testRPID,
+		flags:     flagUserPresent | flagUserVerified | flagAttestedCredData,
+		format:    "none",
+	}
+}
+
+func authentication(challenge string) ceremony {
+	return ceremony{
+		typ:       "webauthn.get",
+		challenge: challenge,
+		origin:    testOrigin,
+		rpID:      testRPID,
+		flags:     flagUserPresent | flagUserVerified,
+	}
+}
+
+// attestationObject builds the CBOR attestation object of a registration.
+func (a *softAuthenticator) attestationObject(c ceremony) []byte {
+	authData := c.authDataHeader(a.signCount)
+	authData = append(authData, make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+	authData = append(authData, a.credentialID...)
+	authData = append(authData, a.coseKey()...)
+
+	return encodeCBOR(map[any]any{
+		"fmt":      c.format,
+		"attStmt":  map[any]any{},
+		"authData": authData,
+	})
+}
+
+func (a *softAuthenticator) register(c ceremony) *AttestationResponse {
+	res := &AttestationResponse{ID: EncodeID(a.credentialID), RawID: EncodeID(a.credentialID), Type: "public-key"}
+	res.Response.ClientDataJSON = EncodeID(c.clientDataJSON())
+	res.Response.AttestationObject = EncodeID(a.attestationObject(c))
+	res.Response.Transports = []string{"internal"}
+	return res
+}
+
+// assert signs an authentication response, bumping the counter first like
+// a real authenticator.
+func (a *softAuthenticator) assert(t *testing.T, c ceremony) *AssertionResponse {
+	t.Helper()
+
+	a.signCount++
+	authData := c.authDataHeader(a.signCount)
+	clientDataJSON := c.clientDataJSON()
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	res := &AssertionResponse{ID: EncodeID(a.credentialID), RawID: EncodeID(a.credentialID), Type: "public-key"}
+	res.Response.ClientDataJSON = EncodeID(clientDataJSON)
+	res.Response.AuthenticatorData = EncodeID(authData)
+	res.Response.Signature = EncodeID(sig)
+	res.Response.UserHandle = EncodeID([]byte("user-1"))
+	return res
+}
+
+func newTestChallenge(t *testing.T) string {
+	t.Helper()
+
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatalf("NewChallenge: %v", err)
+	}
+	return challenge
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	rp := testRP()
+	a := newSoftAuthenticator(t)
+
+	challenge := newTestChallenge(t)
+	res := a.register(registration(challenge))
+
+	got, err := Challenge(res.Response.ClientDataJSON)
+	if err != nil || got != challenge {
+		t.Fatalf("Challenge = %q, %v; want %q", got, err, challenge)
+	}
+
+	cred, err := rp.VerifyRegistration(res, challenge)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	if string(cred.ID) != string(a.credentialID) || !cred.UserVerified || cred.SignCount != 0 {
+		t.Fatalf("unexpected credential %+v", cred)
+	}
+
+	signCount := cred.SignCount
+	for i := 0; i < 3; i++ {
+		challenge := newTestChallenge(t)
+		assertion, err := rp.VerifyAssertion(a.assert(t, authentication(challenge)), challenge, cred.PublicKey, signCount)
+		if err != nil {
+			t.Fatalf("VerifyAssertion %d: %v", i+1, err)
+		}
+		if assertion.SignCount != signCount+1 || string(assertion.UserHandle) != "user-1" {
+			t.Fatalf("unexpected assertion %+v", assertion)
+		}
+		signCount = assertion.SignCount
+	}
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	rp := testRP()
+	challenge := newTestChallenge(t)
+
+	tests := []struct {
+		name    string
+		change  func(c *ceremony)
+		res     func(a *softAuthenticator, res *AttestationResponse)
+		wantErr error
+	}{
+		{
+			name:    "challenge mismatch",
+			change:  func(c *ceremony) { c.challenge = "another-challenge" },
+			wantErr: ErrChallenge,
+		},
+		{
+			name:    "origin mismatch",
+			change:  func(c *ceremony) { c.origin = "https://evil.example.com" },
+			wantErr: ErrOrigin,
+		},
+		{
+			name:    "cross origin iframe",
+			change:  func(c *ceremony) { c.crossOrigin = true },
+			wantErr: ErrOrigin,
+		},
+		{
+			name:    "assertion client data",
+			change:  func(c *ceremony) { c.typ = "webauthn.get" },
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name:    "rp id hash mismatch",
+			change:  func(c *ceremony) { c.rpID = "evil.example.com" },
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name:    "user not present",
+			change:  func(c *ceremony) { c.flags &^= flagUserPresent },
+			wantErr: ErrUserPresence,
+		},
+		{
+			name:    "no attested credential data",
+			change:  func(c *ceremony) { c.flags &^= flagAttestedCredData },
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name:    "packed attestation",
+			change:  func(c *ceremony) { c.format = "packed" },
+			wantErr: ErrAttestation,
+		},
+		{
+			name: "raw id differs from the attested credential",
+			res: func(a *softAuthenticator, res *AttestationResponse) {
+				res.RawID = EncodeID([]byte("another-credential"))
+			},
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name: "wrong credential type",
+			res: func(a *softAuthenticator, res *AttestationResponse) {
+				res.Type = "password"
+			},
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name: "trailing bytes after the attestation object",
+			res: func(a *softAuthenticator, res *AttestationResponse) {
+				res.Response.AttestationObject = EncodeID(append(a.attestationObject(registration(challenge)), 0x00))
+			},
+			wantErr: ErrInvalidResponse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newSoftAuthenticator(t)
+			c := registration(challenge)
+			if tt.change != nil {
+				tt.change(&c)
+			}
+			res := a.register(c)
+			if tt.res != nil {
+				tt.res(a, res)
+			}
+
+			if _, err := rp.VerifyRegistration(res, challenge); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyRegistrationTruncated(t *testing.T) {
+	rp := testRP()
+	a := newSoftAuthenticator(t)
+	challenge := newTestChallenge(t)
+	attObj := a.attestationObject(registration(challenge))
+
+	// Every prefix of a valid attestation object has to be refused, none
+	// may panic.
+	for n := 0; n < len(attObj); n++ {
+		res := a.register(registration(challenge))
+		res.Response.AttestationObject = EncodeID(attObj[:n])
+		if _, err := rp.VerifyRegistration(res, challenge); err == nil {
+			t.Fatalf("a %d byte prefix was accepted", n)
+		}
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	rp := testRP()
+	challenge := newTestChallenge(t)
+
+	tests := []struct {
+		name string
+		// stored is the counter the server has, the authenticator is at
+		// counter and reports counter+1.
+		stored  uint32
+		counter uint32
+		change  func(c *ceremony)
+		res     func(t *testing.T, res *AssertionResponse)
+		wantErr error
+	}{
+		{
+			name:    "challenge mismatch",
+			change:  func(c *ceremony) { c.challenge = "another-challenge" },
+			wantErr: ErrChallenge,
+		},
+		{
+			name:    "origin mismatch",
+			change:  func(c *ceremony) { c.origin = "https://app.example.com:8443" },
+			wantErr: ErrOrigin,
+		},
+		{
+			name:    "registration client data",
+			change:  func(c *ceremony) { c.typ = "webauthn.create" },
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name:    "rp id hash mismatch",
+			change:  func(c *ceremony) { c.rpID = "evil.example.com" },
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name:    "user not present",
+			change:  func(c *ceremony) { c.flags = flagUserVerified },
+			wantErr: ErrUserPresence,
+		},
+		{
+			name:    "sign count went back",
+			stored:  10,
+			counter: 3,
+			wantErr: ErrSignCount,
+		},
+		{
+			name:    "sign count did not move",
+			stored:  10,
+			counter: 9,
+			wantErr: ErrSignCount,
+		},
+		{
+			name:    "sign count moved forward",
+			stored:  10,
+			counter: 10,
+		},
+		{
+			name: "client data changed after signing",
+			res: func(t *testing.T, res *AssertionResponse) {
+				var cd map[string]any
+				raw, _ := decodeBase64(res.Response.ClientDataJSON)
+				_ = json.Unmarshal(raw, &cd)
+				cd["tokenBinding"] = "injected"
+				b, _ := json.Marshal(cd)
+				res.Response.ClientDataJSON = EncodeID(b)
+			},
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name: "signed by another key",
+			res: func(t *testing.T, res *AssertionResponse) {
+				other := newSoftAuthenticator(t)
+				forged := other.assert(t, authentication(challenge))
+				res.Response.Signature = forged.Response.Signature
+			},
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name: "truncated authenticator data",
+			res: func(t *testing.T, res *AssertionResponse) {
+				authData, _ := decodeBase64(res.Response.AuthenticatorData)
+				res.Response.AuthenticatorData = EncodeID(authData[:36])
+			},
+			wantErr: ErrInvalidResponse,
+		},
+		{
+			name: "truncated signature",
+			res: func(t *testing.T, res *AssertionResponse) {
+				sig, _ := decodeBase64(res.Response.Signature)
+				res.Response.Signature = EncodeID(sig[:len(sig)/2])
+			},
+			wantErr: ErrInvalidResponse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newSoftAuthenticator(t)
+			a.signCount = tt.counter
+			publicKey := a.coseKey()
+
+			c := authentication(challenge)
+			if tt.change != nil {
+				tt.change(&c)
+			}
+			res := a.assert(t, c)
+			if tt.res != nil {
+				tt.res(t, res)
+			}
+
+			_, err := rp.VerifyAssertion(res, challenge, publicKey, tt.stored)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("expected the assertion to verify, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestVerifyAssertionWithoutCounter covers authenticators that always
+// report zero, such as synced passkeys.
+func TestVerifyAssertionWithoutCounter(t *testing.T) {
+	rp := testRP()
+	a := newSoftAuthenticator(t)
+	challenge := newTestChallenge(t)
+
+	c := authentication(challenge)
+	clientDataJSON := c.clientDataJSON()
+	authData := c.authDataHeader(0)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	res := &AssertionResponse{RawID: EncodeID(a.credentialID), Type: "public-key"}
+	res.Response.ClientDataJSON = EncodeID(clientDataJSON)
+	res.Response.AuthenticatorData = EncodeID(authData)
+	res.Response.Signature = EncodeID(sig)
+
+	if _, err := rp.VerifyAssertion(res, challenge, a.coseKey(), 0); err != nil {
+		t.Fatalf("VerifyAssertion: %v", err)
+	}
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	value, rest, err := decodeCBOR(encodeCBOR(map[any]any{
+		"fmt":    "none",
+		int64(1): int64(-7),
+		"list":   []any{true, false, nil, []byte{1, 2}},
+		"big":    int64(1) << 40,
+	}))
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("decodeCBOR = %v, %v", err, rest)
+	}
+
+	m := value.(map[any]any)
+	list := m["list"].([]any)
+	if m["fmt"] != "none" || m[int64(1)] != int64(-7) || m["big"] != int64(1)<<40 || list[0] != true || list[2] != nil {
+		t.Fatalf("unexpected value %#v", value)
+	}
+}
+
+func TestDecodeCBORMalformed(t *testing.T) {
+	deep := make([]byte, 0, maxCBORDepth+2)
+	for i := 0; i < maxCBORDepth+2; i++ {
+		deep = append(deep, 0x81)
+	}
+	deep = append(deep, 0x00)
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"empty", nil},
+		{"truncated uint16 argument", []byte{0x19, 0x01}},
+		{"truncated uint64 argument", []byte{0x1b, 0, 0, 0, 0}},
+		{"reserved additional info", []byte{0x1c}},
+		{"byte string longer than the input", []byte{0x45, 1, 2}},
+		{"text string longer than the input", []byte{0x78, 0xff, 'a'}},
+		{"array count larger than the input", []byte{0x9a, 0xff, 0xff, 0xff, 0xff, 0x00}},
+		{"map count larger than the input", []byte{0xbb, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff}},
+		{"truncated map value", []byte{0xa1, 0x01}},
+		{"byte string map key", []byte{0xa1, 0x41, 0x00, 0x00}},
+		{"duplicate map key", []byte{0xa2, 0x01, 0x00, 0x01, 0x00}},
+		{"indefinite length byte string", []byte{0x5f, 0x41, 0x00, 0xff}},
+		{"tag", []byte{0xc0, 0x00}},
+		{"half float", []byte{0xf9, 0x3c, 0x00}},
+		{"undefined", []byte{0xf7}},
+		{"negative integer overflow", []byte{0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"unsigned integer overflow", []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"nested too deep", deep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCBOR(tt.input); !errors.Is(err, errMalformedCBOR) {
+				t.Fatalf("expected errMalformedCBOR, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	a := newSoftAuthenticator(t)
+	valid := a.coseKey()
+
+	key := func(change func(m map[any]any)) []byte {
+		m := map[any]any{
+			int64(coseKty):    int64(ktyEC2),
+			int64(coseAlg):    AlgES256,
+			int64(coseCrvOrN): int64(crvP256),
+			int64(coseXOrE):   a.key.X.FillBytes(make([]byte, 32)),
+			int64(coseY):      a.key.Y.FillBytes(make([]byte, 32)),
+		}
+		change(m)
+		return encodeCBOR(m)
+	}
+
+	if _, err := parsePublicKey(valid); err != nil {
+		t.Fatalf("parsePublicKey: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		raw  []byte
+	}{
+		{"trailing bytes", append(append([]byte{}, valid...), 0x00)},
+		{"truncated", valid[:len(valid)-1]},
+		{"not a map", encodeCBOR([]any{int64(1)})},
+		{"unsupported algorithm", key(func(m map[any]any) { m[int64(coseAlg)] = int64(-35) })},
+		{"wrong curve", key(func(m map[any]any) { m[int64(coseCrvOrN)] = int64(2) })},
+		{"short coordinate", key(func(m map[any]any) { m[int64(coseXOrE)] = []byte{1, 2, 3} })},
+		{"point not on the curve", key(func(m map[any]any) { m[int64(coseY)] = make([]byte, 32) })},
+		{"missing y", key(func(m map[any]any) { delete(m, int64(coseY)) })},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parsePublicKey(tt.raw); err == nil {
+				t.Fatal("an invalid key was accepted")
+			}
+		})
+	}
+}
+
+// encodeCBOR is the encoding counterpart of decodeCBOR for the types it
+// returns.
+func encodeCBOR(v any) []byte {
+	switch v := v.(type) {
+	case int64:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case []any:
+		b := cborHead(4, uint64(len(v)))
+		for _, item := range v {
+			b = append(b, encodeCBOR(item)...)
+		}
+		return b
+	case map[any]any:
+		b := cborHead(5, uint64(len(v)))
+		for key, value := range v {
+			b = append(b, encodeCBOR(key)...)
+			b = append(b, encodeCBOR(value)...)
+		}
+		return b
+	case bool:
+		if v {
+			return []byte{0xf5}
+		}
+		return []byte{0xf4}
+	case nil:
+		return []byte{0xf6}
+	}
+	panic("encodeCBOR: unsupported type")
+}
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, n)
+}
@@ -1,18 +1,28 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/mifaabiyyu/backend-go/api"
+	"github.com/mifaabiyyu/backend-go/internal/abac"
 	"github.com/mifaabiyyu/backend-go/internal/auth"
 	"github.com/mifaabiyyu/backend-go/internal/db"
 	"github.com/mifaabiyyu/backend-go/internal/env"
+	"github.com/mifaabiyyu/backend-go/internal/hierarchy"
+	"github.com/mifaabiyyu/backend-go/internal/lockout"
 	"github.com/mifaabiyyu/backend-go/internal/mailer"
+	"github.com/mifaabiyyu/backend-go/internal/oidc"
+	"github.com/mifaabiyyu/backend-go/internal/password"
 	"github.com/mifaabiyyu/backend-go/internal/ratelimiter"
 	"github.com/mifaabiyyu/backend-go/internal/store"
 	"github.com/mifaabiyyu/backend-go/internal/store/cache"
+	"github.com/mifaabiyyu/backend-go/internal/webauthn"
 	"go.uber.org/zap"
 )
 
@@ -31,7 +41,8 @@ func main() {
 			Db:      env.GetInt("REDIS_DB", 0),
 			Enabled: env.GetBool("REDIS_ENABLED", false),
 		},
-		Env: env.GetString("ENV", "development"),
+		Env:         env.GetString("ENV", "development"),
+		FrontendURL: env.GetString("FRONTEND_URL", "http://localhost:5174"),
 		Mail: api.MailConfig{
 			Exp:       time.Hour * 24 * 3, // 3 days
 			FromEmail: env.GetString("FROM_EMAIL", ""),
@@ -43,21 +54,65 @@ func main() {
 			},
 		},
 		Auth: api.AuthConfig{
-			Basic: api.BasicConfig{
-				User: env.GetString("AUTH_BASIC_USER", "admin"),
-				Pass: env.GetString("AUTH_BASIC_PASS", "admin"),
-			},
 			Token: api.TokenConfig{
-				Secret: env.GetString("AUTH_TOKEN_SECRET", "example"),
-				Exp:    time.Hour * 24 * 3, // 3 days
-				Iss:    "gophersocial",
+				Secret:     env.GetString("AUTH_TOKEN_SECRET", "example"),
+				Exp:        time.Minute * 15,
+				RefreshExp: time.Hour * 24 * 30, // 30 days
+				Iss:        env.GetString("AUTH_TOKEN_ISS", "gophersocial"),
+				Aud:        env.GetString("AUTH_TOKEN_AUD", "gophersocial"),
+				Leeway:     time.Second * 30,
+
+				ImpersonationExp: time.Minute * 15,
+
+				SigningKeyFile: env.GetString("AUTH_TOKEN_SIGNING_KEY_FILE", ""),
+				VerifyKeyFiles: env.GetStrings("AUTH_TOKEN_VERIFY_KEY_FILES", nil),
+			},
+			Password: password.Config{
+				Algorithm: env.GetString("PASSWORD_HASH_ALGORITHM", password.Argon2idName),
+				Argon2: password.Argon2Params{
+					Memory:      uint32(env.GetInt("PASSWORD_ARGON2_MEMORY_KIB", int(password.DefaultArgon2Params.Memory))),
+					Iterations:  uint32(env.GetInt("PASSWORD_ARGON2_ITERATIONS", int(password.DefaultArgon2Params.Iterations))),
+					Parallelism: uint8(env.GetInt("PASSWORD_ARGON2_PARALLELISM", int(password.DefaultArgon2Params.Parallelism))),
+				},
+				BcryptCost: env.GetInt("PASSWORD_BCRYPT_COST", password.DefaultBcryptCost),
+			},
+			PasswordPolicy: password.PolicyConfig{
+				MinLength:            env.GetInt("PASSWORD_MIN_LENGTH", 8),
+				MaxBytes:             password.BcryptMaxBytes,
+				MinCharClasses:       env.GetInt("PASSWORD_MIN_CHAR_CLASSES", 2),
+				DisallowPersonalInfo: true,
 			},
+			BreachedPasswordsFile: env.GetString("PASSWORD_BREACHED_LIST_FILE", ""),
+			OAuth:                 oauthConfigs(env.GetStrings("OAUTH_PROVIDERS", nil)),
+			WebAuthn: webauthn.Config{
+				RPID:                    env.GetString("WEBAUTHN_RP_ID", "localhost"),
+				RPName:                  env.GetString("WEBAUTHN_RP_NAME", "Backend Go"),
+				Origins:                 env.GetStrings("WEBAUTHN_ORIGINS", []string{"http://localhost:5174"}),
+				Timeout:                 time.Minute * 5,
+				RequireUserVerification: env.GetBool("WEBAUTHN_REQUIRE_USER_VERIFICATION", false),
+			},
+			PolicyFile: env.GetString("ABAC_POLICY_FILE", ""),
 		},
 		RateLimiter: ratelimiter.Config{
 			RequestsPerTimeFrame: env.GetInt("RATELIMITER_REQUESTS_COUNT", 20),
 			TimeFrame:            time.Second * 5,
 			Enabled:              env.GetBool("RATE_LIMITER_ENABLED", true),
 		},
+		Lockout: lockout.Config{
+			MaxAccountFailures: env.GetInt("LOCKOUT_MAX_ACCOUNT_FAILURES", 10),
+			MaxIPFailures:      env.GetInt("LOCKOUT_MAX_IP_FAILURES", 50),
+			FreeAttempts:       3,
+			BaseDelay:          time.Second,
+			MaxDelay:           time.Second * 30,
+			Window:             time.Minute * 15,
+			LockoutDuration:    time.Minute * 15,
+			Enabled:            env.GetBool("LOCKOUT_ENABLED", true),
+		},
+		Privacy: api.PrivacyConfig{
+			ErasureGracePeriod: time.Hour * 24 * time.Duration(env.GetInt("ERASURE_GRACE_PERIOD_DAYS", 30)),
+			ExportExp:          time.Hour * 24 * 7, // 7 days
+			PollInterval:       time.Second * 30,
+		},
 	}
 
 	logger := zap.Must(zap.NewProduction()).Sugar()
@@ -89,6 +144,25 @@ func main() {
 		cfg.RateLimiter.TimeFrame,
 	)
 
+	// Password hashing
+	hasher, err := password.New(cfg.Auth.Password)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	password.SetDefault(hasher)
+
+	breached, err := password.BundledHashList()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if cfg.Auth.BreachedPasswordsFile != "" {
+		if err := breached.LoadFile(cfg.Auth.BreachedPasswordsFile); err != nil {
+			logger.Fatal(err)
+		}
+	}
+	logger.Infow("breached password list loaded", "hashes", breached.Len())
+	passwordPolicy := password.NewPolicy(cfg.Auth.PasswordPolicy, breached)
+
 	// Mailer
 	// mailer := mailer.NewSendgrid(cfg.mail.sendGrid.apiKey, cfg.mail.fromEmail)
 	mailtrap, err := mailer.NewMailTrapClient(cfg.Mail.MailTrap.ApiKey, cfg.Mail.FromEmail)
@@ -97,11 +171,25 @@ func main() {
 	}
 
 	// Authenticator
-	jwtAuthenticator := auth.NewJWTAuthenticator(
+	var authenticator auth.Authenticator = auth.NewJWTAuthenticator(
 		cfg.Auth.Token.Secret,
+		cfg.Auth.Token.Aud,
 		cfg.Auth.Token.Iss,
-		cfg.Auth.Token.Iss,
+		cfg.Auth.Token.Leeway,
 	)
+	if cfg.Auth.Token.SigningKeyFile != "" {
+		authenticator, err = auth.NewKeySetAuthenticator(
+			cfg.Auth.Token.Aud,
+			cfg.Auth.Token.Iss,
+			cfg.Auth.Token.Leeway,
+			cfg.Auth.Token.SigningKeyFile,
+			cfg.Auth.Token.VerifyKeyFiles...,
+		)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		logger.Info("asymmetric token signing enabled")
+	}
 
 	defer dbCon.Close()
 	logger.Info("database connection pool established")
@@ -109,17 +197,103 @@ func main() {
 	store := store.NewStore(dbCon)
 	cacheStorage := cache.NewRedisStorage(rdb)
 
+	// Token revocation list
+	var revocations auth.RevocationStore = store.RevokedTokens
+	if cfg.RedisCfg.Enabled {
+		revocations = cacheStorage.RevokedTokens
+	}
+	revocations = auth.WithLeeway(revocations, cfg.Auth.Token.Leeway)
+
+	// Failed login counters, kept in process when Redis is disabled
+	var loginAttempts lockout.Store = cacheStorage.LoginAttempts
+	if !cfg.RedisCfg.Enabled {
+		loginAttempts = cache.NewMemoryLoginAttemptStore()
+	}
+
+	// Role permissions, cached in process when Redis is disabled
+	if !cfg.RedisCfg.Enabled {
+		cacheStorage.Permissions = cache.NewMemoryPermissionStore()
+	}
+	loginGuard := lockout.New(loginAttempts, cfg.Lockout)
+
+	// Social login
+	oauthProviders := make(map[string]*oidc.Provider, len(cfg.Auth.OAuth))
+	for _, providerCfg := range cfg.Auth.OAuth {
+		oauthProviders[providerCfg.Name] = oidc.NewProvider(providerCfg, &http.Client{Timeout: 10 * time.Second})
+		logger.Infow("oauth provider enabled", "provider", providerCfg.Name, "issuer", providerCfg.Issuer)
+	}
+
+	// Passkeys, disabled by setting WEBAUTHN_RP_ID to an empty value
+	var relyingParty *webauthn.RelyingParty
+	if cfg.Auth.WebAuthn.RPID != "" {
+		relyingParty = webauthn.New(cfg.Auth.WebAuthn)
+		logger.Infow("passkeys enabled", "rp_id", cfg.Auth.WebAuthn.RPID, "origins", cfg.Auth.WebAuthn.Origins)
+	}
+
+	// Role hierarchy and ABAC policies
+	roles := hierarchy.New(hierarchy.NewRoleStore(store.Queries))
+	policies, err := abac.NewEngine(cfg.Auth.PolicyFile)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infow("abac policies loaded", "file", cfg.Auth.PolicyFile, "policies", len(policies.Policies().Policies))
+
 	app := api.Application{
-		Config:        cfg,
-		Store:         store,
-		CacheStorage:  cacheStorage,
-		Logger:        logger,
-		Mailer:        mailtrap,
-		Authenticator: jwtAuthenticator,
-		RateLimiter:   rateLimiter,
+		Config:         cfg,
+		Store:          store,
+		CacheStorage:   cacheStorage,
+		Logger:         logger,
+		Mailer:         mailtrap,
+		Authenticator:  authenticator,
+		Revocations:    revocations,
+		RateLimiter:    rateLimiter,
+		Lockout:        loginGuard,
+		PasswordPolicy: passwordPolicy,
+		OAuthProviders: oauthProviders,
+		WebAuthn:       relyingParty,
+		Roles:          roles,
+		Policies:       policies,
+		Attributes:     abac.NewDirectory(store.Queries, roles),
 	}
 	app.InitMiddleware()
 	mux := app.Mount()
 
+	// Data exports, account erasures, permission cache invalidation and
+	// policy reloads
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	defer stopWorker()
+	go app.RunPrivacyWorker(workerCtx)
+	go app.RunPermissionListener(workerCtx)
+	go app.RunPolicyReloader(workerCtx)
+
 	log.Fatal(app.Run(mux))
 }
+
+// knownIssuers saves configuring the issuer of well known providers.
+// Microsoft's common endpoint serves every tenant, ID tokens are checked
+// against the issuer of the tenant in their tid claim and only the tenants
+// in OAUTH_MICROSOFT_TENANTS are accepted.
+var knownIssuers = map[string]string{
+	"google":    "https://accounts.google.com",
+	"microsoft": "https://login.microsoftonline.com/common/v2.0",
+	"gitlab":    "https://gitlab.com",
+}
+
+// oauthConfigs reads OAUTH_<NAME>_* for every provider name.
+func oauthConfigs(names []string) []oidc.Config {
+	configs := make([]oidc.Config, 0, len(names))
+	for _, name := range names {
+		prefix := "OAUTH_" + strings.ToUpper(name) + "_"
+		configs = append(configs, oidc.Config{
+			Name:         name,
+			Issuer:       env.GetString(prefix+"ISSUER", knownIssuers[name]),
+			ClientID:     env.GetString(prefix+"CLIENT_ID", ""),
+			ClientSecret: env.GetString(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  env.GetString(prefix+"REDIRECT_URL", fmt.Sprintf("http://localhost:3000/v1/auth/oauth/%s/callback", name)),
+			Scopes:       env.GetStrings(prefix+"SCOPES", nil),
+			Leeway:       time.Second * 30,
+			Tenants:      env.GetStrings(prefix+"TENANTS", nil),
+		})
+	}
+	return configs
+}
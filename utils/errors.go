@@ -61,3 +61,14 @@ func (app *AppWrapper) RateLimitExceededResponse(w http.ResponseWriter, r *http.
 
 	WriteJSONError(w, http.StatusTooManyRequests, "rate limit exceeded, retry after: "+retryAfter)
 }
+
+func (app *AppWrapper) FailedValidationResponse(w http.ResponseWriter, r *http.Request, err error, fields map[string][]string) {
+	app.Logger.Warnw("failed validation", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+
+	type envelope struct {
+		Error  string              `json:"error"`
+		Fields map[string][]string `json:"fields"`
+	}
+
+	WriteJSON(w, http.StatusUnprocessableEntity, &envelope{Error: err.Error(), Fields: fields})
+}